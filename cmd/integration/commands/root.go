@@ -7,6 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	kv2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/migrations"
 	"github.com/ledgerwatch/log/v3"
@@ -37,7 +38,7 @@ func RootCommand() *cobra.Command {
 func dbCfg(label kv.Label, logger log.Logger, path string) kv2.MdbxOpts {
 	opts := kv2.NewMDBX(logger).Path(path).Label(label)
 	if label == kv.ChainDB {
-		opts = opts.MapSize(8 * datasize.TB)
+		opts = opts.MapSize(8 * datasize.TB).WithTablessCfg(rawdb.WithChaindataTables)
 	}
 	if databaseVerbosity != -1 {
 		opts = opts.DBVerbosity(kv.DBVerbosityLvl(databaseVerbosity))
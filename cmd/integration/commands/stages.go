@@ -27,6 +27,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/integrity"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/freezer"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/migrations"
@@ -609,7 +610,7 @@ func stageSenders(db kv.RwDB, ctx context.Context) error {
 		return err
 	}
 
-	cfg := stagedsync.StageSendersCfg(db, chainConfig, tmpdir, pm, br)
+	cfg := stagedsync.StageSendersCfg(db, chainConfig, tmpdir, pm, br, getFreezer(db), ethconfig.Defaults.AncientKeepRecent)
 	if unwind > 0 {
 		u := sync.NewUnwindState(stages.Senders, s.BlockNumber-unwind, s.BlockNumber)
 		if err = stagedsync.UnwindSendersStage(u, tx, cfg, ctx); err != nil {
@@ -658,12 +659,14 @@ func stageExec(db kv.RwDB, ctx context.Context) error {
 	log.Info("Stage", "name", s.ID, "progress", s.BlockNumber)
 	if pruneTo > 0 {
 		pm.History = prune.Distance(s.BlockNumber - pruneTo)
+		pm.AccountHistory = prune.Distance(s.BlockNumber - pruneTo)
+		pm.StorageHistory = prune.Distance(s.BlockNumber - pruneTo)
 		pm.Receipts = prune.Distance(s.BlockNumber - pruneTo)
 		pm.CallTraces = prune.Distance(s.BlockNumber - pruneTo)
 		pm.TxIndex = prune.Distance(s.BlockNumber - pruneTo)
 	}
 
-	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, nil, chainConfig, engine, vmConfig, nil, false, tmpdir, getBlockReader(chainConfig, db))
+	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, nil, chainConfig, engine, vmConfig, nil, false, false, tmpdir, getBlockReader(chainConfig, db))
 	if unwind > 0 {
 		u := sync.NewUnwindState(stages.Execution, s.BlockNumber-unwind, s.BlockNumber)
 		err := stagedsync.UnwindExecutionStage(u, s, nil, ctx, cfg, false)
@@ -714,6 +717,8 @@ func stageTrie(db kv.RwDB, ctx context.Context) error {
 
 	if pruneTo > 0 {
 		pm.History = prune.Distance(s.BlockNumber - pruneTo)
+		pm.AccountHistory = prune.Distance(s.BlockNumber - pruneTo)
+		pm.StorageHistory = prune.Distance(s.BlockNumber - pruneTo)
 		pm.Receipts = prune.Distance(s.BlockNumber - pruneTo)
 		pm.CallTraces = prune.Distance(s.BlockNumber - pruneTo)
 		pm.TxIndex = prune.Distance(s.BlockNumber - pruneTo)
@@ -722,7 +727,7 @@ func stageTrie(db kv.RwDB, ctx context.Context) error {
 
 	log.Info("StageExec", "progress", execStage.BlockNumber)
 	log.Info("StageTrie", "progress", s.BlockNumber)
-	cfg := stagedsync.StageTrieCfg(db, true, true, tmpdir, getBlockReader(chainConfig, db))
+	cfg := stagedsync.StageTrieCfg(db, true, true, tmpdir, getBlockReader(chainConfig, db), nil)
 	if unwind > 0 {
 		u := sync.NewUnwindState(stages.IntermediateHashes, s.BlockNumber-unwind, s.BlockNumber)
 		if err := stagedsync.UnwindIntermediateHashesStage(u, s, tx, cfg, ctx); err != nil {
@@ -769,6 +774,8 @@ func stageHashState(db kv.RwDB, ctx context.Context) error {
 	s := stage(sync, tx, nil, stages.HashState)
 	if pruneTo > 0 {
 		pm.History = prune.Distance(s.BlockNumber - pruneTo)
+		pm.AccountHistory = prune.Distance(s.BlockNumber - pruneTo)
+		pm.StorageHistory = prune.Distance(s.BlockNumber - pruneTo)
 		pm.Receipts = prune.Distance(s.BlockNumber - pruneTo)
 		pm.CallTraces = prune.Distance(s.BlockNumber - pruneTo)
 		pm.TxIndex = prune.Distance(s.BlockNumber - pruneTo)
@@ -823,6 +830,8 @@ func stageLogIndex(db kv.RwDB, ctx context.Context) error {
 	s := stage(sync, tx, nil, stages.LogIndex)
 	if pruneTo > 0 {
 		pm.History = prune.Distance(s.BlockNumber - pruneTo)
+		pm.AccountHistory = prune.Distance(s.BlockNumber - pruneTo)
+		pm.StorageHistory = prune.Distance(s.BlockNumber - pruneTo)
 		pm.Receipts = prune.Distance(s.BlockNumber - pruneTo)
 		pm.CallTraces = prune.Distance(s.BlockNumber - pruneTo)
 		pm.TxIndex = prune.Distance(s.BlockNumber - pruneTo)
@@ -880,6 +889,8 @@ func stageCallTraces(kv kv.RwDB, ctx context.Context) error {
 	s := stage(sync, tx, nil, stages.CallTraces)
 	if pruneTo > 0 {
 		pm.History = prune.Distance(s.BlockNumber - pruneTo)
+		pm.AccountHistory = prune.Distance(s.BlockNumber - pruneTo)
+		pm.StorageHistory = prune.Distance(s.BlockNumber - pruneTo)
 		pm.Receipts = prune.Distance(s.BlockNumber - pruneTo)
 		pm.CallTraces = prune.Distance(s.BlockNumber - pruneTo)
 		pm.TxIndex = prune.Distance(s.BlockNumber - pruneTo)
@@ -939,6 +950,8 @@ func stageHistory(db kv.RwDB, ctx context.Context) error {
 	stageAcc := stage(sync, tx, nil, stages.AccountHistoryIndex)
 	if pruneTo > 0 {
 		pm.History = prune.Distance(stageAcc.BlockNumber - pruneTo)
+		pm.AccountHistory = prune.Distance(stageAcc.BlockNumber - pruneTo)
+		pm.StorageHistory = prune.Distance(stageAcc.BlockNumber - pruneTo)
 		pm.Receipts = prune.Distance(stageAcc.BlockNumber - pruneTo)
 		pm.CallTraces = prune.Distance(stageAcc.BlockNumber - pruneTo)
 		pm.TxIndex = prune.Distance(stageAcc.BlockNumber - pruneTo)
@@ -1008,13 +1021,15 @@ func stageTxLookup(db kv.RwDB, ctx context.Context) error {
 	s := stage(sync, tx, nil, stages.TxLookup)
 	if pruneTo > 0 {
 		pm.History = prune.Distance(s.BlockNumber - pruneTo)
+		pm.AccountHistory = prune.Distance(s.BlockNumber - pruneTo)
+		pm.StorageHistory = prune.Distance(s.BlockNumber - pruneTo)
 		pm.Receipts = prune.Distance(s.BlockNumber - pruneTo)
 		pm.CallTraces = prune.Distance(s.BlockNumber - pruneTo)
 		pm.TxIndex = prune.Distance(s.BlockNumber - pruneTo)
 	}
 	log.Info("Stage", "name", s.ID, "progress", s.BlockNumber)
 	isBor := chainConfig.Bor != nil
-	cfg := stagedsync.StageTxLookupCfg(db, pm, tmpdir, allSnapshots(chainConfig, db), isBor)
+	cfg := stagedsync.StageTxLookupCfg(db, pm, tmpdir, allSnapshots(chainConfig, db), getFreezer(db), isBor)
 	if unwind > 0 {
 		u := sync.NewUnwindState(stages.TxLookup, s.BlockNumber-unwind, s.BlockNumber)
 		err = stagedsync.UnwindTxLookup(u, s, tx, cfg, ctx)
@@ -1099,12 +1114,33 @@ func allSnapshots(cc *params.ChainConfig, db kv.RwDB) *snapshotsync.RoSnapshots
 	return _allSnapshotsSingleton
 }
 
+var openFreezerOnce sync.Once
+var _freezerSingleton *freezer.Freezer
+
+// getFreezer - freezer recorded in the db by the node started with --datadir.ancient, nil if it's not used
+func getFreezer(db kv.RoDB) *freezer.Freezer {
+	openFreezerOnce.Do(func() {
+		if err := db.View(context.Background(), func(tx kv.Tx) (err error) {
+			if _freezerSingleton, err = rawdb.OpenRecordedFreezer(tx, false); err != nil || _freezerSingleton == nil {
+				return err
+			}
+			return rawdb.TruncateFreezer(tx, _freezerSingleton)
+		}); err != nil {
+			panic(err)
+		}
+	})
+	return _freezerSingleton
+}
+
 var openBlockReaderOnce sync.Once
 var _blockReaderSingleton services.FullBlockReader
 
 func getBlockReader(cc *params.ChainConfig, db kv.RwDB) (blockReader services.FullBlockReader) {
 	openBlockReaderOnce.Do(func() {
 		_blockReaderSingleton = snapshotsync.NewBlockReader()
+		if f := getFreezer(db); f != nil {
+			_blockReaderSingleton = snapshotsync.NewBlockReaderWithFreezer(f)
+		}
 		if sn := allSnapshots(cc, db); sn.Cfg().Enabled {
 			x := snapshotsync.NewBlockReaderWithSnapshots(sn)
 			_blockReaderSingleton = x
@@ -1196,7 +1232,7 @@ func newSync(ctx context.Context, db kv.RwDB, miningConfig *params.MiningConfig)
 		panic(err)
 	}
 
-	sync, err := stages2.NewStagedSync(context.Background(), logger, db, p2p.Config{}, cfg, sentryControlServer, tmpdir, &stagedsync.Notifications{}, nil, allSn, nil)
+	sync, err := stages2.NewStagedSync(context.Background(), logger, db, p2p.Config{}, cfg, sentryControlServer, tmpdir, &stagedsync.Notifications{}, nil, allSn, getFreezer(db), nil)
 	if err != nil {
 		panic(err)
 	}
@@ -1207,7 +1243,7 @@ func newSync(ctx context.Context, db kv.RwDB, miningConfig *params.MiningConfig)
 			stagedsync.StageMiningCreateBlockCfg(db, miner, *chainConfig, engine, nil, nil, nil, tmpdir),
 			stagedsync.StageMiningExecCfg(db, miner, events, *chainConfig, engine, &vm.Config{}, tmpdir, nil),
 			stagedsync.StageHashStateCfg(db, tmpdir),
-			stagedsync.StageTrieCfg(db, false, true, tmpdir, br),
+			stagedsync.StageTrieCfg(db, false, true, tmpdir, br, nil),
 			stagedsync.StageMiningFinishCfg(db, *chainConfig, engine, miner, ctx.Done()),
 		),
 		stagedsync.MiningUnwindOrder,
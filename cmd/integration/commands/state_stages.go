@@ -181,7 +181,7 @@ func syncBySmallSteps(db kv.RwDB, miningConfig params.MiningConfig, ctx context.
 
 	stateStages.DisableStages(stages.Headers, stages.BlockHashes, stages.Bodies, stages.Senders)
 
-	execCfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, changeSetHook, chainConfig, engine, vmConfig, nil, false, dirs.Tmp, getBlockReader(chainConfig, db))
+	execCfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, changeSetHook, chainConfig, engine, vmConfig, nil, false, false, dirs.Tmp, getBlockReader(chainConfig, db))
 
 	execUntilFunc := func(execToBlock uint64) func(firstCycle bool, badBlockUnwind bool, stageState *stagedsync.StageState, unwinder stagedsync.Unwinder, tx kv.RwTx) error {
 		return func(firstCycle bool, badBlockUnwind bool, s *stagedsync.StageState, unwinder stagedsync.Unwinder, tx kv.RwTx) error {
@@ -277,7 +277,7 @@ func syncBySmallSteps(db kv.RwDB, miningConfig params.MiningConfig, ctx context.
 		}
 
 		if integrityFast {
-			if err := checkChanges(expectedAccountChanges, tx, expectedStorageChanges, execAtBlock, pm.History.PruneTo(execToBlock)); err != nil {
+			if err := checkChanges(expectedAccountChanges, tx, expectedStorageChanges, execAtBlock, pm.AccountHistoryAmount().PruneTo(execToBlock), pm.StorageHistoryAmount().PruneTo(execToBlock)); err != nil {
 				return err
 			}
 			integrity.Trie(db, tx, integritySlow, ctx)
@@ -365,13 +365,21 @@ func syncBySmallSteps(db kv.RwDB, miningConfig params.MiningConfig, ctx context.
 	return nil
 }
 
-func checkChanges(expectedAccountChanges map[uint64]*changeset.ChangeSet, tx kv.Tx, expectedStorageChanges map[uint64]*changeset.ChangeSet, execAtBlock, prunedTo uint64) error {
-	checkHistoryFrom := execAtBlock
-	if prunedTo > checkHistoryFrom {
-		checkHistoryFrom = prunedTo
+func checkChanges(expectedAccountChanges map[uint64]*changeset.ChangeSet, tx kv.Tx, expectedStorageChanges map[uint64]*changeset.ChangeSet, execAtBlock, accountPrunedTo, storagePrunedTo uint64) error {
+	checkAccountHistoryFrom, checkStorageHistoryFrom := execAtBlock, execAtBlock
+	if accountPrunedTo > checkAccountHistoryFrom {
+		checkAccountHistoryFrom = accountPrunedTo
+	}
+	if storagePrunedTo > checkStorageHistoryFrom {
+		checkStorageHistoryFrom = storagePrunedTo
+	}
+	// block is compared only if both its account and storage changes are kept
+	checkChangesFrom := checkAccountHistoryFrom
+	if checkStorageHistoryFrom > checkChangesFrom {
+		checkChangesFrom = checkStorageHistoryFrom
 	}
 	for blockN := range expectedAccountChanges {
-		if blockN <= checkHistoryFrom {
+		if blockN <= checkChangesFrom {
 			continue
 		}
 		if err := checkChangeSet(tx, blockN, expectedAccountChanges[blockN], expectedStorageChanges[blockN]); err != nil {
@@ -381,10 +389,10 @@ func checkChanges(expectedAccountChanges map[uint64]*changeset.ChangeSet, tx kv.
 		delete(expectedStorageChanges, blockN)
 	}
 
-	if err := checkHistory(tx, kv.AccountChangeSet, checkHistoryFrom); err != nil {
+	if err := checkHistory(tx, kv.AccountChangeSet, checkAccountHistoryFrom); err != nil {
 		return err
 	}
-	if err := checkHistory(tx, kv.StorageChangeSet, checkHistoryFrom); err != nil {
+	if err := checkHistory(tx, kv.StorageChangeSet, checkStorageHistoryFrom); err != nil {
 		return err
 	}
 	return nil
@@ -426,7 +434,7 @@ func loopIh(db kv.RwDB, ctx context.Context, unwind uint64) error {
 	}
 	_ = sync.SetCurrentStage(stages.IntermediateHashes)
 	u = &stagedsync.UnwindState{ID: stages.IntermediateHashes, UnwindPoint: to}
-	if err = stagedsync.UnwindIntermediateHashesStage(u, stage(sync, tx, nil, stages.IntermediateHashes), tx, stagedsync.StageTrieCfg(db, true, true, dirs.Tmp, getBlockReader(chainConfig, db)), ctx); err != nil {
+	if err = stagedsync.UnwindIntermediateHashesStage(u, stage(sync, tx, nil, stages.IntermediateHashes), tx, stagedsync.StageTrieCfg(db, true, true, dirs.Tmp, getBlockReader(chainConfig, db), nil), ctx); err != nil {
 		return err
 	}
 	must(tx.Commit())
@@ -491,7 +499,7 @@ func loopExec(db kv.RwDB, ctx context.Context, unwind uint64) error {
 	from := progress(tx, stages.Execution)
 	to := from + unwind
 
-	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, nil, chainConfig, engine, vmConfig, nil, false, dirs.Tmp, getBlockReader(chainConfig, db))
+	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, nil, chainConfig, engine, vmConfig, nil, false, false, dirs.Tmp, getBlockReader(chainConfig, db))
 
 	// set block limit of execute stage
 	sync.MockExecFunc(stages.Execution, func(firstCycle bool, badBlockUnwind bool, stageState *stagedsync.StageState, unwinder stagedsync.Unwinder, tx kv.RwTx) error {
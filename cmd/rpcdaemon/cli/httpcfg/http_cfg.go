@@ -24,6 +24,7 @@ type HttpCfg struct {
 	HttpCompression         bool
 	API                     []string
 	Gascap                  uint64
+	GasOraclePercentile     int
 	MaxTraces               uint64
 	WebsocketEnabled        bool
 	WebsocketCompression    bool
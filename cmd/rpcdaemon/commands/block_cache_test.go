@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockCacheReorg(t *testing.T) {
+	chain := func(fork byte, parent common.Hash, from, to uint64) []*types.Block {
+		var blocks []*types.Block
+		for n := from; n <= to; n++ {
+			b := types.NewBlockWithHeader(&types.Header{ParentHash: parent, Number: new(big.Int).SetUint64(n), Extra: []byte{fork}})
+			blocks = append(blocks, b)
+			parent = b.Hash()
+		}
+		return blocks
+	}
+	a := chain(0xa, common.Hash{}, 1, 5)
+	c := NewBlockCache(16)
+	for _, b := range a {
+		c.Add(b)
+	}
+	hits := blockCacheHits.Get()
+	require.Equal(t, a[3], c.GetByNumber(4, a[3].Hash()))
+	require.Equal(t, a[4], c.Get(a[4].Hash()))
+	require.Equal(t, hits+2, blockCacheHits.Get())
+
+	// 2-block reorg: 4 and 5 are replaced
+	b := chain(0xb, a[2].Hash(), 4, 5)
+	c.OnNewHead(b[0].Header())
+	require.Nil(t, c.GetByNumber(4, b[0].Hash()))
+	require.Nil(t, c.Get(a[3].Hash()))
+	require.Nil(t, c.Get(a[4].Hash()))
+	require.Equal(t, a[2], c.Get(a[2].Hash()))
+	for _, blk := range b {
+		c.Add(blk)
+	}
+	require.Equal(t, b[0], c.GetByNumber(4, b[0].Hash()))
+	require.Equal(t, b[1], c.Get(b[1].Hash()))
+	// reader which still sees the old chain in its tx doesn't get the new block
+	require.Nil(t, c.GetByNumber(5, a[4].Hash()))
+
+	// replacing block of the same number without notification
+	c.Add(a[4])
+	require.Nil(t, c.Get(b[1].Hash()))
+	require.Equal(t, a[4], c.GetByNumber(5, a[4].Hash()))
+}
+
+func TestBlockCacheEviction(t *testing.T) {
+	c := NewBlockCache(2)
+	var blocks []*types.Block
+	for n := int64(1); n <= 3; n++ {
+		blk := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(n)})
+		blocks = append(blocks, blk)
+		c.Add(blk)
+	}
+	require.Nil(t, c.Get(blocks[0].Hash()))
+	require.Equal(t, blocks[1], c.Get(blocks[1].Hash()))
+	require.Equal(t, blocks[2], c.Get(blocks[2].Hash()))
+}
@@ -42,7 +42,7 @@ func TestTraceBlockByNumber(t *testing.T) {
 	db := rpcdaemontest.CreateTestKV(t)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
-	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000)
+	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 0)
 	api := NewPrivateDebugAPI(baseApi, db, 0)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
@@ -89,7 +89,7 @@ func TestTraceBlockByHash(t *testing.T) {
 	db := rpcdaemontest.CreateTestKV(t)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
-	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000)
+	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 0)
 	api := NewPrivateDebugAPI(baseApi, db, 0)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/remotedb"
+)
+
+// ChaindbAPI - debug_chaindb* methods. Exposed only on the authenticated (engine) endpoint
+type ChaindbAPI interface {
+	ChaindbProperty(ctx context.Context, property string) (string, error)
+}
+
+type ChaindbAPIImpl struct {
+	db kv.RoDB
+}
+
+func NewChaindbAPI(db kv.RoDB) *ChaindbAPIImpl {
+	return &ChaindbAPIImpl{db: db}
+}
+
+// ChaindbProperty implements debug_chaindbProperty. Properties: "pagesize", "dbsize", "freelist" (size in bytes),
+// "freepages" (amount of pages which can be reused), name of a table (size in bytes)
+// and "stats" (all of the above for non-empty tables, human-readable)
+func (api *ChaindbAPIImpl) ChaindbProperty(ctx context.Context, property string) (string, error) {
+	if _, ok := api.db.(*remotedb.RemoteKV); ok {
+		return "", fmt.Errorf("debug_chaindbProperty is not available with remote db, start rpcdaemon with --datadir")
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	switch property {
+	case "pagesize":
+		return strconv.FormatUint(api.db.PageSize(), 10), nil
+	case "dbsize":
+		sz, err := tx.DBSize()
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(sz, 10), nil
+	case "freelist":
+		sz, err := tx.BucketSize("freelist")
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(sz, 10), nil
+	case "freepages":
+		sz, err := tx.BucketSize("freelist")
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(sz/4, 10), nil // page_id encoded as bigEndian_u32
+	case "stats":
+		return api.stats(tx)
+	}
+	if cfg, ok := api.db.AllBuckets()[property]; !ok || cfg.IsDeprecated {
+		return "", fmt.Errorf("unknown property: %s", property)
+	}
+	sz, err := tx.BucketSize(property)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(sz, 10), nil
+}
+
+func (api *ChaindbAPIImpl) stats(tx kv.Tx) (string, error) {
+	dbSize, err := tx.DBSize()
+	if err != nil {
+		return "", err
+	}
+	freelist, err := tx.BucketSize("freelist")
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "PageSize: %s\n", libcommon.ByteCount(api.db.PageSize()))
+	fmt.Fprintf(&sb, "DBSize: %s\n", libcommon.ByteCount(dbSize))
+	fmt.Fprintf(&sb, "FreeList: %s\n", libcommon.ByteCount(freelist))
+	fmt.Fprintf(&sb, "ReclaimableSpace: %s\n", libcommon.ByteCount(freelist/4*api.db.PageSize()))
+
+	sizes, err := tableSizes(api.db, tx)
+	if err != nil {
+		return "", err
+	}
+	tables := make([]string, 0, len(sizes))
+	for table := range sizes {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Fprintf(&sb, "%s: %s\n", table, libcommon.ByteCount(sizes[table]))
+	}
+	return sb.String(), nil
+}
+
+// tableSizes - sizes in bytes of non-empty tables, db must be local
+func tableSizes(db kv.RoDB, tx kv.Tx) (map[string]uint64, error) {
+	sizes := map[string]uint64{}
+	for table, cfg := range db.AllBuckets() {
+		if cfg.IsDeprecated { // not opened
+			continue
+		}
+		sz, err := tx.BucketSize(table)
+		if err != nil {
+			return nil, err
+		}
+		if sz == 0 {
+			continue
+		}
+		sizes[table] = sz
+	}
+	return sizes, nil
+}
@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaindbProperty(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	api := NewChaindbAPI(db)
+	ctx := context.Background()
+
+	for _, property := range []string{"pagesize", "dbsize", kv.PlainState} {
+		v, err := api.ChaindbProperty(ctx, property)
+		require.NoError(t, err, property)
+		sz, err := strconv.ParseUint(v, 10, 64)
+		require.NoError(t, err, property)
+		require.NotZero(t, sz, property)
+	}
+
+	stats, err := api.ChaindbProperty(ctx, "stats")
+	require.NoError(t, err)
+	require.True(t, strings.Contains(stats, kv.PlainState+": "))
+
+	_, err = api.ChaindbProperty(ctx, "leveldb.stats")
+	require.Error(t, err)
+}
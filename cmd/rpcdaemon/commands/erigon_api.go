@@ -5,7 +5,9 @@ import (
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -23,6 +25,7 @@ type ErigonAPI interface {
 
 	// Receipt related (see ./erigon_receipts.go)
 	GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error)
+	GetLatestLogs(ctx context.Context, fromBlock hexutil.Uint64, crit filters.FilterCriteria) (*LatestLogs, error)
 	//GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error)
 
 	// WatchTheBurn / reward related (see ./erigon_issuance.go)
@@ -33,6 +36,7 @@ type ErigonAPI interface {
 
 	// NodeInfo returns a collection of metadata known about the host.
 	NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error)
+	NodeInfoExtended(ctx context.Context) (*NodeInfoExtended, error)
 }
 
 // ErigonImpl is implementation of the ErigonAPI interface
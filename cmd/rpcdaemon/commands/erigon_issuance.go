@@ -59,8 +59,10 @@ func (api *ErigonImpl) WatchTheBurn(ctx context.Context, blockNr rpc.BlockNumber
 		return Issuance{}, fmt.Errorf("could not find block header")
 	}
 
-	body := rawdb.ReadCanonicalBodyWithTransactions(tx, hash, uint64(blockNr))
-
+	body, err := api._blockReader.Body(ctx, tx, hash, uint64(blockNr))
+	if err != nil {
+		return Issuance{}, err
+	}
 	if body == nil {
 		return Issuance{}, fmt.Errorf("could not find block body")
 	}
@@ -102,7 +104,14 @@ func (api *ErigonImpl) WatchTheBurn(ctx context.Context, blockNr rpc.BlockNumber
 	tips := big.NewInt(0)
 
 	if header.BaseFee != nil {
-		receipts, err := rawdb.ReadReceiptsByHash(tx, hash)
+		block, err := api.blockWithSenders(tx, hash, uint64(blockNr))
+		if err != nil {
+			return Issuance{}, err
+		}
+		if block == nil {
+			return Issuance{}, fmt.Errorf("could not find block %d", blockNr)
+		}
+		receipts, err := api.getReceiptsWithoutLogs(ctx, tx, chainConfig, block, block.Body().SendersFromTxs())
 		if err != nil {
 			return Issuance{}, err
 		}
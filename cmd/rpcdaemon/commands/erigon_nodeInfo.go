@@ -2,8 +2,15 @@ package commands
 
 import (
 	"context"
+	"runtime"
+	"runtime/pprof"
 
+	"github.com/ledgerwatch/erigon-lib/kv/remotedb"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/params"
 )
 
 const (
@@ -14,3 +21,100 @@ const (
 func (api *ErigonImpl) NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error) {
 	return api.ethBackend.NodeInfo(ctx, allNodesInfo)
 }
+
+// NodeInfoExtended - see ErigonImpl.NodeInfoExtended
+type NodeInfoExtended struct {
+	ClientVersion    string                    `json:"clientVersion"`    // of Erigon
+	RpcDaemonVersion string                    `json:"rpcDaemonVersion"` // of the process which serves the request
+	NodeType         string                    `json:"nodeType"`         // archive, full (--prune=hrtc) or pruned
+	PruneMode        string                    `json:"pruneMode"`
+	Stages           []StageProgress           `json:"stages"`
+	Tables           map[string]hexutil.Uint64 `json:"tables,omitempty"` // size in bytes, only with local db
+	Runtime          RuntimeInfo               `json:"runtime"`          // of the process which serves the request
+	Nodes            []p2p.NodeInfo            `json:"nodes"`
+}
+
+type StageProgress struct {
+	StageName   string         `json:"stageName"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+type RuntimeInfo struct {
+	Goroutines int            `json:"goroutines"`
+	OSThreads  int            `json:"osThreads"`
+	HeapAlloc  hexutil.Uint64 `json:"heapAlloc"`
+	Sys        hexutil.Uint64 `json:"sys"`
+}
+
+// NodeInfoExtended implements erigon_nodeInfoExtended. Returns in one call what node health dashboards need:
+// versions, sync progress of every stage, prune mode, table sizes and p2p info of all sentries
+func (api *ErigonImpl) NodeInfoExtended(ctx context.Context) (*NodeInfoExtended, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	info := &NodeInfoExtended{RpcDaemonVersion: params.VersionWithCommit(params.GitCommit, "")}
+	if info.ClientVersion, err = api.ethBackend.ClientVersion(ctx); err != nil {
+		return nil, err
+	}
+	if info.Nodes, err = api.ethBackend.NodeInfo(ctx, allNodesInfo); err != nil {
+		return nil, err
+	}
+
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return nil, err
+	}
+	info.PruneMode = pm.String()
+	info.NodeType = nodeType(pm)
+
+	info.Stages = make([]StageProgress, len(stages.AllStages))
+	for i, stage := range stages.AllStages {
+		progress, err := stages.GetStageProgress(tx, stage)
+		if err != nil {
+			return nil, err
+		}
+		info.Stages[i] = StageProgress{StageName: string(stage), BlockNumber: hexutil.Uint64(progress)}
+	}
+
+	if _, remote := api.db.(*remotedb.RemoteKV); !remote {
+		sizes, err := tableSizes(api.db, tx)
+		if err != nil {
+			return nil, err
+		}
+		info.Tables = make(map[string]hexutil.Uint64, len(sizes))
+		for table, sz := range sizes {
+			info.Tables[table] = hexutil.Uint64(sz)
+		}
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	info.Runtime = RuntimeInfo{
+		Goroutines: runtime.NumGoroutine(),
+		OSThreads:  pprof.Lookup("threadcreate").Count(),
+		HeapAlloc:  hexutil.Uint64(m.HeapAlloc),
+		Sys:        hexutil.Uint64(m.Sys),
+	}
+	return info, nil
+}
+
+func nodeType(pm prune.Mode) string {
+	pruned := 0
+	accountHistory, storageHistory := pm.AccountHistoryAmount(), pm.StorageHistoryAmount()
+	for _, amount := range []prune.BlockAmount{accountHistory, storageHistory, pm.Receipts, pm.TxIndex, pm.CallTraces} {
+		if amount.Enabled() {
+			pruned++
+		}
+	}
+	switch {
+	case pruned == 0:
+		return "archive"
+	case accountHistory.Enabled() && storageHistory.Enabled() && pm.Receipts.Enabled() && pm.TxIndex.Enabled() && pm.CallTraces.Enabled():
+		return "full"
+	default:
+		return "pruned"
+	}
+}
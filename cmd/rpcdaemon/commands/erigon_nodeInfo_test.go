@@ -0,0 +1,20 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeType(t *testing.T) {
+	require.Equal(t, "archive", nodeType(prune.DefaultMode))
+
+	full, err := prune.FromCli("hrtc", 0, 0, 0, 0, 0, 0, 0, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, "full", nodeType(full))
+
+	pruned := prune.DefaultMode
+	pruned.AccountHistory = prune.Before(100)
+	require.Equal(t, "pruned", nodeType(pruned))
+}
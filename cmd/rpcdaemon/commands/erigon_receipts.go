@@ -5,7 +5,10 @@ import (
 	"fmt"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 // GetLogsByHash implements erigon_getLogsByHash. Returns an array of arrays of logs generated by the transactions in the block given by the block's hash.
@@ -40,6 +43,56 @@ func (api *ErigonImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][
 	return logs, nil
 }
 
+// latestLogsMaxBlocks - erigon_getLatestLogs reads at most this amount of blocks per call
+const latestLogsMaxBlocks = 1024
+
+// LatestLogs - result of erigon_getLatestLogs. Next poll should start from LastBlock+1
+type LatestLogs struct {
+	Logs      []*types.Log   `json:"logs"`
+	LastBlock hexutil.Uint64 `json:"lastBlock"`
+}
+
+// GetLatestLogs implements erigon_getLatestLogs. Returns logs matching addresses and topics of the filter (block range
+// of the filter is ignored) in blocks from `fromBlock` to the latest one, but not more than latestLogsMaxBlocks blocks.
+// Intended for polling of recent blocks: logs of every block are read directly, without LogAddressIndex/LogTopicIndex
+func (api *ErigonImpl) GetLatestLogs(ctx context.Context, fromBlock hexutil.Uint64, crit filters.FilterCriteria) (*LatestLogs, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err = checkReceiptStorage(tx, "erigon_getLatestLogs"); err != nil {
+		return nil, err
+	}
+
+	latest, err := rpchelper.GetLatestBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	begin := uint64(fromBlock)
+	end := latest
+	if end >= begin && end-begin >= latestLogsMaxBlocks {
+		end = begin + latestLogsMaxBlocks - 1
+	}
+
+	result := &LatestLogs{Logs: []*types.Log{}, LastBlock: hexutil.Uint64(end)}
+	if begin > latest {
+		result.LastBlock = hexutil.Uint64(begin - 1)
+		return result, nil
+	}
+	for block := begin; block <= end; block++ {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		blockLogs, err := api.getBlockLogs(tx, block, crit.Addresses, crit.Topics)
+		if err != nil {
+			return nil, err
+		}
+		result.Logs = append(result.Logs, blockLogs...)
+	}
+	return result, nil
+}
+
 // GetLogsByNumber implements erigon_getLogsByHash. Returns all the logs that appear in a block given the block's hash.
 // func (api *ErigonImpl) GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error) {
 // 	tx, err := api.db.Begin(ctx, false)
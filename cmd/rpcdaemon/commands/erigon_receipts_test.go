@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLatestLogs(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethAPI := NewEthAPI(base, db, nil, nil, nil, 5000000, 0)
+	api := NewErigonAPI(base, db, nil)
+	ctx := context.Background()
+
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	latest, err := rpchelper.GetLatestBlockNumber(tx)
+	tx.Rollback()
+	require.NoError(t, err)
+
+	expected, err := ethAPI.GetLogs(ctx, filters.FilterCriteria{})
+	require.NoError(t, err)
+	require.NotEmpty(t, expected)
+	res, err := api.GetLatestLogs(ctx, 0, filters.FilterCriteria{})
+	require.NoError(t, err)
+	require.Equal(t, expected, res.Logs)
+	require.Equal(t, hexutil.Uint64(latest), res.LastBlock)
+
+	// polling from the next block returns nothing, and the same next block
+	res, err = api.GetLatestLogs(ctx, res.LastBlock+1, filters.FilterCriteria{})
+	require.NoError(t, err)
+	require.Empty(t, res.Logs)
+	require.Equal(t, hexutil.Uint64(latest), res.LastBlock)
+
+	crit := filters.FilterCriteria{Addresses: []common.Address{expected[0].Address}}
+	res, err = api.GetLatestLogs(ctx, hexutil.Uint64(expected[0].BlockNumber), crit)
+	require.NoError(t, err)
+	require.Equal(t, expected[0], res.Logs[0])
+	for _, log := range res.Logs {
+		require.Equal(t, expected[0].Address, log.Address)
+	}
+}
+
+func TestReceiptStorageModeMinimal(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethAPI := NewEthAPI(base, db, nil, nil, nil, 5000000, 0)
+	api := NewErigonAPI(base, db, nil)
+	ctx := context.Background()
+
+	logs, err := ethAPI.GetLogs(ctx, filters.FilterCriteria{})
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	expected, err := api.GetLogsByHash(ctx, logs[0].BlockHash)
+	require.NoError(t, err)
+
+	// receipts are stored without logs: methods returning stored receipts and logs fail, others re-execute the block
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.DatabaseInfo, prune.ReceiptStorageModeKey, []byte{byte(prune.ReceiptStorageMinimal)})
+	}))
+	_, err = ethAPI.GetLogs(ctx, filters.FilterCriteria{})
+	require.Error(t, err)
+	_, err = api.GetLatestLogs(ctx, 0, filters.FilterCriteria{})
+	require.Error(t, err)
+	_, err = ethAPI.GetTransactionReceipt(ctx, logs[0].TxHash)
+	require.Error(t, err)
+	_, err = ethAPI.GetBlockReceipts(ctx, rpc.BlockNumber(logs[0].BlockNumber))
+	require.Error(t, err)
+	res, err := api.GetLogsByHash(ctx, logs[0].BlockHash)
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+}
+
+func TestGetLogsFrozen(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), false)
+	ethAPI := NewEthAPI(base, db, nil, nil, nil, 5000000, 0)
+	api := NewErigonAPI(base, db, nil)
+	ctx := context.Background()
+
+	expected, err := ethAPI.GetLogs(ctx, filters.FilterCriteria{})
+	require.NoError(t, err)
+	require.NotEmpty(t, expected)
+	blockNr := rpc.BlockNumber(expected[0].BlockNumber)
+	expectedCount, err := ethAPI.GetBlockTransactionCountByNumber(ctx, blockNr)
+	require.NoError(t, err)
+	expectedTraffic, err := api.CumulativeChainTraffic(ctx, blockNr)
+	require.NoError(t, err)
+
+	f, err := rawdb.OpenFreezer(t.TempDir(), false)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		latest, err := rpchelper.GetLatestBlockNumber(tx)
+		if err != nil {
+			return err
+		}
+		return rawdb.FreezeBlocks(tx, f, latest+1, 1000)
+	}))
+
+	base = NewBaseApi(nil, stateCache, snapshotsync.NewBlockReaderWithFreezer(f), false)
+	ethAPI = NewEthAPI(base, db, nil, nil, nil, 5000000, 0)
+	api = NewErigonAPI(base, db, nil)
+	logs, err := ethAPI.GetLogs(ctx, filters.FilterCriteria{})
+	require.NoError(t, err)
+	require.Equal(t, expected, logs)
+	count, err := ethAPI.GetBlockTransactionCountByNumber(ctx, blockNr)
+	require.NoError(t, err)
+	require.Equal(t, expectedCount, count)
+	count, err = ethAPI.GetBlockTransactionCountByHash(ctx, expected[0].BlockHash)
+	require.NoError(t, err)
+	require.Equal(t, expectedCount, count)
+	traffic, err := api.CumulativeChainTraffic(ctx, blockNr)
+	require.NoError(t, err)
+	require.Equal(t, expectedTraffic, traffic)
+}
@@ -25,15 +25,25 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
 	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
 )
 
 func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address) (types.Receipts, error) {
-	if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
-		return cached, nil
+	mode, err := prune.GetReceiptStorageMode(tx)
+	if err != nil {
+		return nil, err
+	}
+	// without --receipt-storage-mode=full receipts are stored without logs or not stored, re-execute the block.
+	// eth_getTransactionReceipt and eth_getBlockReceipts don't get here in these modes, see checkReceiptStorage
+	if mode.WriteLogs() {
+		if stored, err := api.storedReceipts(ctx, tx, block, senders); err != nil || stored != nil {
+			return stored, err
+		}
 	}
 
 	getHeader := func(hash common.Hash, number uint64) *types.Header {
@@ -44,7 +54,7 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 		return h
 	}
 	contractHasTEVM := ethdb.GetHasTEVM(tx)
-	_, _, _, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, block.Hash(), 0)
+	_, _, _, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, block.Hash(), 0, api.historyCache)
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +80,39 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 	return receipts, nil
 }
 
+// getReceiptsWithoutLogs - for callers which need only status and gas used of receipts: logs are empty if the node
+// runs with --receipt-storage-mode=minimal, which stores receipts without them. Re-executes the block if receipts are not stored
+func (api *BaseAPI) getReceiptsWithoutLogs(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	if stored, err := api.storedReceipts(ctx, tx, block, senders); err != nil || stored != nil {
+		return stored, err
+	}
+	return api.getReceipts(ctx, tx, chainConfig, block, senders)
+}
+
+// storedReceipts - receipts from the db or from the freezer, nil if they are not stored
+func (api *BaseAPI) storedReceipts(ctx context.Context, tx kv.Tx, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
+		return cached, nil
+	}
+	if r, ok := api._blockReader.(services.FrozenReceiptsReader); ok {
+		return r.FrozenReceipts(ctx, block, senders)
+	}
+	return nil, nil
+}
+
+// checkReceiptStorage - receipts with logs and log indices are stored only if the node runs with --receipt-storage-mode=full.
+// Methods returning receipts or logs as stored fail without it instead of re-executing blocks
+func checkReceiptStorage(tx kv.Tx, method string) error {
+	mode, err := prune.GetReceiptStorageMode(tx)
+	if err != nil {
+		return err
+	}
+	if !mode.WriteLogs() {
+		return fmt.Errorf("%s is not available: node runs with --receipt-storage-mode=%s", method, mode)
+	}
+	return nil
+}
+
 // GetLogs implements eth_getLogs. Returns an array of logs matching a given filter object.
 func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*types.Log, error) {
 	var begin, end uint64
@@ -80,6 +123,9 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 		return logs, beginErr
 	}
 	defer tx.Rollback()
+	if err := checkReceiptStorage(tx, "eth_getLogs"); err != nil {
+		return nil, err
+	}
 
 	if crit.BlockHash != nil {
 		number := rawdb.ReadHeaderNumber(tx, *crit.BlockHash)
@@ -154,54 +200,62 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 			return nil, err
 		}
 
-		block := uint64(iter.Next())
-		var logIndex uint
-		var blockLogs []*types.Log
-		err := tx.ForPrefix(kv.Log, dbutils.EncodeBlockNumber(block), func(k, v []byte) error {
-			var logs types.Logs
-			if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
-				return fmt.Errorf("receipt unmarshal failed:  %w", err)
-			}
-			for _, log := range logs {
-				log.Index = logIndex
-				logIndex++
-			}
-			filtered := filterLogs(logs, crit.Addresses, crit.Topics)
-			if len(filtered) == 0 {
-				return nil
-			}
-			txIndex := uint(binary.BigEndian.Uint32(k[8:]))
-			for _, log := range filtered {
-				log.TxIndex = txIndex
-			}
-			blockLogs = append(blockLogs, filtered...)
-
-			return nil
-		})
+		blockLogs, err := api.getBlockLogs(tx, uint64(iter.Next()), crit.Addresses, crit.Topics)
 		if err != nil {
 			return logs, err
 		}
-		if len(blockLogs) == 0 {
-			continue
-		}
+		logs = append(logs, blockLogs...)
+	}
 
-		b, err := api.blockByNumberWithSenders(tx, block)
-		if err != nil {
-			return nil, err
+	return logs, nil
+}
+
+// getBlockLogs - logs of the block matching given addresses and topics (see filterLogs), read from kv.Log
+func (api *BaseAPI) getBlockLogs(tx kv.Tx, block uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+	var logIndex uint
+	var blockLogs []*types.Log
+	err := tx.ForPrefix(kv.Log, dbutils.EncodeBlockNumber(block), func(k, v []byte) error {
+		var logs types.Logs
+		if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
+			return fmt.Errorf("receipt unmarshal failed:  %w", err)
 		}
-		if b == nil {
-			return nil, fmt.Errorf("block not found %d", block)
+		for _, log := range logs {
+			log.Index = logIndex
+			logIndex++
 		}
-		blockHash := b.Hash()
-		for _, log := range blockLogs {
-			log.BlockNumber = block
-			log.BlockHash = blockHash
-			log.TxHash = b.Transactions()[log.TxIndex].Hash()
+		filtered := filterLogs(logs, addresses, topics)
+		if len(filtered) == 0 {
+			return nil
 		}
-		logs = append(logs, blockLogs...)
+		txIndex := uint(binary.BigEndian.Uint32(k[8:]))
+		for _, log := range filtered {
+			log.TxIndex = txIndex
+		}
+		blockLogs = append(blockLogs, filtered...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(blockLogs) == 0 {
+		return nil, nil
 	}
 
-	return logs, nil
+	b, err := api.blockByNumberWithSenders(tx, block)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("block not found %d", block)
+	}
+	blockHash := b.Hash()
+	for _, log := range blockLogs {
+		log.BlockNumber = block
+		log.BlockHash = blockHash
+		log.TxHash = b.Transactions()[log.TxIndex].Hash()
+	}
+	return blockLogs, nil
 }
 
 // The Topic list restricts matches to particular event topics. Each event has a list
@@ -250,6 +304,9 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, hash common.Hash)
 		return nil, err
 	}
 	defer tx.Rollback()
+	if err = checkReceiptStorage(tx, "eth_getTransactionReceipt"); err != nil {
+		return nil, err
+	}
 
 	var borTx *types.Transaction
 	var blockHash common.Hash
@@ -334,6 +391,9 @@ func (api *APIImpl) GetBlockReceipts(ctx context.Context, number rpc.BlockNumber
 		return nil, err
 	}
 	defer tx.Rollback()
+	if err = checkReceiptStorage(tx, "eth_getBlockReceipts"); err != nil {
+		return nil, err
+	}
 
 	blockNum, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(number), tx, api.filters)
 	if err != nil {
@@ -2,13 +2,13 @@ package commands
 
 import (
 	"context"
+	"math/big"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
-	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/params"
@@ -74,22 +74,22 @@ func (api *APIImpl) Syncing(ctx context.Context) (interface{}, error) {
 }
 
 // ChainId implements eth_chainId. Returns the current ethereum chainId.
-func (api *APIImpl) ChainId(ctx context.Context) (hexutil.Uint64, error) {
+func (api *APIImpl) ChainId(ctx context.Context) (*hexutil.Big, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer tx.Rollback()
 
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return hexutil.Uint64(chainConfig.ChainID.Uint64()), nil
+	return (*hexutil.Big)(chainConfig.ChainID), nil
 }
 
 // ChainID alias of ChainId - just for convenience
-func (api *APIImpl) ChainID(ctx context.Context) (hexutil.Uint64, error) {
+func (api *APIImpl) ChainID(ctx context.Context) (*hexutil.Big, error) {
 	return api.ChainId(ctx)
 }
 
@@ -113,7 +113,7 @@ func (api *APIImpl) GasPrice(ctx context.Context) (*hexutil.Big, error) {
 	if err != nil {
 		return nil, err
 	}
-	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO)
+	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), api.gpo)
 	tipcap, err := oracle.SuggestTipCap(ctx)
 	if err != nil {
 		return nil, err
@@ -125,24 +125,35 @@ func (api *APIImpl) GasPrice(ctx context.Context) (*hexutil.Big, error) {
 }
 
 // MaxPriorityFeePerGas returns a suggestion for a gas tip cap for dynamic fee transactions.
+// It's the 60th percentile (--rpc.gas-oracle.percentile) of effective tips of transactions from the last 20 blocks:
+// above median - to get included in a couple of blocks, but without overpaying for spikes of few blocks.
+// Never less than 1 gwei - tips below it are often not mined at all. Before London it's 0 - there are no tips
 func (api *APIImpl) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
+	if head := rawdb.ReadCurrentHeader(tx); head == nil || head.BaseFee == nil {
+		return (*hexutil.Big)(new(big.Int)), nil
+	}
 	cc, err := api.chainConfig(tx)
 	if err != nil {
 		return nil, err
 	}
-	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO)
+	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), api.gpo)
 	tipcap, err := oracle.SuggestTipCap(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if tipcap.Cmp(minPriorityFeePerGas) < 0 {
+		tipcap = new(big.Int).Set(minPriorityFeePerGas)
+	}
 	return (*hexutil.Big)(tipcap), err
 }
 
+var minPriorityFeePerGas = big.NewInt(params.GWei)
+
 type feeHistoryResult struct {
 	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
 	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
@@ -160,7 +171,7 @@ func (api *APIImpl) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex,
 	if err != nil {
 		return nil, err
 	}
-	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO)
+	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), api.gpo)
 
 	oldest, reward, baseFee, gasUsed, err := oracle.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
 	if err != nil {
@@ -212,7 +223,11 @@ func (b *GasPriceOracleBackend) ChainConfig() *params.ChainConfig {
 	return b.cc
 }
 func (b *GasPriceOracleBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return rawdb.ReadReceiptsByHash(b.tx, hash)
+	block, err := b.baseApi.blockByHashWithSenders(b.tx, hash)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return b.baseApi.getReceiptsWithoutLogs(ctx, b.tx, b.cc, block, block.Body().SendersFromTxs())
 }
 func (b *GasPriceOracleBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return nil, nil
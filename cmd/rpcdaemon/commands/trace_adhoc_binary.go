@@ -0,0 +1,371 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+)
+
+// Bits of the header of one account in the binary state diff
+const (
+	stateDiffBalance byte = 1 << iota // balance changed, followed by from and to
+	stateDiffNonce                    // nonce changed, followed by from and to
+	stateDiffCode                     // code changed, followed by from and to
+	stateDiffStorage                  // storage changed, followed by the number of slots and the slots
+	stateDiffBorn                     // account created ("+"), followed by balance, nonce and code
+	stateDiffDied                     // account removed ("-"), followed by balance, nonce and code
+)
+
+// maxStateDiffBytes - limit of length of byte strings (code is the longest), protects decoder from allocating
+// huge buffers on corrupted input
+const maxStateDiffBytes = 1 << 24
+
+// StateDiffEncoder - writes state diffs (as in TraceCallResult.StateDiff, after StateDiff.CompareStates) in compact
+// binary form. For every account: address, header byte with bitfield of changed fields, then only the changed fields.
+// Numbers are uvarints, byte strings are prefixed with uvarint length, storage values have leading zeros trimmed.
+// Accounts and slots are sorted, so the same diff is always encoded the same way
+type StateDiffEncoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewStateDiffEncoder(w io.Writer) *StateDiffEncoder {
+	return &StateDiffEncoder{w: w}
+}
+
+func (e *StateDiffEncoder) Encode(diff map[common.Address]*StateDiffAccount) error {
+	addrs := make([]common.Address, 0, len(diff))
+	for addr := range diff {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	e.buf = appendUvarint(e.buf[:0], uint64(len(addrs)))
+	for _, addr := range addrs {
+		if err := e.appendAccount(addr, diff[addr]); err != nil {
+			return fmt.Errorf("state diff of %x: %w", addr, err)
+		}
+	}
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func (e *StateDiffEncoder) appendAccount(addr common.Address, acc *StateDiffAccount) error {
+	var header byte
+	var balance [2]*hexutil.Big
+	var nonce [2]hexutil.Uint64
+	var code [2]hexutil.Bytes
+	switch v := acc.Balance.(type) {
+	case nil, string: // "=", or CompareStates wasn't called
+	case map[string]*StateDiffBalance:
+		change, ok := v["*"]
+		if !ok || change == nil {
+			return fmt.Errorf("unexpected balance %v", v)
+		}
+		header |= stateDiffBalance
+		balance[0], balance[1] = change.From, change.To
+	case map[string]*hexutil.Big:
+		if b, ok := v["+"]; ok {
+			header |= stateDiffBorn
+			balance[0] = b
+		} else if b, ok = v["-"]; ok {
+			header |= stateDiffDied
+			balance[0] = b
+		} else {
+			return fmt.Errorf("unexpected balance %v", v)
+		}
+	default:
+		return fmt.Errorf("unexpected balance type %T", v)
+	}
+	switch v := acc.Nonce.(type) {
+	case nil, string:
+	case map[string]*StateDiffNonce:
+		change, ok := v["*"]
+		if !ok || change == nil {
+			return fmt.Errorf("unexpected nonce %v", v)
+		}
+		header |= stateDiffNonce
+		nonce[0], nonce[1] = change.From, change.To
+	case map[string]hexutil.Uint64:
+		for _, n := range v {
+			nonce[0] = n
+		}
+	default:
+		return fmt.Errorf("unexpected nonce type %T", v)
+	}
+	switch v := acc.Code.(type) {
+	case nil, string:
+	case map[string]*StateDiffCode:
+		change, ok := v["*"]
+		if !ok || change == nil {
+			return fmt.Errorf("unexpected code %v", v)
+		}
+		header |= stateDiffCode
+		code[0], code[1] = change.From, change.To
+	case map[string]hexutil.Bytes:
+		for _, c := range v {
+			code[0] = c
+		}
+	default:
+		return fmt.Errorf("unexpected code type %T", v)
+	}
+	if len(acc.Storage) > 0 {
+		header |= stateDiffStorage
+	}
+
+	e.buf = append(append(e.buf, addr[:]...), header)
+	if header&(stateDiffBorn|stateDiffDied) != 0 {
+		e.appendBig(balance[0])
+		e.buf = appendUvarint(e.buf, uint64(nonce[0]))
+		e.appendBytes(code[0])
+	}
+	if header&stateDiffBalance != 0 {
+		e.appendBig(balance[0])
+		e.appendBig(balance[1])
+	}
+	if header&stateDiffNonce != 0 {
+		e.buf = appendUvarint(e.buf, uint64(nonce[0]))
+		e.buf = appendUvarint(e.buf, uint64(nonce[1]))
+	}
+	if header&stateDiffCode != 0 {
+		e.appendBytes(code[0])
+		e.appendBytes(code[1])
+	}
+	if header&stateDiffStorage == 0 {
+		return nil
+	}
+
+	keys := make([]common.Hash, 0, len(acc.Storage))
+	for key := range acc.Storage {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	e.buf = appendUvarint(e.buf, uint64(len(keys)))
+	for _, key := range keys {
+		e.buf = append(e.buf, key[:]...)
+		// created account has only new values of slots ("+"), others - old and new ("*")
+		if header&stateDiffBorn != 0 {
+			to, ok := acc.Storage[key]["+"].(*common.Hash)
+			if !ok {
+				return fmt.Errorf("unexpected storage of created account at %x: %v", key, acc.Storage[key])
+			}
+			e.appendBytes(bytes.TrimLeft(to[:], "\x00"))
+			continue
+		}
+		change, ok := acc.Storage[key]["*"].(*StateDiffStorage)
+		if !ok {
+			return fmt.Errorf("unexpected storage at %x: %v", key, acc.Storage[key])
+		}
+		e.appendBytes(bytes.TrimLeft(change.From[:], "\x00"))
+		e.appendBytes(bytes.TrimLeft(change.To[:], "\x00"))
+	}
+	return nil
+}
+
+func (e *StateDiffEncoder) appendBig(v *hexutil.Big) {
+	if v == nil {
+		e.appendBytes(nil)
+		return
+	}
+	e.appendBytes(v.ToInt().Bytes())
+}
+
+func (e *StateDiffEncoder) appendBytes(v []byte) {
+	e.buf = append(appendUvarint(e.buf, uint64(len(v))), v...)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+// StateDiffDecoder - reads state diffs written by StateDiffEncoder, restoring the same structure as
+// StateDiff.CompareStates produces: unchanged fields are "=", changed - "*", created and removed accounts - "+" and "-"
+type StateDiffDecoder struct {
+	r *bufio.Reader
+}
+
+func NewStateDiffDecoder(r io.Reader) *StateDiffDecoder {
+	return &StateDiffDecoder{r: bufio.NewReader(r)}
+}
+
+func (d *StateDiffDecoder) Decode() (map[common.Address]*StateDiffAccount, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(map[common.Address]*StateDiffAccount)
+	for i := uint64(0); i < n; i++ {
+		var addr common.Address
+		if _, err = io.ReadFull(d.r, addr[:]); err != nil {
+			return nil, err
+		}
+		acc, err := d.readAccount()
+		if err != nil {
+			return nil, fmt.Errorf("state diff of %x: %w", addr, err)
+		}
+		diff[addr] = acc
+	}
+	return diff, nil
+}
+
+func (d *StateDiffDecoder) readAccount() (*StateDiffAccount, error) {
+	header, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	acc := &StateDiffAccount{Balance: "=", Nonce: "=", Code: "=", Storage: make(map[common.Hash]map[string]interface{})}
+	if kind := header & (stateDiffBorn | stateDiffDied); kind != 0 {
+		if kind == stateDiffBorn|stateDiffDied || header&(stateDiffBalance|stateDiffNonce|stateDiffCode) != 0 {
+			return nil, fmt.Errorf("invalid header %08b", header)
+		}
+		tag := "+"
+		if kind == stateDiffDied {
+			tag = "-"
+		}
+		balance, err := d.readBig()
+		if err != nil {
+			return nil, err
+		}
+		nonce, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return nil, err
+		}
+		code, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		acc.Balance = map[string]*hexutil.Big{tag: balance}
+		acc.Nonce = map[string]hexutil.Uint64{tag: hexutil.Uint64(nonce)}
+		acc.Code = map[string]hexutil.Bytes{tag: code}
+	}
+	if header&stateDiffBalance != 0 {
+		from, err := d.readBig()
+		if err != nil {
+			return nil, err
+		}
+		to, err := d.readBig()
+		if err != nil {
+			return nil, err
+		}
+		acc.Balance = map[string]*StateDiffBalance{"*": {From: from, To: to}}
+	}
+	if header&stateDiffNonce != 0 {
+		from, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return nil, err
+		}
+		to, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return nil, err
+		}
+		acc.Nonce = map[string]*StateDiffNonce{"*": {From: hexutil.Uint64(from), To: hexutil.Uint64(to)}}
+	}
+	if header&stateDiffCode != 0 {
+		from, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		to, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		acc.Code = map[string]*StateDiffCode{"*": {From: from, To: to}}
+	}
+	if header&stateDiffStorage == 0 {
+		return acc, nil
+	}
+
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		var key common.Hash
+		if _, err = io.ReadFull(d.r, key[:]); err != nil {
+			return nil, err
+		}
+		if header&stateDiffBorn != 0 {
+			to, err := d.readHash()
+			if err != nil {
+				return nil, err
+			}
+			acc.Storage[key] = map[string]interface{}{"+": &to}
+			continue
+		}
+		from, err := d.readHash()
+		if err != nil {
+			return nil, err
+		}
+		to, err := d.readHash()
+		if err != nil {
+			return nil, err
+		}
+		acc.Storage[key] = map[string]interface{}{"*": &StateDiffStorage{From: from, To: to}}
+	}
+	return acc, nil
+}
+
+func (d *StateDiffDecoder) readBig() (*hexutil.Big, error) {
+	b, err := d.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(new(big.Int).SetBytes(b)), nil
+}
+
+func (d *StateDiffDecoder) readHash() (common.Hash, error) {
+	b, err := d.readBytes()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(b) > common.HashLength {
+		return common.Hash{}, fmt.Errorf("storage value is %d bytes long", len(b))
+	}
+	return common.BytesToHash(b), nil
+}
+
+func (d *StateDiffDecoder) readBytes() ([]byte, error) {
+	l, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return nil, err
+	}
+	if l > maxStateDiffBytes {
+		return nil, fmt.Errorf("too long byte string: %d", l)
+	}
+	b := make([]byte, l)
+	if _, err = io.ReadFull(d.r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// MarshalBinary - see StateDiffEncoder
+func (sd *StateDiff) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewStateDiffEncoder(&buf).Encode(sd.sdMap); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary - see StateDiffDecoder
+func (sd *StateDiff) UnmarshalBinary(data []byte) error {
+	d := NewStateDiffDecoder(bytes.NewReader(data))
+	sdMap, err := d.Decode()
+	if err != nil {
+		return err
+	}
+	if _, err = d.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("unexpected data after state diff")
+	}
+	sd.sdMap = sdMap
+	return nil
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"encoding/json"
+	"math/big"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -106,3 +107,49 @@ func TestReplayBlockTransactions(t *testing.T) {
 	v := addrDiff.Balance.(map[string]*hexutil.Big)["+"].ToInt().Uint64()
 	require.Equal(t, uint64(1_000_000_000_000_000), v)
 }
+
+func TestStateDiffBinary(t *testing.T) {
+	changed := &StateDiffAccount{
+		Balance: map[string]*StateDiffBalance{"*": {From: (*hexutil.Big)(big.NewInt(1000)), To: (*hexutil.Big)(big.NewInt(900))}},
+		Nonce:   map[string]*StateDiffNonce{"*": {From: 1, To: 2}},
+		Code:    "=",
+		Storage: make(map[common.Hash]map[string]interface{}),
+	}
+	for i := 0; i < 100; i++ {
+		changed.Storage[common.BigToHash(big.NewInt(int64(i)))] = map[string]interface{}{"*": &StateDiffStorage{From: common.BigToHash(big.NewInt(int64(i))), To: common.BigToHash(big.NewInt(int64(i + 1)))}}
+	}
+	to := common.HexToHash("0x05")
+	sd := &StateDiff{sdMap: map[common.Address]*StateDiffAccount{
+		common.HexToAddress("0x01"): changed,
+		common.HexToAddress("0x02"): {
+			Balance: map[string]*hexutil.Big{"+": (*hexutil.Big)(big.NewInt(1))},
+			Nonce:   map[string]hexutil.Uint64{"+": 1},
+			Code:    map[string]hexutil.Bytes{"+": {0x60, 0x00}},
+			Storage: map[common.Hash]map[string]interface{}{{1}: {"+": &to}},
+		},
+		common.HexToAddress("0x03"): {
+			Balance: map[string]*hexutil.Big{"-": (*hexutil.Big)(big.NewInt(7))},
+			Nonce:   map[string]hexutil.Uint64{"-": 3},
+			Code:    map[string]hexutil.Bytes{"-": {0x60, 0x01}},
+			Storage: make(map[common.Hash]map[string]interface{}),
+		},
+	}}
+
+	encoded, err := sd.MarshalBinary()
+	require.NoError(t, err)
+	again, err := sd.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, encoded, again)
+
+	var decoded StateDiff
+	require.NoError(t, decoded.UnmarshalBinary(encoded))
+	expected, err := json.Marshal(sd.sdMap)
+	require.NoError(t, err)
+	actual, err := json.Marshal(decoded.sdMap)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(actual))
+	require.Less(t, 5*len(encoded), len(expected))
+
+	require.Error(t, decoded.UnmarshalBinary(encoded[:len(encoded)-1]))
+	require.Error(t, decoded.UnmarshalBinary(append(encoded, 0)))
+}
@@ -180,6 +180,18 @@ func (back *RemoteBackend) BodyRlp(ctx context.Context, tx kv.Getter, hash commo
 func (back *RemoteBackend) Body(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (body *types.Body, err error) {
 	return back.blockReader.Body(ctx, tx, hash, blockHeight)
 }
+func (back *RemoteBackend) FrozenReceipts(ctx context.Context, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	if r, ok := back.blockReader.(services.FrozenReceiptsReader); ok {
+		return r.FrozenReceipts(ctx, block, senders)
+	}
+	return nil, nil
+}
+func (back *RemoteBackend) FrozenBodyTxIds(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (uint64, uint32, bool, error) {
+	if r, ok := back.blockReader.(services.FrozenBodyReader); ok {
+		return r.FrozenBodyTxIds(ctx, tx, hash, blockHeight)
+	}
+	return 0, 0, false, nil
+}
 func (back *RemoteBackend) Header(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (*types.Header, error) {
 	return back.blockReader.Header(ctx, tx, hash, blockHeight)
 }
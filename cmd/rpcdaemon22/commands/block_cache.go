@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+var (
+	blockCacheHits   = metrics.GetOrCreateCounter("block_cache_hit")
+	blockCacheMisses = metrics.GetOrCreateCounter("block_cache_miss")
+	_                = metrics.NewGauge("block_cache_hit_rate", func() float64 {
+		hits, misses := blockCacheHits.Get(), blockCacheMisses.Get()
+		if hits+misses == 0 {
+			return 0
+		}
+		return float64(hits) / float64(hits+misses)
+	})
+)
+
+// BlockCache - canonical blocks with senders, by number (LRU) and by hash (index of blocks in the LRU).
+// Block of a number may change on reorg, so reads are consistent with the caller's transaction: GetByNumber
+// and Get return the block only if it has the hash the caller has read from its tx. Block tags ("latest",
+// "safe", "finalized") are resolved to numbers in the same tx, so they can't see a stale block either.
+// Additionally numbers starting from a new head are invalidated, see OnNewHead. Thread-safe
+type BlockCache struct {
+	byNumber *lru.Cache // uint64 -> *types.Block
+	byHash   sync.Map   // common.Hash -> uint64, only for blocks in byNumber
+}
+
+func NewBlockCache(size int) *BlockCache {
+	c := &BlockCache{}
+	var err error
+	c.byNumber, err = lru.NewWithEvict(size, func(_, value interface{}) {
+		c.byHash.Delete(value.(*types.Block).Hash())
+	})
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Get - block by hash, nil if it's not cached
+func (c *BlockCache) Get(hash common.Hash) *types.Block {
+	if number, ok := c.byHash.Load(hash); ok {
+		return c.GetByNumber(number.(uint64), hash)
+	}
+	blockCacheMisses.Inc()
+	return nil
+}
+
+// GetByNumber - block of `number`, if it's the block with canonical `hash` (read from the caller's tx)
+func (c *BlockCache) GetByNumber(number uint64, hash common.Hash) *types.Block {
+	if it, ok := c.byNumber.Get(number); ok {
+		if block := it.(*types.Block); block.Hash() == hash {
+			blockCacheHits.Inc()
+			return block
+		}
+	}
+	blockCacheMisses.Inc()
+	return nil
+}
+
+// Add - caches canonical block, replacing the one previously cached for its number
+func (c *BlockCache) Add(block *types.Block) {
+	// calc fields before put to cache
+	for _, txn := range block.Transactions() {
+		txn.Hash()
+	}
+	hash := block.Hash()
+	if it, ok := c.byNumber.Peek(block.NumberU64()); ok {
+		c.byHash.Delete(it.(*types.Block).Hash()) // replacing value doesn't call eviction callback
+	}
+	c.byNumber.Add(block.NumberU64(), block)
+	c.byHash.Store(hash, block.NumberU64())
+}
+
+// OnNewHead - invalidates blocks starting from the number of the new head: on reorg they are replaced
+func (c *BlockCache) OnNewHead(header *types.Header) {
+	from := header.Number.Uint64()
+	for _, key := range c.byNumber.Keys() {
+		if key.(uint64) >= from {
+			c.byNumber.Remove(key)
+		}
+	}
+}
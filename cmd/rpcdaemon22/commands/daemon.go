@@ -7,6 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon22/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
@@ -14,19 +15,21 @@ import (
 
 // APIList describes the list of available RPC apis
 func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
-	starknet starknet.CAIROVMClient, filters *rpchelper.Filters, stateCache kvcache.Cache,
+	starknet starknet.CAIROVMClient, filters *rpchelper.Filters, stateCache kvcache.Cache, historyCache *state.HistoryCache,
 	blockReader services.FullBlockReader, agg *libstate.Aggregator, txNums []uint64, cfg httpcfg.HttpCfg) (list []rpc.API) {
 
 	base := NewBaseApi(filters, stateCache, blockReader, agg, txNums, cfg.WithDatadir)
+	base.setHistoryCache(historyCache)
 	if cfg.TevmEnabled {
 		base.EnableTevmExperiment()
 	}
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.GasOraclePercentile)
 	erigonImpl := NewErigonAPI(base, db, eth)
 	starknetImpl := NewStarknetAPI(base, db, starknet, txPool)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
 	netImpl := NewNetAPIImpl(eth)
 	debugImpl := NewPrivateDebugAPI(base, db, cfg.Gascap)
+	chaindbImpl := NewChaindbAPI(db)
 	traceImpl := NewTraceAPI(base, db, &cfg)
 	web3Impl := NewWeb3APIImpl(eth)
 	dbImpl := NewDBAPIImpl() /* deprecated */
@@ -51,6 +54,13 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 				Service:   PrivateDebugAPI(debugImpl),
 				Version:   "1.0",
 			})
+			list = append(list, rpc.API{
+				Namespace:     "debug",
+				Public:        false,
+				Service:       ChaindbAPI(chaindbImpl),
+				Version:       "1.0",
+				Authenticated: true,
+			})
 		case "net":
 			list = append(list, rpc.API{
 				Namespace: "net",
@@ -79,7 +79,7 @@ func (api *ErigonImpl) GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Ti
 	firstHeaderTime := firstHeader.Time
 
 	if currenttHeaderTime <= uintTimestamp {
-		blockResponse, err := buildBlockResponse(tx, highestNumber, fullTx)
+		blockResponse, err := api.buildBlockResponse(tx, highestNumber, fullTx)
 		if err != nil {
 			return nil, err
 		}
@@ -88,7 +88,7 @@ func (api *ErigonImpl) GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Ti
 	}
 
 	if firstHeaderTime >= uintTimestamp {
-		blockResponse, err := buildBlockResponse(tx, 0, fullTx)
+		blockResponse, err := api.buildBlockResponse(tx, 0, fullTx)
 		if err != nil {
 			return nil, err
 		}
@@ -105,14 +105,14 @@ func (api *ErigonImpl) GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Ti
 	resultingHeader := rawdb.ReadHeaderByNumber(tx, uint64(blockNum))
 
 	if resultingHeader.Time > uintTimestamp {
-		response, err := buildBlockResponse(tx, uint64(blockNum)-1, fullTx)
+		response, err := api.buildBlockResponse(tx, uint64(blockNum)-1, fullTx)
 		if err != nil {
 			return nil, err
 		}
 		return response, nil
 	}
 
-	response, err := buildBlockResponse(tx, uint64(blockNum), fullTx)
+	response, err := api.buildBlockResponse(tx, uint64(blockNum), fullTx)
 	if err != nil {
 		return nil, err
 	}
@@ -120,8 +120,8 @@ func (api *ErigonImpl) GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Ti
 	return response, nil
 }
 
-func buildBlockResponse(db kv.Tx, blockNum uint64, fullTx bool) (map[string]interface{}, error) {
-	block, err := rawdb.ReadBlockByNumber(db, blockNum)
+func (api *ErigonImpl) buildBlockResponse(db kv.Tx, blockNum uint64, fullTx bool) (map[string]interface{}, error) {
+	block, err := api.blockByNumberWithSenders(db, blockNum)
 	if err != nil {
 		return nil, err
 	}
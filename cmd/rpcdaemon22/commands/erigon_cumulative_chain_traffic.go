@@ -23,7 +23,11 @@ func (api *ErigonImpl) CumulativeChainTraffic(ctx context.Context, blockNr rpc.B
 		return ChainTraffic{}, err
 	}
 
-	_, baseTxId, txCount, err := rawdb.ReadBodyByNumber(tx, blockNumber)
+	hash, err := rawdb.ReadCanonicalHash(tx, blockNumber)
+	if err != nil {
+		return ChainTraffic{}, err
+	}
+	baseTxId, txCount, _, err := api.bodyTxIds(ctx, tx, hash, blockNumber)
 	if err != nil {
 		return ChainTraffic{}, err
 	}
@@ -12,6 +12,7 @@ import (
 	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/rpc"
 )
 
@@ -58,6 +59,22 @@ func (api *APIImpl) GetTransactionCount(ctx context.Context, address common.Addr
 		return nil, fmt.Errorf("getTransactionCount cannot open tx: %w", err1)
 	}
 	defer tx.Rollback()
+	blockNumber, _, latest, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if !latest {
+		r, err := state.NewHistoricalNonceReader(tx, api.historyCache)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		n, err := r.GetHistoricalNonce(address, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		return (*hexutil.Uint64)(&n), nil
+	}
 	reader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, api.filters, api.stateCache)
 	if err != nil {
 		return nil, err
@@ -6,7 +6,6 @@ import (
 	"math/big"
 	"sync"
 
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -17,8 +16,11 @@ import (
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	ethFilters "github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/eth/gasprice"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
@@ -70,7 +72,7 @@ type EthAPI interface {
 	// System related (see ./eth_system.go)
 	BlockNumber(ctx context.Context) (hexutil.Uint64, error)
 	Syncing(ctx context.Context) (interface{}, error)
-	ChainId(ctx context.Context) (hexutil.Uint64, error) /* called eth_protocolVersion elsewhere */
+	ChainId(ctx context.Context) (*hexutil.Big, error) /* called eth_protocolVersion elsewhere */
 	ProtocolVersion(_ context.Context) (hexutil.Uint, error)
 	GasPrice(_ context.Context) (*hexutil.Big, error)
 
@@ -95,8 +97,9 @@ type EthAPI interface {
 
 type BaseAPI struct {
 	stateCache   kvcache.Cache // thread-safe
-	blocksLRU    *lru.Cache    // thread-safe
+	blocks       *BlockCache   // thread-safe
 	filters      *rpchelper.Filters
+	historyCache *state.HistoryCache // thread-safe, nil if disabled
 	_chainConfig *params.ChainConfig
 	_genesis     *types.Block
 	_genesisLock sync.RWMutex
@@ -113,12 +116,29 @@ func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader serv
 	if !singleNodeMode {
 		blocksLRUSize = 512
 	}
-	blocksLRU, err := lru.New(blocksLRUSize)
-	if err != nil {
-		panic(err)
-	}
+	api := &BaseAPI{filters: f, stateCache: stateCache, blocks: NewBlockCache(blocksLRUSize), _blockReader: blockReader, _txnReader: blockReader, _agg: agg, _txNums: txNums}
+	api.subscribeHeads()
+	return api
+}
 
-	return &BaseAPI{filters: f, stateCache: stateCache, blocksLRU: blocksLRU, _blockReader: blockReader, _txnReader: blockReader, _agg: agg, _txNums: txNums}
+// subscribeHeads - invalidates cached blocks on new heads until the filters are stopped
+func (api *BaseAPI) subscribeHeads() {
+	if api.filters == nil {
+		return
+	}
+	heads := make(chan *types.Header, 8)
+	id := api.filters.SubscribeNewHeads(heads)
+	go func() {
+		<-api.filters.Done()
+		// the reader below keeps draining, so filters can't block on sending to it, and they don't send after unsubscribe
+		api.filters.UnsubscribeHeads(id)
+		close(heads)
+	}()
+	go func() {
+		for h := range heads {
+			api.blocks.OnNewHead(h)
+		}
+	}()
 }
 
 func (api *BaseAPI) chainConfig(tx kv.Tx) (*params.ChainConfig, error) {
@@ -128,6 +148,14 @@ func (api *BaseAPI) chainConfig(tx kv.Tx) (*params.ChainConfig, error) {
 
 func (api *BaseAPI) EnableTevmExperiment() { api.TevmEnabled = true }
 
+// setHistoryCache - cache of historical state shared by tracing calls, purged on unwind by the state change stream
+func (api *BaseAPI) setHistoryCache(cache *state.HistoryCache) { api.historyCache = cache }
+
+// historicalStateReader - state at the beginning of `blockNr`, values are cached in api.historyCache
+func (api *BaseAPI) historicalStateReader(tx kv.Tx, blockNr uint64) *state.PlainState {
+	return state.NewPlainState(tx, blockNr).SetHistoryCache(api.historyCache)
+}
+
 // nolint:unused
 func (api *BaseAPI) genesis(tx kv.Tx) (*types.Block, error) {
 	_, genesis, err := api.chainConfigWithGenesis(tx)
@@ -146,9 +174,9 @@ func (api *BaseAPI) blockByNumberWithSenders(tx kv.Tx, number uint64) (*types.Bl
 	return api.blockWithSenders(tx, hash, number)
 }
 func (api *BaseAPI) blockByHashWithSenders(tx kv.Tx, hash common.Hash) (*types.Block, error) {
-	if api.blocksLRU != nil {
-		if it, ok := api.blocksLRU.Get(hash); ok && it != nil {
-			return it.(*types.Block), nil
+	if api.blocks != nil {
+		if block := api.blocks.Get(hash); block != nil {
+			return block, nil
 		}
 	}
 	number := rawdb.ReadHeaderNumber(tx, hash)
@@ -158,9 +186,9 @@ func (api *BaseAPI) blockByHashWithSenders(tx kv.Tx, hash common.Hash) (*types.B
 	return api.blockWithSenders(tx, hash, *number)
 }
 func (api *BaseAPI) blockWithSenders(tx kv.Tx, hash common.Hash, number uint64) (*types.Block, error) {
-	if api.blocksLRU != nil {
-		if it, ok := api.blocksLRU.Get(hash); ok && it != nil {
-			return it.(*types.Block), nil
+	if api.blocks != nil {
+		if block := api.blocks.GetByNumber(number, hash); block != nil {
+			return block, nil
 		}
 	}
 	block, _, err := api._blockReader.BlockWithSenders(context.Background(), tx, hash, number)
@@ -175,17 +203,30 @@ func (api *BaseAPI) blockWithSenders(tx kv.Tx, hash common.Hash, number uint64)
 	if block.Transactions().Len() == 0 {
 		return block, nil
 	}
-	if api.blocksLRU != nil {
-		// calc fields before put to cache
-		for _, txn := range block.Transactions() {
-			txn.Hash()
+	if api.blocks != nil {
+		// cache is by number, so only canonical blocks go there
+		canonicalHash, err := rawdb.ReadCanonicalHash(tx, number)
+		if err != nil {
+			return nil, err
+		}
+		if canonicalHash == hash {
+			api.blocks.Add(block)
 		}
-		block.Hash()
-		api.blocksLRU.Add(hash, block)
 	}
 	return block, nil
 }
 
+// bodyTxIds - like rawdb.ReadBody, finds canonical bodies moved to the freezer too. ok is false if the body is not found
+func (api *BaseAPI) bodyTxIds(ctx context.Context, tx kv.Tx, hash common.Hash, number uint64) (baseTxId uint64, txAmount uint32, ok bool, err error) {
+	if body, baseTxId, txAmount := rawdb.ReadBody(tx, hash, number); body != nil {
+		return baseTxId, txAmount, true, nil
+	}
+	if r, ok := api._blockReader.(services.FrozenBodyReader); ok {
+		return r.FrozenBodyTxIds(ctx, tx, hash, number)
+	}
+	return 0, 0, false, nil
+}
+
 func (api *BaseAPI) chainConfigWithGenesis(tx kv.Tx) (*params.ChainConfig, *types.Block, error) {
 	api._genesisLock.RLock()
 	cc, genesisBlock := api._chainConfig, api._genesis
@@ -237,13 +278,18 @@ type APIImpl struct {
 	mining     txpool.MiningClient
 	db         kv.RoDB
 	GasCap     uint64
+	gpo        gasprice.Config
 }
 
-// NewEthAPI returns APIImpl instance
-func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64) *APIImpl {
+// NewEthAPI returns APIImpl instance. gasOraclePercentile - see gasprice.Config.Percentile, 0 means default
+func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64, gasOraclePercentile int) *APIImpl {
 	if gascap == 0 {
 		gascap = uint64(math.MaxUint64 / 2)
 	}
+	gpo := ethconfig.Defaults.GPO
+	if gasOraclePercentile > 0 {
+		gpo.Percentile = gasOraclePercentile
+	}
 
 	return &APIImpl{
 		BaseAPI:    base,
@@ -252,6 +298,7 @@ func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpoo
 		txPool:     txPool,
 		mining:     mining,
 		GasCap:     gascap,
+		gpo:        gpo,
 	}
 }
 
@@ -288,12 +288,13 @@ func (api *APIImpl) GetBlockTransactionCountByNumber(ctx context.Context, blockN
 	if err != nil {
 		return nil, err
 	}
-	body, _, txAmount, err := rawdb.ReadBodyByNumber(tx, blockNum)
+	blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
 	if err != nil {
 		return nil, err
 	}
-	if body == nil {
-		return nil, nil
+	_, txAmount, ok, err := api.bodyTxIds(ctx, tx, blockHash, blockNum)
+	if err != nil || !ok {
+		return nil, err
 	}
 	n := hexutil.Uint(txAmount)
 	return &n, nil
@@ -311,9 +312,9 @@ func (api *APIImpl) GetBlockTransactionCountByHash(ctx context.Context, blockHas
 	if num == nil {
 		return nil, nil
 	}
-	body, _, txAmount := rawdb.ReadBody(tx, blockHash, *num)
-	if body == nil {
-		return nil, nil
+	_, txAmount, ok, err := api.bodyTxIds(ctx, tx, blockHash, *num)
+	if err != nil || !ok {
+		return nil, err
 	}
 	n := hexutil.Uint(txAmount)
 	return &n, nil
@@ -22,14 +22,24 @@ import (
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/filters"
 	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
 )
 
 func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address) (types.Receipts, error) {
-	if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
-		return cached, nil
+	mode, err := prune.GetReceiptStorageMode(tx)
+	if err != nil {
+		return nil, err
+	}
+	// without --receipt-storage-mode=full receipts are stored without logs or not stored, re-execute the block.
+	// eth_getTransactionReceipt and eth_getBlockReceipts don't get here in these modes, see checkReceiptStorage
+	if mode.WriteLogs() {
+		if stored, err := api.storedReceipts(ctx, tx, block, senders); err != nil || stored != nil {
+			return stored, err
+		}
 	}
 
 	getHeader := func(hash common.Hash, number uint64) *types.Header {
@@ -40,7 +50,7 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 		return h
 	}
 	contractHasTEVM := ethdb.GetHasTEVM(tx)
-	_, _, _, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, block.Hash(), 0)
+	_, _, _, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, block.Hash(), 0, api.historyCache)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +76,39 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 	return receipts, nil
 }
 
+// getReceiptsWithoutLogs - for callers which need only status and gas used of receipts: logs are empty if the node
+// runs with --receipt-storage-mode=minimal, which stores receipts without them. Re-executes the block if receipts are not stored
+func (api *BaseAPI) getReceiptsWithoutLogs(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	if stored, err := api.storedReceipts(ctx, tx, block, senders); err != nil || stored != nil {
+		return stored, err
+	}
+	return api.getReceipts(ctx, tx, chainConfig, block, senders)
+}
+
+// storedReceipts - receipts from the db or from the freezer, nil if they are not stored
+func (api *BaseAPI) storedReceipts(ctx context.Context, tx kv.Tx, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
+		return cached, nil
+	}
+	if r, ok := api._blockReader.(services.FrozenReceiptsReader); ok {
+		return r.FrozenReceipts(ctx, block, senders)
+	}
+	return nil, nil
+}
+
+// checkReceiptStorage - receipts with logs and log indices are stored only if the node runs with --receipt-storage-mode=full.
+// Methods returning receipts or logs as stored fail without it instead of re-executing blocks
+func checkReceiptStorage(tx kv.Tx, method string) error {
+	mode, err := prune.GetReceiptStorageMode(tx)
+	if err != nil {
+		return err
+	}
+	if !mode.WriteLogs() {
+		return fmt.Errorf("%s is not available: node runs with --receipt-storage-mode=%s", method, mode)
+	}
+	return nil
+}
+
 // GetLogs implements eth_getLogs. Returns an array of logs matching a given filter object.
 func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*types.Log, error) {
 	var begin, end uint64
@@ -76,6 +119,9 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 		return logs, beginErr
 	}
 	defer tx.Rollback()
+	if err := checkReceiptStorage(tx, "eth_getLogs"); err != nil {
+		return nil, err
+	}
 
 	if crit.BlockHash != nil {
 		number := rawdb.ReadHeaderNumber(tx, *crit.BlockHash)
@@ -111,6 +157,13 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 	if end < begin {
 		return nil, fmt.Errorf("end (%d) < begin (%d)", end, begin)
 	}
+	return api.getLogs(ctx, tx, begin, end, crit.Addresses, crit.Topics)
+}
+
+// getLogs - logs of blocks from begin to end (inclusive) matching given addresses and topics (see filterLogs),
+// transactions found via LogAddrIterator/LogTopicIterator of the aggregator are re-executed to get them
+func (api *BaseAPI) getLogs(ctx context.Context, tx kv.Tx, begin, end uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+	logs := []*types.Log{}
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
 		return nil, err
@@ -125,7 +178,7 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 	txNumbers := roaring64.New()
 	txNumbers.AddRange(fromTxNum, toTxNum) // [min,max)
 
-	topicsBitmap, err := getTopicsBitmap(api._agg, tx, crit.Topics, fromTxNum, toTxNum)
+	topicsBitmap, err := getTopicsBitmap(api._agg, tx, topics, fromTxNum, toTxNum)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +187,7 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 	}
 
 	var addrBitmap *roaring64.Bitmap
-	for _, addr := range crit.Addresses {
+	for _, addr := range addresses {
 		var bitmapForORing roaring64.Bitmap
 		it := api._agg.LogAddrIterator(addr.Bytes(), fromTxNum, toTxNum, nil)
 		for it.HasNext() {
@@ -204,7 +257,7 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([
 		if err != nil {
 			return nil, err
 		}
-		filtered := filterLogs(ibs.GetLogs(txHash), crit.Addresses, crit.Topics)
+		filtered := filterLogs(ibs.GetLogs(txHash), addresses, topics)
 		for _, log := range filtered {
 			log.BlockNumber = blockNum
 			log.BlockHash = lastBlockHash
@@ -258,6 +311,9 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, hash common.Hash)
 		return nil, err
 	}
 	defer tx.Rollback()
+	if err = checkReceiptStorage(tx, "eth_getTransactionReceipt"); err != nil {
+		return nil, err
+	}
 
 	var borTx *types.Transaction
 	var blockHash common.Hash
@@ -342,6 +398,9 @@ func (api *APIImpl) GetBlockReceipts(ctx context.Context, number rpc.BlockNumber
 		return nil, err
 	}
 	defer tx.Rollback()
+	if err = checkReceiptStorage(tx, "eth_getBlockReceipts"); err != nil {
+		return nil, err
+	}
 
 	blockNum, err := getBlockNumber(number, tx)
 	if err != nil {
@@ -8,8 +8,10 @@ import (
 	"math/big"
 
 	txPoolProto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
@@ -31,9 +33,37 @@ func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutil.By
 	if err := checkTxFee(txn.GetPrice().ToBig(), txn.GetGas(), ethconfig.Defaults.RPCTxFeeCap); err != nil {
 		return common.Hash{}, err
 	}
+	if err := types.ValidateNonce(txn.GetNonce()); err != nil {
+		return common.Hash{}, err
+	}
 	if !txn.Protected() {
 		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
+	// Read what's needed from the db and release the tx: it must not stay open while the pool is called
+	var cc *params.ChainConfig
+	var header *types.Header
+	var blockNum *uint64
+	if err := api.db.View(ctx, func(tx kv.Tx) error {
+		var err error
+		if cc, err = api.chainConfig(tx); err != nil {
+			return err
+		}
+		header = rawdb.ReadCurrentHeader(tx)
+		blockNum = rawdb.ReadCurrentBlockNumber(tx)
+		return nil
+	}); err != nil {
+		return common.Hash{}, err
+	}
+	// Don't pollute the pool with transactions which can't be included into any block
+	if header != nil {
+		if err := core.ValidateTransactionGasLimit(txn, header.GasLimit); err != nil {
+			return common.Hash{}, err
+		}
+		if err := core.ValidateTransactionIntrinsicGas(txn, cc.Rules(header.Number.Uint64()+1)); err != nil {
+			return common.Hash{}, err
+		}
+	}
+
 	hash := txn.Hash()
 	res, err := api.txPool.Add(ctx, &txPoolProto.AddRequest{RlpTxs: [][]byte{encodedTx}})
 	if err != nil {
@@ -44,21 +74,10 @@ func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutil.By
 		return hash, fmt.Errorf("%s: %s", txPoolProto.ImportResult_name[int32(res.Imported[0])], res.Errors[0])
 	}
 
-	tx, err := api.db.BeginRo(ctx)
-	if err != nil {
-		return common.Hash{}, err
-	}
-	defer tx.Rollback()
-
 	// Print a log with full txn details for manual investigations and interventions
-	blockNum := rawdb.ReadCurrentBlockNumber(tx)
 	if blockNum == nil {
 		return common.Hash{}, err
 	}
-	cc, err := api.chainConfig(tx)
-	if err != nil {
-		return common.Hash{}, err
-	}
 	signer := types.MakeSigner(cc, *blockNum)
 	from, err := txn.Sender(*signer)
 	if err != nil {
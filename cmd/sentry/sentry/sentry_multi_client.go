@@ -254,7 +254,7 @@ type MultiClient struct {
 	networkId   uint64
 	db          kv.RwDB
 	Engine      consensus.Engine
-	blockReader services.HeaderAndCanonicalReader
+	blockReader services.FullBlockReader
 	logPeerInfo bool
 }
 
@@ -267,7 +267,7 @@ func NewMultiClient(
 	networkID uint64,
 	sentries []direct.SentryClient,
 	syncCfg ethconfig.Sync,
-	blockReader services.HeaderAndCanonicalReader,
+	blockReader services.FullBlockReader,
 	logPeerInfo bool,
 ) (*MultiClient, error) {
 	hd := headerdownload.NewHeaderDownload(
@@ -559,7 +559,7 @@ func (cs *MultiClient) getBlockBodies66(ctx context.Context, inreq *proto_sentry
 		return err
 	}
 	defer tx.Rollback()
-	response := eth.AnswerGetBlockBodiesQuery(tx, query.GetBlockBodiesPacket)
+	response := eth.AnswerGetBlockBodiesQuery(tx, query.GetBlockBodiesPacket, cs.blockReader)
 	tx.Rollback()
 	b, err := rlp.EncodeToBytes(&eth.BlockBodiesRLPPacket66{
 		RequestId:            query.RequestId,
@@ -102,7 +102,12 @@ var (
 
 	AncientFlag = DirectoryFlag{
 		Name:  "datadir.ancient",
-		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+		Usage: "Data directory of the freezer: bodies and receipts of old blocks are moved there from chaindata (default = disabled, requires --snapshots=false)",
+	}
+	AncientKeepRecentFlag = cli.Uint64Flag{
+		Name:  "ancient.keep-recent",
+		Usage: "Amount of recent blocks, bodies and receipts of which stay in chaindata when --datadir.ancient is set",
+		Value: ethconfig.Defaults.AncientKeepRecent,
 	}
 	MinFreeDiskSpaceFlag = DirectoryFlag{
 		Name:  "datadir.minfreedisk",
@@ -223,6 +228,10 @@ var (
 		Name:  "watch-the-burn",
 		Usage: "Enable WatchTheBurn stage to keep track of ETH issuance",
 	}
+	TrackAccountVersionsFlag = cli.BoolFlag{
+		Name:  "state.track-versions",
+		Usage: "Record number of the block which last modified every account, for debugging of state corruption. Adds 28 bytes per account to chaindata",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -380,6 +389,11 @@ var (
 		Usage: "Sets a cap on gas that can be used in eth_call/estimateGas",
 		Value: 50000000,
 	}
+	RpcGasOraclePercentileFlag = cli.IntFlag{
+		Name:  "rpc.gas-oracle.percentile",
+		Usage: "Percentile of priority fees of recent transactions suggested by eth_maxPriorityFeePerGas and eth_gasPrice",
+		Value: ethconfig.Defaults.GPO.Percentile,
+	}
 	RpcTraceCompatFlag = cli.BoolFlag{
 		Name:  "trace.compat",
 		Usage: "Bug for bug compatibility with OE for trace_ routines",
@@ -1375,6 +1389,8 @@ func CheckExclusive(ctx *cli.Context, args ...interface{}) {
 func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.Config) {
 	cfg.Sync.UseSnapshots = ctx.GlobalBoolT(SnapshotFlag.Name)
 	cfg.Dirs = nodeConfig.Dirs
+	cfg.AncientDir = ctx.GlobalString(AncientFlag.Name)
+	cfg.AncientKeepRecent = ctx.GlobalUint64(AncientKeepRecentFlag.Name)
 	cfg.Snapshot.KeepBlocks = ctx.GlobalBool(SnapKeepBlocksFlag.Name)
 	cfg.Snapshot.Produce = !ctx.GlobalBool(SnapStopFlag.Name)
 	if !ctx.GlobalIsSet(DownloaderAddrFlag.Name) {
@@ -1429,6 +1445,7 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	cfg.Ethstats = ctx.GlobalString(EthStatsURLFlag.Name)
 	cfg.P2PEnabled = len(nodeConfig.P2P.SentryAddr) == 0
 	cfg.EnabledIssuance = ctx.GlobalIsSet(EnabledIssuance.Name)
+	cfg.TrackAccountVersions = ctx.GlobalBool(TrackAccountVersionsFlag.Name)
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkID = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -17,7 +17,12 @@
 package core
 
 import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/secp256k1"
+	"golang.org/x/sync/errgroup"
 )
 
 // CalcGasLimit computes the gas limit of the next block after parent. It aims
@@ -45,3 +50,56 @@ func CalcGasLimit(parentGasLimit, desiredLimit uint64) uint64 {
 	}
 	return limit
 }
+
+// ValidateBlockBodyAndReceipts recovers senders of all block transactions (in
+// parallel, see recoverBlockSenders) and concurrently checks receipts root of
+// the block. Recovered senders are cached in the transactions. Receipts root is
+// checked from Byzantium only, as in ExecuteBlockEphemerally.
+func ValidateBlockBodyAndReceipts(config *params.ChainConfig, block *types.Block, receipts types.Receipts) error {
+	var g errgroup.Group
+	g.Go(func() error {
+		return recoverBlockSenders(config, block)
+	})
+	g.Go(func() error {
+		if !config.IsByzantium(block.NumberU64()) {
+			return nil
+		}
+		if receiptSha := types.DeriveSha(receipts); receiptSha != block.ReceiptHash() {
+			return fmt.Errorf("invalid receipt root hash of block %d (remote: %x local: %x)", block.NumberU64(), block.ReceiptHash(), receiptSha)
+		}
+		return nil
+	})
+	return g.Wait()
+}
+
+// recoverBlockSenders splits transactions between as many goroutines as
+// secp256k1 has contexts. Sender already cached in a transaction must match the
+// recovered one.
+func recoverBlockSenders(config *params.ChainConfig, block *types.Block) error {
+	txs := block.Transactions()
+	signer := types.MakeSigner(config, block.NumberU64())
+	threads := secp256k1.NumOfContexts()
+	batch := (len(txs) + threads - 1) / threads
+	var g errgroup.Group
+	for threadNo, from := 0, 0; from < len(txs); threadNo, from = threadNo+1, from+batch {
+		threadNo, from, to := threadNo, from, from+batch
+		if to > len(txs) {
+			to = len(txs)
+		}
+		g.Go(func() error {
+			cryptoContext := secp256k1.ContextForThread(threadNo)
+			for i := from; i < to; i++ {
+				sender, err := signer.SenderWithContext(cryptoContext, txs[i])
+				if err != nil {
+					return fmt.Errorf("error recovering sender for tx=%x: %w", txs[i].Hash(), err)
+				}
+				if cached, ok := txs[i].GetSender(); ok && cached != sender {
+					return fmt.Errorf("sender of tx=%x is %x, recovered %x", txs[i].Hash(), cached, sender)
+				}
+				txs[i].SetSender(sender)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
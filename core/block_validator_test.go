@@ -17,14 +17,20 @@
 package core_test
 
 import (
+	"math/big"
 	"testing"
 
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/ethdb/olddb"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/stretchr/testify/require"
 )
 
 // Tests that simple header verification works, for both good and bad blocks.
@@ -100,3 +106,46 @@ func TestHeaderWithSealVerification(t *testing.T) {
 		engine.Close()
 	}
 }
+
+func makeBlockWithReceipts(t testing.TB, txCount int) (*types.Block, types.Receipts, common.Address) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.MakeSigner(params.TestChainConfig, 1)
+	txs := make([]types.Transaction, txCount)
+	receipts := make(types.Receipts, txCount)
+	for i := range txs {
+		tx := types.NewTransaction(uint64(i), common.Address{1}, uint256.NewInt(1), params.TxGas, uint256.NewInt(1), nil)
+		txs[i], err = types.SignTx(tx, *signer, key)
+		require.NoError(t, err)
+		receipts[i] = &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: uint64(i+1) * params.TxGas, Logs: []*types.Log{}}
+	}
+	header := &types.Header{Number: big.NewInt(1), ReceiptHash: types.DeriveSha(receipts)}
+	return types.NewBlockFromStorage(header.Hash(), header, txs, nil), receipts, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestValidateBlockBodyAndReceipts(t *testing.T) {
+	block, receipts, sender := makeBlockWithReceipts(t, 50)
+	require.NoError(t, core.ValidateBlockBodyAndReceipts(params.TestChainConfig, block, receipts))
+	for _, tx := range block.Transactions() {
+		cached, ok := tx.GetSender()
+		require.True(t, ok)
+		require.Equal(t, sender, cached)
+	}
+
+	receipts[10].Status = types.ReceiptStatusFailed
+	require.Error(t, core.ValidateBlockBodyAndReceipts(params.TestChainConfig, block, receipts))
+	receipts[10].Status = types.ReceiptStatusSuccessful
+
+	block.Transactions()[20].SetSender(common.Address{2})
+	require.Error(t, core.ValidateBlockBodyAndReceipts(params.TestChainConfig, block, receipts))
+}
+
+func BenchmarkValidateBlockBodyAndReceipts(b *testing.B) {
+	block, receipts, _ := makeBlockWithReceipts(b, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := core.ValidateBlockBodyAndReceipts(params.TestChainConfig, block, receipts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -20,6 +20,7 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"time"
 
@@ -438,7 +439,7 @@ func FinalizeBlockExecution(engine consensus.Engine, stateReader state.StateRead
 	}
 
 	var originalSystemAcc *accounts.Account
-	if cc.ChainID.Uint64() == 77 { // hack for Sokol - don't understand why eip158 is enabled, but OE still save SystemAddress with nonce=0
+	if cc.ChainID.Cmp(big.NewInt(77)) == 0 { // hack for Sokol - don't understand why eip158 is enabled, but OE still save SystemAddress with nonce=0
 		n := ibs.GetNonce(state.SystemAddress) //hack - because syscall must use ApplyMessage instead of ApplyTx (and don't create tx at all). But CallContract must create tx.
 		if n > 0 {
 			var err error
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon/event"
+)
+
+// chainHeadQueue - how many events can wait for the subscriber which doesn't receive them in time
+const chainHeadQueue = 10
+
+// ChainHeadFeed - notifies in-process consumers (txpool, miner, rpc subscriptions) about the new canonical head.
+// Unlike event.Feed, Send never blocks on slow subscriber: each subscriber has own queue of chainHeadQueue events,
+// events which don't fit into the queue are dropped for this subscriber and counted by
+// `chain_head_dropped{consumer="..."}` metric. Zero value is ready to use
+type ChainHeadFeed struct {
+	lock sync.Mutex
+	subs map[*chainHeadSub]struct{}
+}
+
+type chainHeadSub struct {
+	queue   chan ChainHeadEvent
+	dropped *metrics.Counter
+}
+
+// Subscribe - same as SubscribeAs with "unknown" consumer
+func (f *ChainHeadFeed) Subscribe(ch chan<- ChainHeadEvent) event.Subscription {
+	return f.SubscribeAs("unknown", ch)
+}
+
+// SubscribeAs - delivers events to `ch` until subscription is cancelled, `consumer` is label of the drop metric
+func (f *ChainHeadFeed) SubscribeAs(consumer string, ch chan<- ChainHeadEvent) event.Subscription {
+	sub := &chainHeadSub{
+		queue:   make(chan ChainHeadEvent, chainHeadQueue),
+		dropped: metrics.GetOrCreateCounter(fmt.Sprintf(`chain_head_dropped{consumer=%q}`, consumer)),
+	}
+	f.lock.Lock()
+	if f.subs == nil {
+		f.subs = map[*chainHeadSub]struct{}{}
+	}
+	f.subs[sub] = struct{}{}
+	f.lock.Unlock()
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer func() {
+			f.lock.Lock()
+			defer f.lock.Unlock()
+			delete(f.subs, sub)
+		}()
+		for {
+			select {
+			case ev := <-sub.queue:
+				select {
+				case ch <- ev:
+				case <-quit:
+					return nil
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
+
+// Send - queues event for all subscribers, returns how many of them had room for it
+func (f *ChainHeadFeed) Send(ev ChainHeadEvent) (nsent int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for sub := range f.subs {
+		select {
+		case sub.queue <- ev:
+			nsent++
+		default:
+			sub.dropped.Inc()
+		}
+	}
+	return nsent
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainHeadFeed(t *testing.T) {
+	var feed ChainHeadFeed
+	block := func(n int64) ChainHeadEvent {
+		return ChainHeadEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(n)})}
+	}
+
+	fast := make(chan ChainHeadEvent)
+	fastSub := feed.SubscribeAs("test_fast", fast)
+	slow := make(chan ChainHeadEvent)
+	slowSub := feed.SubscribeAs("test_slow", slow)
+	dropped := metrics.GetOrCreateCounter(`chain_head_dropped{consumer="test_slow"}`)
+	droppedBefore := dropped.Get()
+
+	// nobody reads `slow`, but Send must not block
+	const n = 2 * chainHeadQueue
+	for i := int64(0); i < n; i++ {
+		require.GreaterOrEqual(t, feed.Send(block(i)), 1)
+		require.Equal(t, uint64(i), (<-fast).Block.NumberU64())
+	}
+	// one event may be already taken from the queue and wait for `slow` to be ready
+	require.GreaterOrEqual(t, dropped.Get()-droppedBefore, uint64(n-chainHeadQueue-1))
+	require.LessOrEqual(t, dropped.Get()-droppedBefore, uint64(n-chainHeadQueue))
+
+	// slow consumer still gets events which were queued, in order
+	prev := (<-slow).Block.NumberU64()
+	for i := 1; i < chainHeadQueue; i++ {
+		next := (<-slow).Block.NumberU64()
+		require.Equal(t, prev+1, next)
+		prev = next
+	}
+
+	fastSub.Unsubscribe()
+	slowSub.Unsubscribe()
+	require.Equal(t, 0, feed.Send(block(n)))
+}
@@ -63,7 +63,7 @@ var (
 
 	// ErrNonceMax is returned if the nonce of a transaction sender account has
 	// maximum allowed value and would become invalid if incremented.
-	ErrNonceMax = errors.New("nonce has max value")
+	ErrNonceMax = types.ErrNonceMax
 
 	// ErrGasLimitReached is returned by the gas pool if the amount of gas required
 	// by a transaction is higher than what's left in the block.
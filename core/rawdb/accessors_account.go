@@ -17,6 +17,8 @@
 package rawdb
 
 import (
+	"encoding/binary"
+
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
@@ -35,3 +37,44 @@ func ReadAccount(db kv.Tx, addr common.Address, acc *accounts.Account) (bool, er
 	}
 	return true, nil
 }
+
+// AccountVersion - number of the block which last modified the account, written by the Execution stage
+// with --state.track-versions (for debugging of state corruption). Not a part of the account encoding:
+// PlainState, HashedAccounts and the state-change stream stay the same with and without it.
+// address -> block number (big-endian u64)
+const AccountVersion = "AccountVersion"
+
+// WithChaindataTables - tables of chaindata for mdbx.MdbxOpts.WithTablessCfg: the ones erigon-lib knows plus
+// AccountVersion. Databases opened without it don't have AccountVersion, see HasAccountVersions
+func WithChaindataTables(defaultBuckets kv.TableCfg) kv.TableCfg {
+	cfg := make(kv.TableCfg, len(defaultBuckets)+1)
+	for name, item := range defaultBuckets {
+		cfg[name] = item
+	}
+	cfg[AccountVersion] = kv.TableCfgItem{}
+	return cfg
+}
+
+// HasAccountVersions - AccountVersion is open in the db of this tx
+func HasAccountVersions(tx kv.BucketMigrator) (bool, error) {
+	return tx.ExistsBucket(AccountVersion)
+}
+
+// ReadAccountVersion - 0 if the account was not modified with version tracking
+func ReadAccountVersion(db kv.Getter, addr common.Address) (uint64, error) {
+	v, err := db.GetOne(AccountVersion, addr[:])
+	if err != nil || len(v) == 0 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func WriteAccountVersion(db kv.Putter, addr common.Address, blockNum uint64) error {
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], blockNum)
+	return db.Put(AccountVersion, addr[:], v[:])
+}
+
+func DeleteAccountVersion(db kv.Deleter, addr common.Address) error {
+	return db.Delete(AccountVersion, addr[:], nil)
+}
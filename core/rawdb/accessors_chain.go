@@ -36,6 +36,7 @@ import (
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/errgroup"
 )
 
 // ReadCanonicalHash retrieves the hash assigned to a canonical block number.
@@ -990,25 +991,21 @@ func ReadReceiptsByHash(db kv.Tx, hash common.Hash) (types.Receipts, error) {
 
 // WriteReceipts stores all the transaction receipts belonging to a block.
 func WriteReceipts(tx kv.Putter, number uint64, receipts types.Receipts) error {
-	buf := bytes.NewBuffer(make([]byte, 0, 1024))
-	for txId, r := range receipts {
-		if len(r.Logs) == 0 {
+	logs, err := encodeReceiptsLogs(receipts)
+	if err != nil {
+		return fmt.Errorf("encode block logs for block %d: %w", number, err)
+	}
+	for txId, v := range logs {
+		if v == nil {
 			continue
 		}
-
-		buf.Reset()
-		err := cbor.Marshal(buf, r.Logs)
-		if err != nil {
-			return fmt.Errorf("encode block logs for block %d: %w", number, err)
-		}
-
-		if err = tx.Put(kv.Log, dbutils.LogKey(number, uint32(txId)), buf.Bytes()); err != nil {
+		if err = tx.Put(kv.Log, dbutils.LogKey(number, uint32(txId)), v); err != nil {
 			return fmt.Errorf("writing logs for block %d: %w", number, err)
 		}
 	}
 
-	buf.Reset()
-	err := cbor.Marshal(buf, receipts)
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	err = cbor.Marshal(buf, receipts)
 	if err != nil {
 		return fmt.Errorf("encode block receipts for block %d: %w", number, err)
 	}
@@ -1021,25 +1018,25 @@ func WriteReceipts(tx kv.Putter, number uint64, receipts types.Receipts) error {
 
 // AppendReceipts stores all the transaction receipts belonging to a block.
 func AppendReceipts(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.Receipts) error {
-	buf := bytes.NewBuffer(make([]byte, 0, 1024))
-
-	for txId, r := range receipts {
-		if len(r.Logs) == 0 {
+	logs, err := encodeReceiptsLogs(receipts)
+	if err != nil {
+		return fmt.Errorf("encode block receipts for block %d: %w", blockNumber, err)
+	}
+	for txId, v := range logs {
+		if v == nil {
 			continue
 		}
-
-		buf.Reset()
-		err := cbor.Marshal(buf, r.Logs)
-		if err != nil {
-			return fmt.Errorf("encode block receipts for block %d: %w", blockNumber, err)
-		}
-
-		if err = tx.Append(kv.Log, dbutils.LogKey(blockNumber, uint32(txId)), buf.Bytes()); err != nil {
+		if err = tx.Append(kv.Log, dbutils.LogKey(blockNumber, uint32(txId)), v); err != nil {
 			return fmt.Errorf("writing receipts for block %d: %w", blockNumber, err)
 		}
 	}
+	return AppendReceiptsWithoutLogs(tx, blockNumber, receipts)
+}
 
-	buf.Reset()
+// AppendReceiptsWithoutLogs stores the transaction receipts belonging to a block, but not their logs.
+// ReadRawReceipts will return such receipts with empty logs
+func AppendReceiptsWithoutLogs(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.Receipts) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
 	err := cbor.Marshal(buf, receipts)
 	if err != nil {
 		return fmt.Errorf("encode block receipts for block %d: %w", blockNumber, err)
@@ -1051,6 +1048,46 @@ func AppendReceipts(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.R
 	return nil
 }
 
+// receiptsEncodeBatch - number of receipts whose logs are encoded by one goroutine
+const receiptsEncodeBatch = 50
+
+// encodeReceiptsLogs - CBOR of the logs of every receipt, nil for receipts without logs.
+// Logs are most of the encoding work for dense blocks, so batches of receipts are encoded in parallel,
+// the caller still writes them sequentially in the order of transactions
+func encodeReceiptsLogs(receipts types.Receipts) ([][]byte, error) {
+	encoded := make([][]byte, len(receipts))
+	encodeBatch := func(from, to int) error {
+		buf := bytes.NewBuffer(make([]byte, 0, 1024))
+		for i := from; i < to; i++ {
+			if len(receipts[i].Logs) == 0 {
+				continue
+			}
+			buf.Reset()
+			if err := cbor.Marshal(buf, receipts[i].Logs); err != nil {
+				return err
+			}
+			encoded[i] = common.CopyBytes(buf.Bytes())
+		}
+		return nil
+	}
+	if len(receipts) <= receiptsEncodeBatch {
+		return encoded, encodeBatch(0, len(receipts))
+	}
+
+	var g errgroup.Group
+	for from := 0; from < len(receipts); from += receiptsEncodeBatch {
+		from, to := from, from+receiptsEncodeBatch
+		if to > len(receipts) {
+			to = len(receipts)
+		}
+		g.Go(func() error { return encodeBatch(from, to) })
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
+
 // TruncateReceipts removes all receipt for given block number or newer
 func TruncateReceipts(db kv.RwTx, number uint64) error {
 	if err := db.ForEach(kv.Receipts, dbutils.EncodeBlockNumber(number), func(k, _ []byte) error {
@@ -434,6 +434,44 @@ func TestBlockReceiptStorage(t *testing.T) {
 	}
 }
 
+// denseReceipts - receipts of a DeFi-like block: every transaction emits several logs with topics and data
+func denseReceipts(n int) types.Receipts {
+	receipts := make(types.Receipts, n)
+	for i := range receipts {
+		r := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: uint64(i+1) * 100_000}
+		if i%10 != 0 { // some transactions have no logs
+			for j := 0; j < 8; j++ {
+				r.Logs = append(r.Logs, &types.Log{
+					Address: common.BytesToAddress([]byte{byte(i), byte(j)}),
+					Topics:  []common.Hash{{byte(i)}, {byte(j)}, {0xdd}},
+					Data:    bytes.Repeat([]byte{byte(i + j)}, 256),
+				})
+			}
+		}
+		receipts[i] = r
+	}
+	return receipts
+}
+
+func TestWriteDenseReceipts(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	receipts := denseReceipts(3*receiptsEncodeBatch + 7)
+	require.NoError(t, WriteReceipts(tx, 1, receipts))
+	require.NoError(t, checkReceiptsRLP(ReadRawReceipts(tx, 1), receipts))
+}
+
+func BenchmarkWriteReceipts(b *testing.B) {
+	_, tx := memdb.NewTestTx(b)
+	receipts := denseReceipts(500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := WriteReceipts(tx, uint64(i), receipts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func checkReceiptsRLP(have, want types.Receipts) error {
 	if len(have) != len(want) {
 		return fmt.Errorf("receipts sizes mismatch: have %d, want %d", len(have), len(want))
@@ -0,0 +1,262 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/freezer"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// Tables of the freezer (--datadir.ancient), items are numbered by block number
+const (
+	FreezerBodies   = "bodies"   // rlp of frozenBody: canonical types.Body with transactions and their ids
+	FreezerReceipts = "receipts" // rlp of types.ReceiptsForStorage with logs, empty if receipts were not stored
+)
+
+// AncientDirKey - key in kv.DatabaseInfo: directory of the freezer, recorded by the node started with --datadir.ancient.
+// Other processes which read blocks from the db (rpcdaemon, integration) open the freezer from it
+var AncientDirKey = []byte("ancientDir")
+
+func ReadAncientDir(db kv.Getter) (string, error) {
+	v, err := db.GetOne(kv.DatabaseInfo, AncientDirKey)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
+
+func WriteAncientDir(db kv.Putter, dir string) error {
+	return db.Put(kv.DatabaseInfo, AncientDirKey, []byte(dir))
+}
+
+// OpenFreezer - opens tables of block bodies and receipts in `dir`
+func OpenFreezer(dir string, readonly bool) (*freezer.Freezer, error) {
+	return freezer.Open(dir, readonly, FreezerBodies, FreezerReceipts)
+}
+
+// OpenRecordedFreezer - opens the freezer recorded in the db by the node, nil if the node doesn't use it
+func OpenRecordedFreezer(tx kv.Getter, readonly bool) (*freezer.Freezer, error) {
+	dir, err := ReadAncientDir(tx)
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	return OpenFreezer(dir, readonly)
+}
+
+// FreezeBlocks - moves canonical bodies with transactions and receipts of blocks [1, blockTo) from the db
+// to the freezer, at most `limit` blocks per call. Headers, senders, canonical markers, total difficulty, tx lookup
+// and logs (kv.Log, they are found by the log indices) stay in the db, genesis is not moved. Freezer is synced before return, so the caller can commit the deletion.
+// If the deletion of the previous call was rolled back, the freezer is truncated back first, see TruncateFreezer
+func FreezeBlocks(tx kv.RwTx, f *freezer.Freezer, blockTo uint64, limit int) error {
+	if err := TruncateFreezer(tx, f); err != nil {
+		return err
+	}
+	from, err := frozenNext(f)
+	if err != nil {
+		return err
+	}
+
+	to := blockTo
+	if from+uint64(limit) < to {
+		to = from + uint64(limit)
+	}
+	for n := from; n < to; n++ {
+		hash, err := ReadCanonicalHash(tx, n)
+		if err != nil {
+			return err
+		}
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("FreezeBlocks: canonical hash of block %d not found", n)
+		}
+		if err = appendIfNext(f, FreezerBodies, n, func() ([]byte, error) {
+			body := ReadCanonicalBodyWithTransactions(tx, hash, n)
+			if body == nil {
+				return nil, fmt.Errorf("FreezeBlocks: body of block %d not found", n)
+			}
+			b, err := ReadBodyForStorageByKey(tx, dbutils.BlockBodyKey(n, hash))
+			if err != nil {
+				return nil, err
+			}
+			bodyRlp, err := rlp.EncodeToBytes(body)
+			if err != nil {
+				return nil, err
+			}
+			return rlp.EncodeToBytes(&frozenBody{BaseTxId: b.BaseTxId, TxAmount: b.TxAmount, Body: bodyRlp})
+		}); err != nil {
+			return err
+		}
+		if err = appendIfNext(f, FreezerReceipts, n, func() ([]byte, error) {
+			receipts := ReadRawReceipts(tx, n)
+			if receipts == nil {
+				return nil, nil
+			}
+			storage := make(types.ReceiptsForStorage, len(receipts))
+			for i, r := range receipts {
+				storage[i] = (*types.ReceiptForStorage)(r)
+			}
+			return rlp.EncodeToBytes(storage)
+		}); err != nil {
+			return err
+		}
+		if err = deleteFrozenBlock(tx, hash, n); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
+
+// TruncateFreezer - drops frozen blocks which are still in the db: freezer is not a part of the db transaction,
+// so the deletion of frozen blocks from the db may be rolled back (or never committed because of a crash) after
+// they were appended. Must be called after opening the freezer for appending
+func TruncateFreezer(tx kv.Getter, f *freezer.Freezer) error {
+	next, err := frozenNext(f)
+	if err != nil {
+		return err
+	}
+	watermark := next
+	for n := next - 1; n > 0; n-- {
+		hash, err := ReadCanonicalHash(tx, n)
+		if err != nil {
+			return err
+		}
+		if hash == (common.Hash{}) || !HasBlock(tx, hash, n) {
+			break
+		}
+		watermark = n
+	}
+	for _, name := range []string{FreezerBodies, FreezerReceipts} {
+		if err = f.Truncate(name, watermark); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frozenNext - lowest next item of the tables, 1 (genesis is not frozen) if any of them is empty
+func frozenNext(f *freezer.Freezer) (uint64, error) {
+	next := uint64(0)
+	for _, name := range []string{FreezerBodies, FreezerReceipts} {
+		first, tableNext, err := f.Items(name)
+		if err != nil {
+			return 0, err
+		}
+		if first == tableNext {
+			return 1, nil
+		}
+		if next == 0 || tableNext < next {
+			next = tableNext
+		}
+	}
+	return next, nil
+}
+
+// appendIfNext - appends item `n` unless the table already has it (crash between appends to different tables)
+func appendIfNext(f *freezer.Freezer, name string, n uint64, item func() ([]byte, error)) error {
+	first, next, err := f.Items(name)
+	if err != nil {
+		return err
+	}
+	if first != next && n < next {
+		return nil
+	}
+	v, err := item()
+	if err != nil {
+		return err
+	}
+	return f.Append(name, n, v)
+}
+
+func deleteFrozenBlock(tx kv.RwTx, hash common.Hash, number uint64) error {
+	key := dbutils.BlockBodyKey(number, hash)
+	b, err := ReadBodyForStorageByKey(tx, key)
+	if err != nil {
+		return err
+	}
+	if b != nil {
+		txIDBytes := make([]byte, 8)
+		for txID := b.BaseTxId; txID < b.BaseTxId+uint64(b.TxAmount); txID++ {
+			binary.BigEndian.PutUint64(txIDBytes, txID)
+			if err = tx.Delete(kv.EthTx, txIDBytes, nil); err != nil {
+				return err
+			}
+		}
+	}
+	if err = tx.Delete(kv.BlockBody, key, nil); err != nil {
+		return err
+	}
+	return tx.Delete(kv.Receipts, dbutils.EncodeBlockNumber(number), nil)
+}
+
+// frozenBody - item of FreezerBodies. Ids of transactions are kept: their sequence in kv.EthTx is the cumulative count
+// of transactions (erigon_cumulativeChainTraffic)
+type frozenBody struct {
+	BaseTxId uint64
+	TxAmount uint32
+	Body     rlp.RawValue
+}
+
+func readFrozenBody(f *freezer.Freezer, number uint64) (*frozenBody, error) {
+	v, err := f.Get(FreezerBodies, number)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	b := new(frozenBody)
+	if err = rlp.DecodeBytes(v, b); err != nil {
+		return nil, fmt.Errorf("frozen body %d: %w", number, err)
+	}
+	return b, nil
+}
+
+// ReadFrozenBody - canonical body of block `number` with transactions, nil if it's not in the freezer
+func ReadFrozenBody(f *freezer.Freezer, number uint64) (*types.Body, error) {
+	b, err := readFrozenBody(f, number)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	body := new(types.Body)
+	if err = rlp.DecodeBytes(b.Body, body); err != nil {
+		return nil, fmt.Errorf("frozen body %d: %w", number, err)
+	}
+	return body, nil
+}
+
+// ReadFrozenBodyTxIds - like ReadBody: id of the first transaction of the block and amount of them, without system ones.
+// ok is false if the body is not in the freezer
+func ReadFrozenBodyTxIds(f *freezer.Freezer, number uint64) (baseTxId uint64, txAmount uint32, ok bool, err error) {
+	b, err := readFrozenBody(f, number)
+	if err != nil || b == nil {
+		return 0, 0, false, err
+	}
+	if b.TxAmount < 2 {
+		return 0, 0, false, fmt.Errorf("frozen body %d: too few txs amount: %d", number, b.TxAmount)
+	}
+	return b.BaseTxId + 1, b.TxAmount - 2, true, nil // 1 system txn in the begining of block, and 1 at the end
+}
+
+// ReadFrozenReceipts - receipts of the block with derived fields, like ReadReceipts. nil if they are not in the freezer
+func ReadFrozenReceipts(f *freezer.Freezer, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	v, err := f.Get(FreezerReceipts, block.NumberU64())
+	if err != nil || v == nil {
+		return nil, err
+	}
+	var storage types.ReceiptsForStorage
+	if err = rlp.DecodeBytes(v, &storage); err != nil {
+		return nil, fmt.Errorf("frozen receipts %d: %w", block.NumberU64(), err)
+	}
+	receipts := make(types.Receipts, len(storage))
+	for i, r := range storage {
+		receipts[i] = (*types.Receipt)(r)
+	}
+	if len(senders) > 0 {
+		block.SendersToTxs(senders)
+	}
+	if err = receipts.DeriveFields(block.Hash(), block.NumberU64(), block.Transactions(), senders); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
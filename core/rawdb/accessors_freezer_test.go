@@ -0,0 +1,126 @@
+package rawdb
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/u256"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreezeBlocks(t *testing.T) {
+	require := require.New(t)
+	db := memdb.NewTestDB(t)
+	f, err := OpenFreezer(t.TempDir(), false)
+	require.NoError(err)
+	defer f.Close()
+
+	var hashes []common.Hash
+	var receipts []types.Receipts
+	var baseTxIds []uint64
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		for n := uint64(0); n < 5; n++ {
+			header := &types.Header{Number: new(big.Int).SetUint64(n)}
+			hash := header.Hash()
+			txn := types.NewTransaction(n, common.HexToAddress("0x1"), u256.Num1, 1, u256.Num1, nil)
+			WriteHeader(tx, header)
+			require.NoError(WriteCanonicalHash(tx, hash, n))
+			require.NoError(WriteBody(tx, hash, n, &types.Body{Transactions: types.Transactions{txn}}))
+			r := types.Receipts{{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: n,
+				Logs:              []*types.Log{{Address: common.BytesToAddress([]byte{byte(n)})}},
+			}}
+			if n != 2 { // receipts may be pruned
+				require.NoError(WriteReceipts(tx, n, r))
+			}
+			_, baseTxId, _ := ReadBody(tx, hash, n)
+			hashes = append(hashes, hash)
+			receipts = append(receipts, r)
+			baseTxIds = append(baseTxIds, baseTxId)
+		}
+		return nil
+	}))
+
+	// deletion from the db is rolled back, frozen items are truncated back to the db
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(err)
+	require.NoError(FreezeBlocks(tx, f, 3, 100))
+	require.False(HasBlock(tx, hashes[1], 1))
+	tx.Rollback()
+	require.NoError(db.View(context.Background(), func(tx kv.Tx) error {
+		require.True(HasBlock(tx, hashes[1], 1))
+		return TruncateFreezer(tx, f)
+	}))
+	first, next, err := f.Items(FreezerBodies)
+	require.NoError(err)
+	require.Equal(first, next)
+
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		require.NoError(FreezeBlocks(tx, f, 2, 100))
+		return nil
+	}))
+	// rolled back again, next call truncates by itself
+	tx, err = db.BeginRw(context.Background())
+	require.NoError(err)
+	require.NoError(FreezeBlocks(tx, f, 3, 100))
+	tx.Rollback()
+
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		require.True(HasBlock(tx, hashes[2], 2))
+		require.NoError(FreezeBlocks(tx, f, 4, 100))
+		return nil
+	}))
+
+	require.NoError(db.View(context.Background(), func(tx kv.Tx) error {
+		require.True(HasBlock(tx, hashes[0], 0))
+		require.True(HasBlock(tx, hashes[4], 4))
+		for n := uint64(1); n < 4; n++ {
+			require.False(HasBlock(tx, hashes[n], n))
+			require.Nil(ReadRawReceipts(tx, n))
+			require.NotNil(ReadHeader(tx, hashes[n], n))
+
+			body, err := ReadFrozenBody(f, n)
+			require.NoError(err)
+			require.NotNil(body)
+			require.Equal(1, len(body.Transactions))
+			require.Equal(n, body.Transactions[0].GetNonce())
+			baseTxId, txAmount, ok, err := ReadFrozenBodyTxIds(f, n)
+			require.NoError(err)
+			require.True(ok)
+			require.Equal(baseTxIds[n], baseTxId)
+			require.Equal(uint32(1), txAmount)
+
+			// logs stay in the db, log indices point to them
+			logs, err := tx.GetOne(kv.Log, dbutils.LogKey(n, 0))
+			require.NoError(err)
+			require.Equal(n != 2, logs != nil)
+
+			block := types.NewBlockFromStorage(hashes[n], ReadHeader(tx, hashes[n], n), body.Transactions, body.Uncles)
+			rs, err := ReadFrozenReceipts(f, block, []common.Address{common.HexToAddress("0x2")})
+			require.NoError(err)
+			if n == 2 {
+				require.Nil(rs)
+				continue
+			}
+			require.NoError(checkReceiptsRLP(rs, receipts[n]))
+			require.Equal(hashes[n], rs[0].BlockHash)
+			require.Equal(hashes[n], rs[0].Logs[0].BlockHash)
+		}
+		return nil
+	}))
+
+	body, err := ReadFrozenBody(f, 4)
+	require.NoError(err)
+	require.Nil(body)
+	first, next, err = f.Items(FreezerBodies)
+	require.NoError(err)
+	require.Equal(uint64(1), first)
+	require.Equal(uint64(4), next)
+}
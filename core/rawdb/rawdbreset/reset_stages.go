@@ -147,6 +147,15 @@ func ResetExec(tx kv.RwTx, g *core.Genesis) error {
 	if err := tx.ClearBucket(kv.BorReceipts); err != nil {
 		return err
 	}
+	hasVersions, err := rawdb.HasAccountVersions(tx)
+	if err != nil {
+		return err
+	}
+	if hasVersions {
+		if err := tx.ClearBucket(rawdb.AccountVersion); err != nil {
+			return err
+		}
+	}
 	if err := stages.SaveStageProgress(tx, stages.Execution, 0); err != nil {
 		return err
 	}
@@ -1,9 +1,11 @@
 package state
 
 import (
+	"testing"
+
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/common"
-	"testing"
+	"github.com/ledgerwatch/erigon/core/types"
 )
 
 func verifyAddrs(t *testing.T, s *IntraBlockState, astrings ...string) {
@@ -171,3 +173,33 @@ func TestAccessList(t *testing.T) {
 		t.Fatalf("expected empty, got %d", got)
 	}
 }
+
+func TestPrepareAccessList(t *testing.T) {
+	addr := common.HexToAddress
+	slot := common.HexToHash
+
+	_, tx := memdb.NewTestTx(t)
+	state := New(NewPlainState(tx, 1))
+	state.Prepare(common.Hash{}, common.Hash{}, 0)
+
+	dst := addr("bb")
+	state.PrepareAccessList(addr("aa"), &dst, []common.Address{addr("01"), addr("02")}, types.AccessList{
+		{Address: addr("cc"), StorageKeys: []common.Hash{slot("01"), slot("02")}},
+		{Address: addr("dd")},
+		{Address: addr("bb"), StorageKeys: []common.Hash{slot("03")}},
+	})
+	verifyAddrs(t, state, "aa", "bb", "01", "02", "cc", "dd")
+	verifySlots(t, state, "cc", "01", "02")
+	verifySlots(t, state, "bb", "03")
+	if addrOk, slotOk := state.SlotInAccessList(addr("dd"), slot("01")); !addrOk || slotOk {
+		t.Fatalf("expected warm address with cold slot, got %t %t", addrOk, slotOk)
+	}
+	if addrOk, slotOk := state.SlotInAccessList(addr("ee"), slot("01")); addrOk || slotOk {
+		t.Fatalf("expected cold address and slot, got %t %t", addrOk, slotOk)
+	}
+
+	// contract creation: destination is added later, by evm.create
+	state.Prepare(common.Hash{}, common.Hash{}, 1)
+	state.PrepareAccessList(addr("aa"), nil, nil, nil)
+	verifyAddrs(t, state, "aa")
+}
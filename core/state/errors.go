@@ -0,0 +1,72 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// StateWriteOp - identifies the state writer operation which failed
+type StateWriteOp uint8
+
+const (
+	OpUpdateAccountData StateWriteOp = iota
+	OpUpdateAccountCode
+	OpDeleteAccount
+	OpWriteAccountStorage
+	OpCreateContract
+	OpWriteChangeSets
+	OpWriteHistory
+)
+
+func (op StateWriteOp) String() string {
+	switch op {
+	case OpUpdateAccountData:
+		return "UpdateAccountData"
+	case OpUpdateAccountCode:
+		return "UpdateAccountCode"
+	case OpDeleteAccount:
+		return "DeleteAccount"
+	case OpWriteAccountStorage:
+		return "WriteAccountStorage"
+	case OpCreateContract:
+		return "CreateContract"
+	case OpWriteChangeSets:
+		return "WriteChangeSets"
+	case OpWriteHistory:
+		return "WriteHistory"
+	default:
+		return fmt.Sprintf("StateWriteOp(%d)", uint8(op))
+	}
+}
+
+// StateWriteError is returned by PlainStateWriter. It carries the failed operation and the
+// affected account (and storage slot, if any), the underlying error is available via errors.Is/errors.As
+type StateWriteError struct {
+	Op      StateWriteOp
+	Address common.Address
+	Slot    *common.Hash // nil for account-level operations
+	Cause   error
+}
+
+func (e *StateWriteError) Error() string {
+	if e.Slot != nil {
+		return fmt.Sprintf("%s %x slot %x: %v", e.Op, e.Address, *e.Slot, e.Cause)
+	}
+	return fmt.Sprintf("%s %x: %v", e.Op, e.Address, e.Cause)
+}
+
+func (e *StateWriteError) Unwrap() error {
+	return e.Cause
+}
+
+func wrapStateWriteErr(op StateWriteOp, address common.Address, slot *common.Hash, err error) error {
+	if err == nil {
+		return nil
+	}
+	if slot != nil {
+		slotCopy := *slot
+		slot = &slotCopy
+	}
+	return &StateWriteError{Op: op, Address: address, Slot: slot, Cause: err}
+}
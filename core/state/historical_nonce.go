@@ -0,0 +1,72 @@
+package state
+
+import (
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+// HistoricalNonceReader answers "what was the nonce of this account at block N" queries
+// using AccountsHistory and AccountChangeSet - the same index lookup (FindByHistory) that PlainState uses.
+// Encoded accounts are looked up in and added to the HistoryCache (nil disables it), which is shared with
+// tracing readers - trace replay tends to query the same pairs many times.
+type HistoricalNonceReader struct {
+	tx       kv.Tx
+	historyC kv.Cursor
+	changesC kv.CursorDupSort
+	cache    *HistoryCache
+}
+
+func NewHistoricalNonceReader(tx kv.Tx, cache *HistoryCache) (*HistoricalNonceReader, error) {
+	historyC, err := tx.Cursor(kv.AccountsHistory)
+	if err != nil {
+		return nil, err
+	}
+	changesC, err := tx.CursorDupSort(kv.AccountChangeSet)
+	if err != nil {
+		historyC.Close()
+		return nil, err
+	}
+	return &HistoricalNonceReader{tx: tx, historyC: historyC, changesC: changesC, cache: cache}, nil
+}
+
+// GetHistoricalNonce returns the nonce of the account after execution of block `blockNumber`.
+// Non-existing accounts have nonce 0.
+func (r *HistoricalNonceReader) GetHistoricalNonce(addr common.Address, blockNumber uint64) (uint64, error) {
+	// history is indexed by "state at the beginning of block", so ask for the next one
+	asOf := blockNumber + 1
+	var enc []byte
+	var cacheKey string
+	var cached, ok bool
+	if r.cache != nil {
+		var err error
+		if cacheKey, cached, err = historyCacheKey(r.tx, asOf, addr[:]); err != nil {
+			return 0, err
+		}
+		if cached {
+			enc, ok = r.cache.get(cacheKey)
+		}
+	}
+	if !ok {
+		var err error
+		if enc, err = GetAsOf(r.tx, r.historyC, r.changesC, false /* storage */, addr[:], asOf); err != nil {
+			return 0, err
+		}
+		if cached {
+			r.cache.add(cacheKey, enc)
+		}
+	}
+	if len(enc) == 0 {
+		return 0, nil
+	}
+	var acc accounts.Account
+	if err := acc.DecodeForStorage(enc); err != nil {
+		return 0, err
+	}
+	return acc.Nonce, nil
+}
+
+func (r *HistoricalNonceReader) Close() {
+	r.historyC.Close()
+	r.changesC.Close()
+}
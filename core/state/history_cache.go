@@ -0,0 +1,78 @@
+package state
+
+import (
+	"encoding/binary"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+)
+
+// HistoryCache - LRU of account and storage values as of a block, see PlainState.SetHistoryCache. Safe for concurrent use.
+// Value as of a block doesn't change when next blocks are executed, but does when the block is unwound, so values are
+// keyed by the hash of the previous canonical block in the reader's transaction: reader of the old chain, which began
+// its transaction before the unwind, doesn't add its values to the keys of the new one.
+// Feed the cache with the state change stream (OnNewBlock), values of unwound blocks are purged on unwind (reorg)
+type HistoryCache struct {
+	lru *lru.Cache
+}
+
+// NewHistoryCache - cache of up to `size` values
+func NewHistoryCache(size int) (*HistoryCache, error) {
+	c, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryCache{lru: c}, nil
+}
+
+// historyCacheKey - key of `key` as of the beginning of block `blockNr` of the canonical chain seen by `tx`.
+// ok is false if the block is not on the chain, its values are not cached
+func historyCacheKey(tx kv.Getter, blockNr uint64, key []byte) (k string, ok bool, err error) {
+	var parent common.Hash
+	if blockNr > 0 {
+		if parent, err = rawdb.ReadCanonicalHash(tx, blockNr-1); err != nil || parent == (common.Hash{}) {
+			return "", false, err
+		}
+	}
+	b := make([]byte, 8+length.Hash+len(key))
+	binary.BigEndian.PutUint64(b, blockNr)
+	copy(b[8:], parent[:])
+	copy(b[8+length.Hash:], key)
+	return string(b), true, nil
+}
+
+// get - value must not be modified, nil value means absence of the account or storage item
+func (c *HistoryCache) get(k string) ([]byte, bool) {
+	v, ok := c.lru.Get(k)
+	if !ok {
+		return nil, false
+	}
+	return v.([]byte), true
+}
+
+func (c *HistoryCache) add(k string, value []byte) {
+	c.lru.Add(k, common.CopyBytes(value))
+}
+
+func (c *HistoryCache) Len() int {
+	return c.lru.Len()
+}
+
+func (c *HistoryCache) Purge() {
+	c.lru.Purge()
+}
+
+// OnNewBlock - purges the cache if the batch of the state change stream unwinds any block: values of the unwound
+// chain are not read anymore
+func (c *HistoryCache) OnNewBlock(sc *remote.StateChangeBatch) {
+	for _, change := range sc.ChangeBatch {
+		if change.Direction == remote.Direction_UNWIND {
+			c.Purge()
+			return
+		}
+	}
+}
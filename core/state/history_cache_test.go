@@ -0,0 +1,87 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlainStateHistoryCache(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	slot := common.HexToHash("0x01")
+	w := NewPlainStateWriterNoHistory(tx)
+	original, acc := accounts.NewAccount(), accounts.NewAccount()
+	acc.Balance.SetUint64(1)
+	acc.Incarnation = 1
+	require.NoError(t, w.UpdateAccountData(addr, &original, &acc))
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(0), uint256.NewInt(7)))
+
+	for n := uint64(0); n < 11; n++ {
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, common.Hash{byte(n + 1)}, n))
+	}
+
+	cache, err := NewHistoryCache(16)
+	require.NoError(t, err)
+	read := func(blockNr uint64) (uint64, []byte) {
+		r := NewPlainState(tx, blockNr).SetHistoryCache(cache)
+		a, err := r.ReadAccountData(addr)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+		v, err := r.ReadAccountStorage(addr, 1, &slot)
+		require.NoError(t, err)
+		return a.Balance.Uint64(), v
+	}
+	balance, v := read(10)
+	require.Equal(t, uint64(1), balance)
+	require.Equal(t, []byte{7}, v)
+	require.Equal(t, 2, cache.Len())
+
+	// change state without history: cached values of block 10 are still returned
+	changed := acc
+	changed.Balance.SetUint64(2)
+	require.NoError(t, w.UpdateAccountData(addr, &acc, &changed))
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(7), uint256.NewInt(8)))
+	balance, v = read(10)
+	require.Equal(t, uint64(1), balance)
+	require.Equal(t, []byte{7}, v)
+
+	// other block is not in the cache
+	balance, v = read(11)
+	require.Equal(t, uint64(2), balance)
+	require.Equal(t, []byte{8}, v)
+
+	// reorg replaced block 9: values of block 10 are read again, even without purge
+	require.NoError(t, rawdb.WriteCanonicalHash(tx, common.Hash{0xff}, 9))
+	balance, v = read(10)
+	require.Equal(t, uint64(2), balance)
+	require.Equal(t, []byte{8}, v)
+
+	// block 12 is not on the chain yet, its values are not cached
+	n := cache.Len()
+	read(12)
+	require.Equal(t, n, cache.Len())
+
+	cache.Purge()
+	require.Zero(t, cache.Len())
+}
+
+func TestHistoryCacheOnNewBlock(t *testing.T) {
+	cache, err := NewHistoryCache(16)
+	require.NoError(t, err)
+	cache.add("k", []byte{2})
+
+	cache.OnNewBlock(&remote.StateChangeBatch{ChangeBatch: []*remote.StateChange{{Direction: remote.Direction_FORWARD, BlockHeight: 2}}})
+	require.Equal(t, 1, cache.Len())
+	cache.OnNewBlock(&remote.StateChangeBatch{ChangeBatch: []*remote.StateChange{
+		{Direction: remote.Direction_UNWIND, BlockHeight: 2},
+		{Direction: remote.Direction_FORWARD, BlockHeight: 2},
+	}})
+	require.Zero(t, cache.Len())
+}
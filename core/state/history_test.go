@@ -16,6 +16,7 @@ import (
 	"github.com/ledgerwatch/erigon/common/changeset"
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
@@ -1144,3 +1145,40 @@ func assertChangesEquals(t *testing.T, changesObtained, changesExpected *changes
 		t.Fatal("block result is incorrect")
 	}
 }
+
+func TestGetHistoricalNonce(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	emptyValAcc := accounts.NewAccount()
+	block3ValAcc := emptyValAcc.SelfCopy()
+	block3ValAcc.Nonce = 3
+	block3ValAcc.Initialised = true
+	block5ValAcc := emptyValAcc.SelfCopy()
+	block5ValAcc.Nonce = 5
+	block5ValAcc.Initialised = true
+
+	addr := common.Address{1}
+	writeBlockData(t, NewPlainStateWriter(tx, tx, 3), []accData{{addr: addr, oldVal: &emptyValAcc, newVal: block3ValAcc}})
+	writeBlockData(t, NewPlainStateWriter(tx, tx, 5), []accData{{addr: addr, oldVal: block3ValAcc, newVal: block5ValAcc}})
+
+	for n := uint64(0); n <= 6; n++ {
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, common.Hash{byte(n + 1)}, n))
+	}
+
+	cache, err := NewHistoryCache(16)
+	require.NoError(t, err)
+	r, err := NewHistoricalNonceReader(tx, cache)
+	require.NoError(t, err)
+	defer r.Close()
+	for blockNum, expected := range map[uint64]uint64{1: 0, 2: 0, 3: 3, 4: 3, 5: 5, 6: 5} {
+		for i := 0; i < 2; i++ { // second round is served from cache
+			nonce, err := r.GetHistoricalNonce(addr, blockNum)
+			require.NoError(t, err)
+			assert.Equal(t, expected, nonce, "block %d", blockNum)
+		}
+	}
+	assert.Equal(t, 6, cache.Len())
+	nonce, err := r.GetHistoricalNonce(common.Address{2}, 4)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), nonce)
+}
@@ -713,6 +713,33 @@ func printAccount(EIP161Enabled bool, addr common.Address, stateObject *stateObj
 	}
 }
 
+// DeleteEmptyAccounts - EIP-161 cleanup: accounts touched by the current transaction which are empty
+// (zero nonce, zero balance, no code) are deleted via stateWriter. Returns deleted addresses.
+// It's the first step of FinalizeTx, don't call it separately if FinalizeTx is called
+func (sdb *IntraBlockState) DeleteEmptyAccounts(chainRules *params.Rules, stateWriter StateWriter) (map[common.Address]struct{}, error) {
+	if !chainRules.IsSpuriousDragon {
+		return nil, nil
+	}
+	for addr, bi := range sdb.balanceInc {
+		if !bi.transferred {
+			sdb.getStateObject(addr)
+		}
+	}
+	deleted := map[common.Address]struct{}{}
+	for addr := range sdb.journal.dirties {
+		so, exist := sdb.stateObjects[addr]
+		if !exist || !so.empty() {
+			continue
+		}
+		if err := stateWriter.DeleteAccount(addr, &so.original); err != nil {
+			return nil, err
+		}
+		so.deleted = true
+		deleted[addr] = struct{}{}
+	}
+	return deleted, nil
+}
+
 // FinalizeTx should be called after every transaction.
 func (sdb *IntraBlockState) FinalizeTx(chainRules *params.Rules, stateWriter StateWriter) error {
 	for addr, bi := range sdb.balanceInc {
@@ -720,7 +747,15 @@ func (sdb *IntraBlockState) FinalizeTx(chainRules *params.Rules, stateWriter Sta
 			sdb.getStateObject(addr)
 		}
 	}
+	deleted, err := sdb.DeleteEmptyAccounts(chainRules, stateWriter)
+	if err != nil {
+		return err
+	}
 	for addr := range sdb.journal.dirties {
+		if _, ok := deleted[addr]; ok {
+			sdb.stateObjectsDirty[addr] = struct{}{}
+			continue
+		}
 		so, exist := sdb.stateObjects[addr]
 		if !exist {
 			// ripeMD is 'touched' at block 1714175, in tx 0x1237f737031e40bcde4a8b7e717b2d15e3ecadfe49bb1bbc71ee9deb09c6fcf2
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+const (
+	mirrorQueueSize    = 4096             // writes waiting for the secondary, next ones are dropped
+	mirrorWarnInterval = 30 * time.Second // failures of the secondary are logged at most once per interval
+)
+
+var errMirrorQueueFull = errors.New("secondary is too slow, write dropped")
+
+// MirroredChangeSetWriter - write-through to two writers: for example to local db and to archive on slower storage.
+// Result is defined by primary writer: its errors are returned, secondary is not called after primary failed.
+// Secondary is written in the background, in the same order, so slow secondary doesn't stall block processing.
+// Its failures are only counted and logged, writes which don't fit into the queue are dropped - it's best-effort
+// and has to be re-synced if SecondaryErrors or SecondaryDropped is not zero. Call Close after the last write
+type MirroredChangeSetWriter struct {
+	primary, secondary WriterWithChangeSets
+	queue              chan mirroredWrite
+	done               chan struct{}
+	secondaryErrors    uint64 // atomic
+	secondaryDropped   uint64 // atomic
+	lastWarn           int64  // atomic, unix nano
+}
+
+type mirroredWrite struct {
+	op    string
+	write func() error
+}
+
+func NewMirroredChangeSetWriter(primary, secondary WriterWithChangeSets) *MirroredChangeSetWriter {
+	return newMirroredChangeSetWriter(primary, secondary, mirrorQueueSize)
+}
+
+func newMirroredChangeSetWriter(primary, secondary WriterWithChangeSets, queueSize int) *MirroredChangeSetWriter {
+	w := &MirroredChangeSetWriter{
+		primary:   primary,
+		secondary: secondary,
+		queue:     make(chan mirroredWrite, queueSize),
+		done:      make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+func (w *MirroredChangeSetWriter) loop() {
+	defer close(w.done)
+	for m := range w.queue {
+		if err := m.write(); err != nil {
+			atomic.AddUint64(&w.secondaryErrors, 1)
+			w.warn(m.op, err)
+		}
+	}
+}
+
+// Close - waits until queued writes are applied to the secondary. Writer can't be used after it
+func (w *MirroredChangeSetWriter) Close() {
+	close(w.queue)
+	<-w.done
+}
+
+// SecondaryErrors - how many writes to secondary failed
+func (w *MirroredChangeSetWriter) SecondaryErrors() uint64 {
+	return atomic.LoadUint64(&w.secondaryErrors)
+}
+
+// SecondaryDropped - how many writes to secondary were dropped because the queue was full
+func (w *MirroredChangeSetWriter) SecondaryDropped() uint64 {
+	return atomic.LoadUint64(&w.secondaryDropped)
+}
+
+func (w *MirroredChangeSetWriter) warn(op string, err error) {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&w.lastWarn)
+	if now-last < int64(mirrorWarnInterval) || !atomic.CompareAndSwapInt64(&w.lastWarn, last, now) {
+		return
+	}
+	log.Warn("Write to secondary failed", "op", op, "err", err, "failed", w.SecondaryErrors(), "dropped", w.SecondaryDropped())
+}
+
+// mirror - queues the write to secondary, arguments must not be modified by the caller after it
+func (w *MirroredChangeSetWriter) mirror(op string, write func() error) {
+	select {
+	case w.queue <- mirroredWrite{op: op, write: write}:
+	default:
+		atomic.AddUint64(&w.secondaryDropped, 1)
+		w.warn(op, errMirrorQueueFull)
+	}
+}
+
+func (w *MirroredChangeSetWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	if err := w.primary.UpdateAccountData(address, original, account); err != nil {
+		return err
+	}
+	original, account = original.SelfCopy(), account.SelfCopy()
+	w.mirror("UpdateAccountData", func() error { return w.secondary.UpdateAccountData(address, original, account) })
+	return nil
+}
+
+func (w *MirroredChangeSetWriter) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	if err := w.primary.UpdateAccountCode(address, incarnation, codeHash, code); err != nil {
+		return err
+	}
+	code = common.CopyBytes(code)
+	w.mirror("UpdateAccountCode", func() error { return w.secondary.UpdateAccountCode(address, incarnation, codeHash, code) })
+	return nil
+}
+
+func (w *MirroredChangeSetWriter) DeleteAccount(address common.Address, original *accounts.Account) error {
+	if err := w.primary.DeleteAccount(address, original); err != nil {
+		return err
+	}
+	original = original.SelfCopy()
+	w.mirror("DeleteAccount", func() error { return w.secondary.DeleteAccount(address, original) })
+	return nil
+}
+
+func (w *MirroredChangeSetWriter) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	if err := w.primary.WriteAccountStorage(address, incarnation, key, original, value); err != nil {
+		return err
+	}
+	k, original, value := *key, original.Clone(), value.Clone()
+	w.mirror("WriteAccountStorage", func() error { return w.secondary.WriteAccountStorage(address, incarnation, &k, original, value) })
+	return nil
+}
+
+func (w *MirroredChangeSetWriter) CreateContract(address common.Address) error {
+	if err := w.primary.CreateContract(address); err != nil {
+		return err
+	}
+	w.mirror("CreateContract", func() error { return w.secondary.CreateContract(address) })
+	return nil
+}
+
+func (w *MirroredChangeSetWriter) WriteChangeSets() error {
+	if err := w.primary.WriteChangeSets(); err != nil {
+		return err
+	}
+	w.mirror("WriteChangeSets", w.secondary.WriteChangeSets)
+	return nil
+}
+
+func (w *MirroredChangeSetWriter) WriteHistory() error {
+	if err := w.primary.WriteHistory(); err != nil {
+		return err
+	}
+	w.mirror("WriteHistory", w.secondary.WriteHistory)
+	return nil
+}
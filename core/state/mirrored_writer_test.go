@@ -0,0 +1,85 @@
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+type failingChangeSetWriter struct{ *NoopWriter }
+
+type blockingChangeSetWriter struct {
+	*NoopWriter
+	unblock chan struct{}
+}
+
+func (w blockingChangeSetWriter) UpdateAccountData(common.Address, *accounts.Account, *accounts.Account) error {
+	<-w.unblock
+	return nil
+}
+
+func (failingChangeSetWriter) UpdateAccountData(common.Address, *accounts.Account, *accounts.Account) error {
+	return errDiskFull
+}
+
+func TestMirroredChangeSetWriter(t *testing.T) {
+	addr := common.HexToAddress("0x1234")
+	original, account := accounts.NewAccount(), accounts.NewAccount()
+	account.Balance.SetUint64(1)
+
+	primary, secondary := NewChangeSetWriter(), NewChangeSetWriter()
+	w := NewMirroredChangeSetWriter(primary, secondary)
+	require.NoError(t, w.UpdateAccountData(addr, &original, &account))
+	w.Close()
+	primaryChanges, err := primary.GetAccountChanges()
+	require.NoError(t, err)
+	require.Equal(t, 1, primaryChanges.Len())
+	secondaryChanges, err := secondary.GetAccountChanges()
+	require.NoError(t, err)
+	require.Equal(t, primaryChanges, secondaryChanges)
+
+	// secondary failure doesn't stop writes
+	primary = NewChangeSetWriter()
+	w = NewMirroredChangeSetWriter(primary, failingChangeSetWriter{NewNoopWriter()})
+	require.NoError(t, w.UpdateAccountData(addr, &original, &account))
+	require.NoError(t, w.UpdateAccountData(addr, &account, &original))
+	w.Close()
+	require.Equal(t, uint64(2), w.SecondaryErrors())
+	primaryChanges, err = primary.GetAccountChanges()
+	require.NoError(t, err)
+	require.Equal(t, 1, primaryChanges.Len())
+
+	// primary failure is returned and secondary is not written
+	secondary = NewChangeSetWriter()
+	w = NewMirroredChangeSetWriter(failingChangeSetWriter{NewNoopWriter()}, secondary)
+	err = w.UpdateAccountData(addr, &original, &account)
+	require.True(t, errors.Is(err, errDiskFull))
+	w.Close()
+	secondaryChanges, err = secondary.GetAccountChanges()
+	require.NoError(t, err)
+	require.Equal(t, 0, secondaryChanges.Len())
+}
+
+func TestMirroredChangeSetWriterSlowSecondary(t *testing.T) {
+	addr := common.HexToAddress("0x1234")
+	original, account := accounts.NewAccount(), accounts.NewAccount()
+	account.Balance.SetUint64(1)
+
+	// secondary is stuck: primary is still written, writes which don't fit into the queue are dropped
+	primary := NewChangeSetWriter()
+	secondary := blockingChangeSetWriter{NoopWriter: NewNoopWriter(), unblock: make(chan struct{})}
+	w := newMirroredChangeSetWriter(primary, secondary, 1)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, w.UpdateAccountData(addr, &original, &account))
+	}
+	primaryChanges, err := primary.GetAccountChanges()
+	require.NoError(t, err)
+	require.Equal(t, 1, primaryChanges.Len())
+	close(secondary.unblock)
+	w.Close()
+	require.Zero(t, w.SecondaryErrors())
+	require.GreaterOrEqual(t, w.SecondaryDropped(), uint64(8))
+}
@@ -48,6 +48,7 @@ type PlainState struct {
 	blockNr                      uint64
 	storage                      map[common.Address]*btree.BTree
 	trace                        bool
+	historyCache                 *HistoryCache
 }
 
 func NewPlainState(tx kv.Tx, blockNr uint64) *PlainState {
@@ -64,6 +65,44 @@ func NewPlainState(tx kv.Tx, blockNr uint64) *PlainState {
 	}
 }
 
+// SetHistoryCache - account and storage values read as of a block are looked up in `cache` first and added to it,
+// so readers of the same block (for example repeated tracing of one block) don't walk history indices again.
+// The cache can be shared by readers of different blocks and transactions, nil disables it
+func (s *PlainState) SetHistoryCache(cache *HistoryCache) *PlainState {
+	s.historyCache = cache
+	return s
+}
+
+func (s *PlainState) getAsOf(storage bool, key []byte, blockNr uint64) ([]byte, error) {
+	var cacheKey string
+	var cached bool
+	if s.historyCache != nil {
+		var err error
+		if cacheKey, cached, err = historyCacheKey(s.tx, blockNr, key); err != nil {
+			return nil, err
+		}
+		if cached {
+			if v, ok := s.historyCache.get(cacheKey); ok {
+				return v, nil
+			}
+		}
+	}
+	var v []byte
+	var err error
+	if storage {
+		v, err = GetAsOf(s.tx, s.storageHistoryC, s.storageChangesC, true /* storage */, key, blockNr)
+	} else {
+		v, err = GetAsOf(s.tx, s.accHistoryC, s.accChangesC, false /* storage */, key, blockNr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cached {
+		s.historyCache.add(cacheKey, v)
+	}
+	return v, nil
+}
+
 func (s *PlainState) SetTrace(trace bool) {
 	s.trace = trace
 }
@@ -152,7 +191,7 @@ func (s *PlainState) ForEachStorage(addr common.Address, startLocation common.Ha
 }
 
 func (s *PlainState) ReadAccountData(address common.Address) (*accounts.Account, error) {
-	enc, err := GetAsOf(s.tx, s.accHistoryC, s.accChangesC, false /* storage */, address[:], s.blockNr)
+	enc, err := s.getAsOf(false /* storage */, address[:], s.blockNr)
 	if err != nil {
 		return nil, err
 	}
@@ -184,7 +223,7 @@ func (s *PlainState) ReadAccountData(address common.Address) (*accounts.Account,
 
 func (s *PlainState) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
 	compositeKey := dbutils.PlainGenerateCompositeStorageKey(address.Bytes(), incarnation, key.Bytes())
-	enc, err := GetAsOf(s.tx, s.storageHistoryC, s.storageChangesC, true /* storage */, compositeKey, s.blockNr)
+	enc, err := s.getAsOf(true /* storage */, compositeKey, s.blockNr)
 	if err != nil {
 		return nil, err
 	}
@@ -214,7 +253,7 @@ func (s *PlainState) ReadAccountCodeSize(address common.Address, incarnation uin
 }
 
 func (s *PlainState) ReadAccountIncarnation(address common.Address) (uint64, error) {
-	enc, err := GetAsOf(s.tx, s.accHistoryC, s.accChangesC, false /* storage */, address[:], s.blockNr+1)
+	enc, err := s.getAsOf(false /* storage */, address[:], s.blockNr+1)
 	if err != nil {
 		return 0, err
 	}
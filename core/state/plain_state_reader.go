@@ -7,6 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 )
 
@@ -40,6 +41,12 @@ func (r *PlainStateReader) ReadAccountData(address common.Address) (*accounts.Ac
 	return &a, nil
 }
 
+// GetAccountVersion - number of block which last modified the account, see PlainStateWriter.SetVersionTracking.
+// 0 if the account doesn't exist or was written without version tracking
+func (r *PlainStateReader) GetAccountVersion(address common.Address) (uint64, error) {
+	return rawdb.ReadAccountVersion(r.db, address)
+}
+
 func (r *PlainStateReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
 	compositeKey := dbutils.PlainGenerateCompositeStorageKey(address.Bytes(), incarnation, key.Bytes())
 	enc, err := r.db.GetOne(kv.PlainState, compositeKey)
@@ -2,11 +2,14 @@ package state
 
 import (
 	"encoding/binary"
+	"fmt"
+	"io"
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/turbo/shards"
 )
@@ -17,16 +20,29 @@ type putDel interface {
 	kv.Putter
 	kv.Deleter
 }
+
+// PlainStateWriter - writes are applied to `db` right away, they are all-or-nothing only as a part of its tx (or batch):
+// after a failed write the caller rolls it back, the writer has no units of its own
 type PlainStateWriter struct {
 	db          putDel
 	csw         *ChangeSetWriter
 	accumulator *shards.Accumulator
+	blockNumber uint64
+
+	trackVersions bool
+	versionBlock  uint64
+	hashedState   bool
+
+	wal      io.Writer
+	walBlock uint64
+	walBuf   []byte // records of writes since the last CommitWAL
 }
 
 func NewPlainStateWriter(db putDel, changeSetsDB kv.RwTx, blockNumber uint64) *PlainStateWriter {
 	return &PlainStateWriter{
-		db:  db,
-		csw: NewChangeSetWriterPlain(changeSetsDB, blockNumber),
+		db:          db,
+		csw:         NewChangeSetWriterPlain(changeSetsDB, blockNumber),
+		blockNumber: blockNumber,
 	}
 }
 
@@ -41,22 +57,110 @@ func (w *PlainStateWriter) SetAccumulator(accumulator *shards.Accumulator) *Plai
 	return w
 }
 
+// SetVersionTracking - UpdateAccountData will record `blockNumber` in rawdb.AccountVersion as the block which last
+// modified the account, DeleteAccount will delete the record. See PlainStateReader.GetAccountVersion.
+// Block number is explicit, because the writer without history doesn't know it. Off by default
+func (w *PlainStateWriter) SetVersionTracking(blockNumber uint64) *PlainStateWriter {
+	w.trackVersions = true
+	w.versionBlock = blockNumber
+	return w
+}
+
+// SetHashedState - writer will also update HashedAccounts, HashedStorage and ContractCode, the same way HashState
+// stage promotes PlainState and PlainContractCode, so both views stay consistent without running the stage.
+// For small chains (dev, tests) where running the whole staged pipeline per block is too much. Off by default
+func (w *PlainStateWriter) SetHashedState(on bool) *PlainStateWriter {
+	w.hashedState = on
+	return w
+}
+
+// SetWAL - every Put/Delete issued by the writer (including code and incarnation map) will be recorded with
+// `blockNumber` and appended to `wal` by CommitWAL. Block number is explicit, because the writer without history
+// doesn't know it. See WALRecord for the format
+func (w *PlainStateWriter) SetWAL(wal io.Writer, blockNumber uint64) *PlainStateWriter {
+	w.wal = wal
+	w.walBlock = blockNumber
+	return w
+}
+
+// CommitWAL - appends records of the writes since the previous call to the WAL. Writes are atomic only as a part
+// of the db tx (or batch) the writer writes to, so call it after that tx is committed, and DiscardWAL after rollback
+func (w *PlainStateWriter) CommitWAL() error {
+	if w.wal == nil || len(w.walBuf) == 0 {
+		return nil
+	}
+	if _, err := w.wal.Write(w.walBuf); err != nil {
+		return fmt.Errorf("writing WAL: %w", err)
+	}
+	w.walBuf = w.walBuf[:0]
+	return nil
+}
+
+// DiscardWAL - drops records of the writes since the previous CommitWAL
+func (w *PlainStateWriter) DiscardWAL() {
+	w.walBuf = w.walBuf[:0]
+}
+
+func (w *PlainStateWriter) put(table string, k, v []byte) error {
+	if err := w.db.Put(table, k, v); err != nil {
+		return err
+	}
+	if w.wal != nil {
+		w.walBuf = appendWALRecord(w.walBuf, WALPut, w.walBlock, table, k, v)
+	}
+	return nil
+}
+
+func (w *PlainStateWriter) delete(table string, k []byte) error {
+	if err := w.db.Delete(table, k, nil); err != nil {
+		return err
+	}
+	if w.wal != nil {
+		w.walBuf = appendWALRecord(w.walBuf, WALDelete, w.walBlock, table, k, nil)
+	}
+	return nil
+}
+
 func (w *PlainStateWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	return wrapStateWriteErr(OpUpdateAccountData, address, nil, w.updateAccountData(address, original, account))
+}
+
+func (w *PlainStateWriter) updateAccountData(address common.Address, original, account *accounts.Account) error {
 	//fmt.Printf("balance,%x,%d\n", address, &account.Balance)
 	if w.csw != nil {
 		if err := w.csw.UpdateAccountData(address, original, account); err != nil {
 			return err
 		}
 	}
+	if w.trackVersions {
+		var v [8]byte
+		binary.BigEndian.PutUint64(v[:], w.versionBlock)
+		if err := w.put(rawdb.AccountVersion, address[:], v[:]); err != nil {
+			return err
+		}
+	}
 	value := make([]byte, account.EncodingLengthForStorage())
 	account.EncodeForStorage(value)
 	if w.accumulator != nil {
 		w.accumulator.ChangeAccount(address, account.Incarnation, value)
 	}
-	return w.db.Put(kv.PlainState, address[:], value)
+	if w.hashedState {
+		addrHash, err := common.HashData(address[:])
+		if err != nil {
+			return err
+		}
+		if err := w.put(kv.HashedAccounts, addrHash[:], value); err != nil {
+			return err
+		}
+	}
+	return w.put(kv.PlainState, address[:], value)
 }
 
 func (w *PlainStateWriter) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	return wrapStateWriteErr(OpUpdateAccountCode, address, nil, w.updateAccountCode(address, incarnation, codeHash, code))
+}
+
+func (w *PlainStateWriter) updateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
 	//fmt.Printf("code,%x,%x\n", address, code)
 	if w.csw != nil {
 		if err := w.csw.UpdateAccountCode(address, incarnation, codeHash, code); err != nil {
@@ -66,13 +170,26 @@ func (w *PlainStateWriter) UpdateAccountCode(address common.Address, incarnation
 	if w.accumulator != nil {
 		w.accumulator.ChangeCode(address, incarnation, code)
 	}
-	if err := w.db.Put(kv.Code, codeHash[:], code); err != nil {
+	if err := w.put(kv.Code, codeHash[:], code); err != nil {
 		return err
 	}
-	return w.db.Put(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], incarnation), codeHash[:])
+	if w.hashedState {
+		addrHash, err := common.HashData(address[:])
+		if err != nil {
+			return err
+		}
+		if err := w.put(kv.ContractCode, dbutils.GenerateStoragePrefix(addrHash[:], incarnation), codeHash[:]); err != nil {
+			return err
+		}
+	}
+	return w.put(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], incarnation), codeHash[:])
 }
 
 func (w *PlainStateWriter) DeleteAccount(address common.Address, original *accounts.Account) error {
+	return wrapStateWriteErr(OpDeleteAccount, address, nil, w.deleteAccount(address, original))
+}
+
+func (w *PlainStateWriter) deleteAccount(address common.Address, original *accounts.Account) error {
 	//fmt.Printf("delete,%x\n", address)
 	if w.csw != nil {
 		if err := w.csw.DeleteAccount(address, original); err != nil {
@@ -82,13 +199,27 @@ func (w *PlainStateWriter) DeleteAccount(address common.Address, original *accou
 	if w.accumulator != nil {
 		w.accumulator.DeleteAccount(address)
 	}
-	if err := w.db.Delete(kv.PlainState, address[:], nil); err != nil {
+	if err := w.delete(kv.PlainState, address[:]); err != nil {
 		return err
 	}
+	if w.trackVersions {
+		if err := w.delete(rawdb.AccountVersion, address[:]); err != nil {
+			return err
+		}
+	}
+	if w.hashedState {
+		addrHash, err := common.HashData(address[:])
+		if err != nil {
+			return err
+		}
+		if err := w.delete(kv.HashedAccounts, addrHash[:]); err != nil {
+			return err
+		}
+	}
 	if original.Incarnation > 0 {
 		var b [8]byte
 		binary.BigEndian.PutUint64(b[:], original.Incarnation)
-		if err := w.db.Put(kv.IncarnationMap, address[:], b[:]); err != nil {
+		if err := w.put(kv.IncarnationMap, address[:], b[:]); err != nil {
 			return err
 		}
 	}
@@ -96,6 +227,10 @@ func (w *PlainStateWriter) DeleteAccount(address common.Address, original *accou
 }
 
 func (w *PlainStateWriter) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	return wrapStateWriteErr(OpWriteAccountStorage, address, key, w.writeAccountStorage(address, incarnation, key, original, value))
+}
+
+func (w *PlainStateWriter) writeAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
 	//fmt.Printf("storage,%x,%x,%x\n", address, *key, value.Bytes())
 	if w.csw != nil {
 		if err := w.csw.WriteAccountStorage(address, incarnation, key, original, value); err != nil {
@@ -111,16 +246,37 @@ func (w *PlainStateWriter) WriteAccountStorage(address common.Address, incarnati
 	if w.accumulator != nil {
 		w.accumulator.ChangeStorage(address, incarnation, *key, v)
 	}
+	if w.hashedState {
+		if err := w.writeHashedStorage(address, incarnation, key, v); err != nil {
+			return err
+		}
+	}
 	if len(v) == 0 {
-		return w.db.Delete(kv.PlainState, compositeKey, nil)
+		return w.delete(kv.PlainState, compositeKey)
 	}
-	return w.db.Put(kv.PlainState, compositeKey, v)
+	return w.put(kv.PlainState, compositeKey, v)
+}
+
+func (w *PlainStateWriter) writeHashedStorage(address common.Address, incarnation uint64, key *common.Hash, v []byte) error {
+	addrHash, err := common.HashData(address[:])
+	if err != nil {
+		return err
+	}
+	locHash, err := common.HashData(key[:])
+	if err != nil {
+		return err
+	}
+	compositeKey := dbutils.GenerateCompositeStorageKey(addrHash, incarnation, locHash)
+	if len(v) == 0 {
+		return w.delete(kv.HashedStorage, compositeKey)
+	}
+	return w.put(kv.HashedStorage, compositeKey, v)
 }
 
 func (w *PlainStateWriter) CreateContract(address common.Address) error {
 	if w.csw != nil {
 		if err := w.csw.CreateContract(address); err != nil {
-			return err
+			return wrapStateWriteErr(OpCreateContract, address, nil, err)
 		}
 	}
 	return nil
@@ -128,7 +284,7 @@ func (w *PlainStateWriter) CreateContract(address common.Address) error {
 
 func (w *PlainStateWriter) WriteChangeSets() error {
 	if w.csw != nil {
-		return w.csw.WriteChangeSets()
+		return wrapStateWriteErr(OpWriteChangeSets, common.Address{}, nil, w.csw.WriteChangeSets())
 	}
 
 	return nil
@@ -136,7 +292,7 @@ func (w *PlainStateWriter) WriteChangeSets() error {
 
 func (w *PlainStateWriter) WriteHistory() error {
 	if w.csw != nil {
-		return w.csw.WriteHistory()
+		return wrapStateWriteErr(OpWriteHistory, common.Address{}, nil, w.csw.WriteHistory())
 	}
 
 	return nil
@@ -0,0 +1,246 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+var errDiskFull = errors.New("disk full")
+
+type failingPutDel struct{}
+
+func (failingPutDel) Put(bucket string, key, value []byte) error { return errDiskFull }
+func (failingPutDel) Delete(bucket string, k, v []byte) error    { return errDiskFull }
+
+func TestPlainStateWriterErrors(t *testing.T) {
+	w := NewPlainStateWriterNoHistory(failingPutDel{})
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	acc := accounts.NewAccount()
+
+	err := w.UpdateAccountData(addr, &acc, &acc)
+	var writeErr *StateWriteError
+	require.True(t, errors.As(err, &writeErr))
+	require.Equal(t, OpUpdateAccountData, writeErr.Op)
+	require.Equal(t, addr, writeErr.Address)
+	require.Nil(t, writeErr.Slot)
+	require.True(t, errors.Is(err, errDiskFull))
+
+	slot := common.HexToHash("0x01")
+	err = w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(0), uint256.NewInt(1))
+	require.True(t, errors.As(err, &writeErr))
+	require.Equal(t, OpWriteAccountStorage, writeErr.Op)
+	require.Equal(t, slot, *writeErr.Slot)
+	slot[0] = 0xff // error keeps its own copy of the slot
+	require.Equal(t, common.HexToHash("0x01"), *writeErr.Slot)
+
+	err = w.UpdateAccountCode(addr, 1, common.HexToHash("0x02"), []byte{1})
+	require.True(t, errors.As(err, &writeErr))
+	require.Equal(t, OpUpdateAccountCode, writeErr.Op)
+
+	err = w.DeleteAccount(addr, &acc)
+	require.True(t, errors.As(err, &writeErr))
+	require.Equal(t, OpDeleteAccount, writeErr.Op)
+
+	// storage write which doesn't change the value doesn't touch the db
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(1), uint256.NewInt(1)))
+}
+
+func TestPlainStateWriterWAL(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	var wal bytes.Buffer
+	w := NewPlainStateWriterNoHistory(tx).SetWAL(&wal, 7)
+
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	emptyAcc := accounts.NewAccount()
+	acc := accounts.NewAccount()
+	acc.Nonce = 1
+	acc.Incarnation = 1
+	require.NoError(t, w.UpdateAccountData(addr, &emptyAcc, &acc))
+	codeHash := common.HexToHash("0x02")
+	require.NoError(t, w.UpdateAccountCode(addr, 1, codeHash, []byte{0x60}))
+	slot := common.HexToHash("0x01")
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(0), uint256.NewInt(5)))
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(5), uint256.NewInt(0)))
+	require.NoError(t, w.DeleteAccount(addr, &acc))
+	// records are appended only when the writes are committed
+	require.Zero(t, wal.Len())
+	require.NoError(t, w.CommitWAL())
+
+	// writes of rolled back tx are not recorded
+	require.NoError(t, w.UpdateAccountData(addr, &emptyAcc, &acc))
+	w.DiscardWAL()
+	require.NoError(t, w.CommitWAL())
+
+	var ops []WALOp
+	var tables []string
+	r := bufio.NewReader(&wal)
+	for {
+		rec, err := ReadWALRecord(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, uint64(7), rec.BlockNumber)
+		ops = append(ops, rec.Op)
+		tables = append(tables, rec.Table)
+	}
+	require.Equal(t, []WALOp{WALPut, WALPut, WALPut, WALPut, WALDelete, WALDelete, WALPut}, ops)
+	require.Equal(t, []string{kv.PlainState, kv.Code, kv.PlainContractCode, kv.PlainState, kv.PlainState, kv.PlainState, kv.IncarnationMap}, tables)
+
+	_, err := ReadWALRecord(bufio.NewReader(bytes.NewReader([]byte{byte(WALPut), 7, 3, 'a'})))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestDeleteEmptyAccounts(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	emptyAddr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	drainedAddr := common.HexToAddress("0x1000000000000000000000000000000000000002")
+	untouchedAddr := common.HexToAddress("0x1000000000000000000000000000000000000003")
+
+	w := NewPlainStateWriterNoHistory(tx)
+	emptyAcc := accounts.NewAccount()
+	acc := accounts.NewAccount()
+	acc.Balance.SetUint64(1)
+	require.NoError(t, w.UpdateAccountData(emptyAddr, &emptyAcc, &emptyAcc))
+	require.NoError(t, w.UpdateAccountData(drainedAddr, &emptyAcc, &acc))
+	require.NoError(t, w.UpdateAccountData(untouchedAddr, &emptyAcc, &emptyAcc))
+
+	touch := func() *IntraBlockState {
+		ibs := New(NewPlainStateReader(tx))
+		ibs.AddBalance(emptyAddr, uint256.NewInt(0))
+		ibs.SubBalance(drainedAddr, uint256.NewInt(1))
+		ibs.GetBalance(untouchedAddr)
+		return ibs
+	}
+
+	// before Spurious Dragon empty accounts are kept
+	require.NoError(t, touch().FinalizeTx(&params.Rules{}, w))
+	for _, addr := range []common.Address{emptyAddr, drainedAddr, untouchedAddr} {
+		a, err := NewPlainStateReader(tx).ReadAccountData(addr)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+	}
+
+	deleted, err := touch().DeleteEmptyAccounts(&params.Rules{IsSpuriousDragon: true}, NewNoopWriter())
+	require.NoError(t, err)
+	require.Equal(t, map[common.Address]struct{}{emptyAddr: {}, drainedAddr: {}}, deleted)
+
+	require.NoError(t, touch().FinalizeTx(&params.Rules{IsSpuriousDragon: true}, w))
+	a, err := NewPlainStateReader(tx).ReadAccountData(emptyAddr)
+	require.NoError(t, err)
+	require.Nil(t, a)
+	a, err = NewPlainStateReader(tx).ReadAccountData(drainedAddr)
+	require.NoError(t, err)
+	require.Nil(t, a)
+	a, err = NewPlainStateReader(tx).ReadAccountData(untouchedAddr)
+	require.NoError(t, err)
+	require.NotNil(t, a)
+}
+
+func TestPlainStateWriterVersionTracking(t *testing.T) {
+	db := mdbx.NewMDBX(log.New()).InMem().WithTablessCfg(rawdb.WithChaindataTables).MustOpen()
+	t.Cleanup(db.Close)
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(tx.Rollback)
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	emptyAcc := accounts.NewAccount()
+	acc := accounts.NewAccount()
+	acc.Nonce = 1
+	acc.CodeHash = common.HexToHash("0x02")
+	require.NoError(t, NewPlainStateWriter(tx, tx, 7).UpdateAccountData(addr, &emptyAcc, &acc))
+	r := NewPlainStateReader(tx)
+	version, err := r.GetAccountVersion(addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), version)
+	untracked, err := tx.GetOne(kv.PlainState, addr[:])
+	require.NoError(t, err)
+
+	require.NoError(t, NewPlainStateWriter(tx, tx, 300).SetVersionTracking(300).UpdateAccountData(addr, &emptyAcc, &acc))
+	version, err = r.GetAccountVersion(addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(300), version)
+	tracked, err := tx.GetOne(kv.PlainState, addr[:])
+	require.NoError(t, err)
+	require.Equal(t, untracked, tracked) // account encoding doesn't change
+
+	// writer without version tracking doesn't touch the version
+	acc.Nonce = 2
+	require.NoError(t, NewPlainStateWriter(tx, tx, 301).UpdateAccountData(addr, &emptyAcc, &acc))
+	version, err = r.GetAccountVersion(addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(300), version)
+
+	require.NoError(t, NewPlainStateWriterNoHistory(tx).SetVersionTracking(302).DeleteAccount(addr, &acc))
+	version, err = r.GetAccountVersion(addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), version)
+
+	version, err = r.GetAccountVersion(common.HexToAddress("0x1000000000000000000000000000000000000002"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), version)
+}
+
+func TestPlainStateWriterHashedState(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	slot := common.HexToHash("0x01")
+	code := []byte{0x60, 0x00}
+	codeHash := common.BytesToHash(crypto.Keccak256(code))
+
+	w := NewPlainStateWriterNoHistory(tx).SetHashedState(true)
+	original, acc := accounts.NewAccount(), accounts.NewAccount()
+	acc.Balance.SetUint64(5)
+	acc.Incarnation = 1
+	acc.CodeHash = codeHash
+	require.NoError(t, w.UpdateAccountData(addr, &original, &acc))
+	require.NoError(t, w.UpdateAccountCode(addr, 1, codeHash, code))
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(0), uint256.NewInt(7)))
+
+	hashed, plain := NewDbStateReader(tx), NewPlainStateReader(tx)
+	hashedAcc, err := hashed.ReadAccountData(addr)
+	require.NoError(t, err)
+	plainAcc, err := plain.ReadAccountData(addr)
+	require.NoError(t, err)
+	require.Equal(t, plainAcc, hashedAcc)
+	v, err := hashed.ReadAccountStorage(addr, 1, &slot)
+	require.NoError(t, err)
+	require.Equal(t, []byte{7}, v)
+	addrHash, err := common.HashData(addr[:])
+	require.NoError(t, err)
+	v, err = tx.GetOne(kv.ContractCode, dbutils.GenerateStoragePrefix(addrHash[:], 1))
+	require.NoError(t, err)
+	require.Equal(t, codeHash[:], v)
+
+	require.NoError(t, w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(7), uint256.NewInt(0)))
+	v, err = hashed.ReadAccountStorage(addr, 1, &slot)
+	require.NoError(t, err)
+	require.Nil(t, v)
+	require.NoError(t, w.DeleteAccount(addr, &acc))
+	hashedAcc, err = hashed.ReadAccountData(addr)
+	require.NoError(t, err)
+	require.Nil(t, hashedAcc)
+
+	// off by default
+	require.NoError(t, NewPlainStateWriterNoHistory(tx).UpdateAccountData(addr, &original, &acc))
+	hashedAcc, err = hashed.ReadAccountData(addr)
+	require.NoError(t, err)
+	require.Nil(t, hashedAcc)
+}
@@ -0,0 +1,40 @@
+package state
+
+import (
+	"bytes"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+)
+
+// WalkStorage - calls `walker` for storage slots of the account with given incarnation in current PlainState, in order
+// of location, starting from `startLocation`. Stops after `limit` slots (0 - no limit) or when walker returns false.
+// `value` is valid only during the call
+func WalkStorage(tx kv.Tx, address common.Address, incarnation uint64, startLocation common.Hash, limit int, walker func(location common.Hash, value []byte) (bool, error)) error {
+	prefix := dbutils.PlainGenerateStoragePrefix(address[:], incarnation)
+	c, err := tx.Cursor(kv.PlainState)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var count int
+	for k, v, err := c.Seek(append(common.CopyBytes(prefix), startLocation[:]...)); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, prefix) {
+			break
+		}
+		goOn, err := walker(common.BytesToHash(k[len(prefix):]), v)
+		if err != nil {
+			return err
+		}
+		count++
+		if !goOn || (limit > 0 && count >= limit) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkStorage(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	next := common.HexToAddress("0x1000000000000000000000000000000000000002")
+	w := NewPlainStateWriterNoHistory(tx)
+	for i := 1; i <= 5; i++ {
+		loc := common.Hash{byte(i)}
+		require.NoError(t, w.WriteAccountStorage(addr, 2, &loc, uint256.NewInt(0), uint256.NewInt(uint64(i))))
+		require.NoError(t, w.WriteAccountStorage(addr, 1, &loc, uint256.NewInt(0), uint256.NewInt(100))) // other incarnation
+		require.NoError(t, w.WriteAccountStorage(next, 2, &loc, uint256.NewInt(0), uint256.NewInt(200)))
+	}
+
+	walk := func(start common.Hash, limit int, stopAt int) (locs []byte, values []byte) {
+		require.NoError(t, WalkStorage(tx, addr, 2, start, limit, func(location common.Hash, value []byte) (bool, error) {
+			locs = append(locs, location[0])
+			values = append(values, value...)
+			return len(locs) != stopAt, nil
+		}))
+		return locs, values
+	}
+	locs, values := walk(common.Hash{}, 0, 0)
+	require.Equal(t, []byte{1, 2, 3, 4, 5}, locs)
+	require.Equal(t, []byte{1, 2, 3, 4, 5}, values)
+	locs, _ = walk(common.Hash{3}, 0, 0)
+	require.Equal(t, []byte{3, 4, 5}, locs)
+	locs, _ = walk(common.Hash{2, 1}, 2, 0)
+	require.Equal(t, []byte{3, 4}, locs)
+	locs, _ = walk(common.Hash{}, 0, 1)
+	require.Equal(t, []byte{1}, locs)
+
+	require.NoError(t, WalkStorage(tx, addr, 3, common.Hash{}, 0, func(common.Hash, []byte) (bool, error) {
+		t.Fatal("no storage of incarnation 3")
+		return false, nil
+	}))
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+// StateBatch - reads many state items with one cursor walk over the hashed state instead of random lookups
+type StateBatch struct {
+	tx kv.Tx
+}
+
+func NewStateBatch(tx kv.Tx) *StateBatch {
+	return &StateBatch{tx: tx}
+}
+
+// GetAccountsBatch - accounts of `addresses`, in the same order. Addresses are visited in order of their hashes,
+// so the cursor only moves forward. Accounts which don't exist are returned as zero value (Initialised == false)
+func (b *StateBatch) GetAccountsBatch(addresses []common.Address) ([]accounts.Account, error) {
+	type item struct {
+		addrHash common.Hash
+		idx      int
+	}
+	items := make([]item, len(addresses))
+	for i := range addresses {
+		addrHash, err := common.HashData(addresses[i][:])
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item{addrHash: addrHash, idx: i}
+	}
+	sort.Slice(items, func(i, j int) bool { return bytes.Compare(items[i].addrHash[:], items[j].addrHash[:]) < 0 })
+
+	c, err := b.tx.Cursor(kv.HashedAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	res := make([]accounts.Account, len(addresses))
+	var k, v []byte
+	for i, it := range items {
+		if i > 0 && it.addrHash == items[i-1].addrHash {
+			res[it.idx] = res[items[i-1].idx]
+			continue
+		}
+		// the cursor is already at or after the wanted key - don't seek backwards
+		if k == nil || bytes.Compare(k, it.addrHash[:]) < 0 {
+			if k, v, err = c.Seek(it.addrHash[:]); err != nil {
+				return nil, err
+			}
+		}
+		if k == nil || !bytes.Equal(k, it.addrHash[:]) {
+			continue
+		}
+		if err = res[it.idx].DecodeForStorage(v); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,47 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountsBatch(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	var addresses []common.Address
+	for i := 0; i < 10; i++ {
+		addr := common.BytesToAddress([]byte{byte(i + 1)})
+		addresses = append(addresses, addr)
+		if i%3 == 0 {
+			continue // not in the db
+		}
+		acc := accounts.NewAccount()
+		acc.Nonce = uint64(i)
+		v := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(v)
+		addrHash, err := common.HashData(addr[:])
+		require.NoError(t, err)
+		require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], v))
+	}
+	addresses = append(addresses, addresses[2]) // duplicates are allowed
+
+	res, err := NewStateBatch(tx).GetAccountsBatch(addresses)
+	require.NoError(t, err)
+	require.Len(t, res, len(addresses))
+	r := NewDbStateReader(tx)
+	for i, addr := range addresses {
+		expected, err := r.ReadAccountData(addr)
+		require.NoError(t, err)
+		if expected == nil {
+			require.False(t, res[i].Initialised, i)
+			continue
+		}
+		require.True(t, res[i].Initialised, i)
+		require.Equal(t, expected.Nonce, res[i].Nonce, i)
+	}
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// StateSnapshot - read-only view of the state after block `blockNr`, pinned to one read transaction.
+// Read transaction sees the db as of its start, so commits of staged sync made after that are not visible:
+// all reads through the snapshot are consistent with each other. Latest block is read from PlainState,
+// older ones from history. Must be closed. Not safe for concurrent use - read transaction is bound to its goroutine
+type StateSnapshot struct {
+	tx      kv.Tx
+	reader  StateReader
+	blockNr uint64
+}
+
+func NewStateSnapshot(ctx context.Context, db kv.RoDB, blockNr uint64) (*StateSnapshot, error) {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if blockNr > executed {
+		tx.Rollback()
+		return nil, fmt.Errorf("state of block %d is not available, execution stage is at %d", blockNr, executed)
+	}
+	var reader StateReader
+	if blockNr == executed {
+		reader = NewPlainStateReader(tx)
+	} else {
+		reader = NewPlainState(tx, blockNr+1)
+	}
+	return &StateSnapshot{tx: tx, reader: reader, blockNr: blockNr}, nil
+}
+
+// BlockNumber - block, state after which is seen by the snapshot
+func (s *StateSnapshot) BlockNumber() uint64 { return s.blockNr }
+
+// GetAccount - nil if account doesn't exist
+func (s *StateSnapshot) GetAccount(address common.Address) (*accounts.Account, error) {
+	return s.reader.ReadAccountData(address)
+}
+
+// GetStorage - value of the slot of the current incarnation of the account, nil if it's empty or account doesn't exist
+func (s *StateSnapshot) GetStorage(address common.Address, key common.Hash) ([]byte, error) {
+	acc, err := s.reader.ReadAccountData(address)
+	if err != nil || acc == nil {
+		return nil, err
+	}
+	return s.reader.ReadAccountStorage(address, acc.Incarnation, &key)
+}
+
+// GetCode - nil if account doesn't exist or has no code
+func (s *StateSnapshot) GetCode(address common.Address) ([]byte, error) {
+	acc, err := s.reader.ReadAccountData(address)
+	if err != nil || acc == nil {
+		return nil, err
+	}
+	return s.reader.ReadAccountCode(address, acc.Incarnation, acc.CodeHash)
+}
+
+// Close - releases the read transaction, snapshot can't be used after it
+func (s *StateSnapshot) Close() {
+	s.tx.Rollback()
+}
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateSnapshot(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	slot := common.HexToHash("0x01")
+	code := []byte{0x60, 0x00}
+	acc := accounts.NewAccount()
+	acc.Balance.SetUint64(1)
+	acc.Incarnation = 1
+	acc.CodeHash = crypto.Keccak256Hash(code)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		w := NewPlainStateWriterNoHistory(tx)
+		original := accounts.NewAccount()
+		if err := w.UpdateAccountData(addr, &original, &acc); err != nil {
+			return err
+		}
+		if err := w.UpdateAccountCode(addr, 1, acc.CodeHash, code); err != nil {
+			return err
+		}
+		if err := w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(0), uint256.NewInt(7)); err != nil {
+			return err
+		}
+		return stages.SaveStageProgress(tx, stages.Execution, 10)
+	}))
+
+	_, err := NewStateSnapshot(context.Background(), db, 11)
+	require.Error(t, err)
+
+	snapshot, err := NewStateSnapshot(context.Background(), db, 10)
+	require.NoError(t, err)
+	defer snapshot.Close()
+	require.Equal(t, uint64(10), snapshot.BlockNumber())
+
+	// changes committed after the snapshot was opened are not visible through it
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		w := NewPlainStateWriterNoHistory(tx)
+		changed := acc
+		changed.Balance.SetUint64(2)
+		if err := w.UpdateAccountData(addr, &acc, &changed); err != nil {
+			return err
+		}
+		if err := w.WriteAccountStorage(addr, 1, &slot, uint256.NewInt(7), uint256.NewInt(8)); err != nil {
+			return err
+		}
+		return stages.SaveStageProgress(tx, stages.Execution, 11)
+	}))
+
+	a, err := snapshot.GetAccount(addr)
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	require.Equal(t, uint64(1), a.Balance.Uint64())
+	v, err := snapshot.GetStorage(addr, slot)
+	require.NoError(t, err)
+	require.Equal(t, []byte{7}, v)
+	c, err := snapshot.GetCode(addr)
+	require.NoError(t, err)
+	require.Equal(t, code, c)
+
+	missing := common.HexToAddress("0x1000000000000000000000000000000000000002")
+	a, err = snapshot.GetAccount(missing)
+	require.NoError(t, err)
+	require.Nil(t, a)
+	v, err = snapshot.GetStorage(missing, slot)
+	require.NoError(t, err)
+	require.Nil(t, v)
+}
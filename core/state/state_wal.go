@@ -0,0 +1,93 @@
+package state
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WALOp - type of the operation recorded in the state writer WAL
+type WALOp uint8
+
+const (
+	WALPut WALOp = iota
+	WALDelete
+)
+
+// WALRecord - one Put/Delete issued by PlainStateWriter.
+// Encoding: op (1 byte), then uvarint-prefixed block number, table, key and value
+type WALRecord struct {
+	Op          WALOp
+	BlockNumber uint64
+	Table       string
+	Key, Value  []byte
+}
+
+func appendWALRecord(buf []byte, op WALOp, blockNumber uint64, table string, k, v []byte) []byte {
+	buf = append(buf, byte(op))
+	buf = appendUvarint(buf, blockNumber)
+	buf = appendUvarint(buf, uint64(len(table)))
+	buf = append(buf, table...)
+	buf = appendUvarint(buf, uint64(len(k)))
+	buf = append(buf, k...)
+	buf = appendUvarint(buf, uint64(len(v)))
+	buf = append(buf, v...)
+	return buf
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	return append(buf, tmp[:n]...)
+}
+
+// ReadWALRecord - decodes next record written by PlainStateWriter.SetWAL, returns io.EOF at the end of the log
+func ReadWALRecord(r *bufio.Reader) (*WALRecord, error) {
+	op, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if WALOp(op) != WALPut && WALOp(op) != WALDelete {
+		return nil, fmt.Errorf("unknown WAL op: %d", op)
+	}
+	rec := &WALRecord{Op: WALOp(op)}
+	if rec.BlockNumber, err = binary.ReadUvarint(r); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	table, err := readWALBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	rec.Table = string(table)
+	if rec.Key, err = readWALBytes(r); err != nil {
+		return nil, err
+	}
+	if rec.Value, err = readWALBytes(r); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func readWALBytes(r *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if l == 0 {
+		return nil, nil
+	}
+	b := make([]byte, l)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return b, nil
+}
+
+// record was cut in the middle
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
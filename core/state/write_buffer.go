@@ -0,0 +1,47 @@
+package state
+
+import (
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// WriteBuffer - view of storage writes done within current block which are not yet flushed to StateWriter.
+// Reads through IntraBlockState.GetState look here before the committed value, that's what provides
+// read-your-writes semantics during EVM execution.
+// Writes through the buffer are journaled exactly like IntraBlockState.SetState, so they can be reverted.
+type WriteBuffer struct {
+	sdb *IntraBlockState
+}
+
+func (sdb *IntraBlockState) WriteBuffer() WriteBuffer {
+	return WriteBuffer{sdb: sdb}
+}
+
+func (b WriteBuffer) Set(addr common.Address, key common.Hash, value uint256.Int) {
+	b.sdb.SetState(addr, &key, value)
+}
+
+// Get - returns value written to given slot, found=false means the slot wasn't written and
+// its value has to be read from the committed state
+func (b WriteBuffer) Get(addr common.Address, key common.Hash) (value uint256.Int, found bool) {
+	so, ok := b.sdb.stateObjects[addr]
+	if !ok || so == nil {
+		return value, false
+	}
+	value, found = so.dirtyStorage[key]
+	return value, found
+}
+
+// ForEach - iterates over all buffered writes in no particular order, stops when `f` returns false
+func (b WriteBuffer) ForEach(f func(addr common.Address, key common.Hash, value uint256.Int) bool) {
+	for addr, so := range b.sdb.stateObjects {
+		if so == nil {
+			continue
+		}
+		for key, value := range so.dirtyStorage {
+			if !f(addr, key, value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	sdb := New(NewPlainState(tx, 1))
+	buf := sdb.WriteBuffer()
+
+	addr := common.HexToAddress("0xaa")
+	key := common.HexToHash("0x01")
+	_, found := buf.Get(addr, key)
+	require.False(t, found)
+
+	snapshot := sdb.Snapshot()
+	buf.Set(addr, key, *uint256.NewInt(42))
+	value, found := buf.Get(addr, key)
+	require.True(t, found)
+	require.Equal(t, *uint256.NewInt(42), value)
+
+	// reads through IntraBlockState see the buffered write
+	var out uint256.Int
+	sdb.GetState(addr, &key, &out)
+	require.Equal(t, *uint256.NewInt(42), out)
+
+	var count int
+	buf.ForEach(func(a common.Address, k common.Hash, v uint256.Int) bool {
+		require.Equal(t, addr, a)
+		require.Equal(t, key, k)
+		count++
+		return true
+	})
+	require.Equal(t, 1, count)
+
+	sdb.RevertToSnapshot(snapshot)
+	_, found = buf.Get(addr, key)
+	require.False(t, found)
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+)
+
+// StateOverlay - executes transactions of several consecutive (simulated) blocks on top of the state after `startBlock`,
+// state changes of every block are visible to the next one. Writes are kept only in memory (shards.StateCache),
+// the db is never written to.
+//
+// Simulated blocks are not validated against their headers and block rewards/system calls are not applied:
+// only transactions are executed.
+type StateOverlay struct {
+	chainConfig *params.ChainConfig
+	engine      consensus.Engine
+	getHeader   func(hash common.Hash, number uint64) *types.Header
+	vmConfig    vm.Config
+
+	cache    *shards.StateCache
+	reader   *state.CachedReader
+	writer   *state.CachedWriter
+	blockNum uint64 // last block, state of which is in the overlay
+}
+
+func NewStateOverlay(tx kv.Tx, chainConfig *params.ChainConfig, engine consensus.Engine, startBlock uint64) (*StateOverlay, error) {
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, err
+	}
+	if startBlock > executed {
+		return nil, fmt.Errorf("state of block %d is not available, execution stage is at %d", startBlock, executed)
+	}
+	cache := shards.NewStateCache(32, 0 /* no limit */) // keeps state writes, so can't evict
+	return &StateOverlay{
+		chainConfig: chainConfig,
+		engine:      engine,
+		getHeader: func(hash common.Hash, number uint64) *types.Header {
+			return rawdb.ReadHeader(tx, hash, number)
+		},
+		cache:    cache,
+		reader:   state.NewCachedReader(state.NewPlainState(tx, startBlock+1), cache),
+		writer:   state.NewCachedWriter(state.NewNoopWriter(), cache),
+		blockNum: startBlock,
+	}, nil
+}
+
+func (o *StateOverlay) SetVMConfig(cfg vm.Config) *StateOverlay {
+	o.vmConfig = cfg
+	return o
+}
+
+// BlockNumber - number of the last block executed in the overlay (or startBlock)
+func (o *StateOverlay) BlockNumber() uint64 { return o.blockNum }
+
+// StateReader - reads the state after the last executed block
+func (o *StateOverlay) StateReader() state.StateReader { return o.reader }
+
+// AdvanceBlock - executes `txs` in the context of `header`, which must be the next block after BlockNumber().
+// If any transaction fails, none of the changes of this block are kept
+func (o *StateOverlay) AdvanceBlock(header *types.Header, txs types.Transactions) (types.Receipts, error) {
+	if o.cache == nil {
+		return nil, fmt.Errorf("state overlay is closed")
+	}
+	blockNum := header.Number.Uint64()
+	if blockNum != o.blockNum+1 {
+		return nil, fmt.Errorf("expected block %d, got %d", o.blockNum+1, blockNum)
+	}
+
+	ibs := state.New(o.reader)
+	gp := new(GasPool).AddGas(header.GasLimit)
+	usedGas := new(uint64)
+	noop := state.NewNoopWriter()
+	receipts := make(types.Receipts, 0, len(txs))
+	for i, txn := range txs {
+		ibs.Prepare(txn.Hash(), header.Hash(), i)
+		receipt, _, err := ApplyTransaction(o.chainConfig, o.getHeader, o.engine, nil, gp, ibs, noop, header, txn, usedGas, o.vmConfig, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%x] of simulated block %d: %w", i, txn.Hash(), blockNum, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	if err := ibs.CommitBlock(o.chainConfig.Rules(blockNum), o.writer); err != nil {
+		return nil, fmt.Errorf("committing simulated block %d failed: %w", blockNum, err)
+	}
+	o.blockNum = blockNum
+	return receipts, nil
+}
+
+// Close - discards simulated state. The db transaction is owned by the caller and stays open
+func (o *StateOverlay) Close() {
+	o.cache, o.reader, o.writer = nil, nil, nil
+}
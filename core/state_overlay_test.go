@@ -0,0 +1,71 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateOverlay(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	gspec.MustWrite(tx, false)
+
+	_, err := NewStateOverlay(tx, gspec.Config, ethash.NewFaker(), 1)
+	require.Error(t, err)
+
+	overlay, err := NewStateOverlay(tx, gspec.Config, ethash.NewFaker(), 0)
+	require.NoError(t, err)
+	defer overlay.Close()
+
+	signer := types.LatestSigner(gspec.Config)
+	transfer := func(nonce uint64) types.Transaction {
+		txn, err := types.SignTx(types.NewTransaction(nonce, to, uint256.NewInt(1000), params.TxGas, new(uint256.Int), nil), *signer, key)
+		require.NoError(t, err)
+		return txn
+	}
+	header := func(number int64) *types.Header {
+		return &types.Header{Number: big.NewInt(number), GasLimit: 1_000_000, Difficulty: big.NewInt(1)}
+	}
+
+	// nonce of the second block must see the first block
+	receipts, err := overlay.AdvanceBlock(header(1), types.Transactions{transfer(0), transfer(1)})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(receipts))
+	_, err = overlay.AdvanceBlock(header(2), types.Transactions{transfer(2)})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), overlay.BlockNumber())
+
+	_, err = overlay.AdvanceBlock(header(4), nil)
+	require.Error(t, err)
+	// failed block leaves no trace
+	_, err = overlay.AdvanceBlock(header(3), types.Transactions{transfer(3), transfer(5)})
+	require.Error(t, err)
+
+	acc, err := overlay.StateReader().ReadAccountData(to)
+	require.NoError(t, err)
+	require.Equal(t, uint256.NewInt(3000), &acc.Balance)
+	acc, err = overlay.StateReader().ReadAccountData(addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), acc.Nonce)
+
+	// db is untouched
+	acc, err = state.NewPlainStateReader(tx).ReadAccountData(to)
+	require.NoError(t, err)
+	require.Nil(t, acc)
+}
@@ -302,8 +302,8 @@ func (st *StateTransition) preCheck(gasBailout bool) error {
 		} else if stNonce > msgNonce {
 			return fmt.Errorf("%w: address %v, tx: %d state: %d", ErrNonceTooLow,
 				st.msg.From().Hex(), msgNonce, stNonce)
-		} else if stNonce+1 < stNonce {
-			return fmt.Errorf("%w: address %v, nonce: %d", ErrNonceMax,
+		} else if err := types.ValidateNonce(msgNonce); err != nil {
+			return fmt.Errorf("%w: address %v, nonce: %d", err,
 				st.msg.From().Hex(), stNonce)
 		}
 
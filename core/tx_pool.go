@@ -18,10 +18,13 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ledgerwatch/erigon-lib/txpool"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
 )
 
 var (
@@ -58,6 +61,27 @@ var (
 	ErrOversizedData = errors.New("oversized data")
 )
 
+// ValidateTransactionGasLimit - transaction which needs more gas than the pending block has can't be included
+// into any block, so it's not worth admitting into the pool
+func ValidateTransactionGasLimit(tx types.Transaction, pendingBlockGasLimit uint64) error {
+	if tx.GetGas() > pendingBlockGasLimit {
+		return fmt.Errorf("%w: have %d, block gas limit %d", ErrGasLimit, tx.GetGas(), pendingBlockGasLimit)
+	}
+	return nil
+}
+
+// ValidateTransactionIntrinsicGas - transaction must have enough gas to pay for itself, see IntrinsicGas
+func ValidateTransactionIntrinsicGas(tx types.Transaction, rules *params.Rules) error {
+	gas, err := IntrinsicGas(tx.GetData(), tx.GetAccessList(), tx.GetTo() == nil, rules.IsHomestead, rules.IsIstanbul)
+	if err != nil {
+		return err
+	}
+	if tx.GetGas() < gas {
+		return fmt.Errorf("%w: have %d, want %d", ErrIntrinsicGas, tx.GetGas(), gas)
+	}
+	return nil
+}
+
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
 	Disable  bool
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTransactionGas(t *testing.T) {
+	transfer := types.NewTransaction(0, common.Address{1}, uint256.NewInt(1), params.TxGas, uint256.NewInt(1), nil)
+	require.NoError(t, ValidateTransactionGasLimit(transfer, params.TxGas))
+	err := ValidateTransactionGasLimit(transfer, params.TxGas-1)
+	require.True(t, errors.Is(err, ErrGasLimit))
+	require.False(t, errors.Is(err, ErrIntrinsicGas))
+
+	rules := params.MainnetChainConfig.Rules(params.MainnetChainConfig.LondonBlock.Uint64())
+	require.NoError(t, ValidateTransactionIntrinsicGas(transfer, rules))
+
+	create := types.NewContractCreation(0, uint256.NewInt(0), params.TxGas, uint256.NewInt(1), nil)
+	err = ValidateTransactionIntrinsicGas(create, rules)
+	require.True(t, errors.Is(err, ErrIntrinsicGas))
+	require.False(t, errors.Is(err, ErrGasLimit))
+
+	withData := types.NewTransaction(0, common.Address{1}, uint256.NewInt(1), params.TxGas, uint256.NewInt(1), []byte{1})
+	require.True(t, errors.Is(ValidateTransactionIntrinsicGas(withData, rules), ErrIntrinsicGas))
+}
@@ -51,6 +51,21 @@ func (al AccessList) StorageKeys() int {
 	return sum
 }
 
+func init() {
+	RegisterTransactionType(AccessListTxType, func(s *rlp.Stream) (Transaction, error) {
+		tx := &AccessListTx{}
+		if err := tx.DecodeRLP(s); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}, func(_ Transaction, rules *params.Rules) error {
+		if !rules.IsBerlin {
+			return errors.New("eip-2930 transactions require Berlin")
+		}
+		return nil
+	})
+}
+
 // AccessListTx is the data of EIP-2930 access list transactions.
 type AccessListTx struct {
 	LegacyTx
@@ -536,8 +551,8 @@ func (tx AccessListTx) AsMessage(s Signer, _ *big.Int, rules *params.Rules) (Mes
 		checkNonce: true,
 	}
 
-	if !rules.IsBerlin {
-		return msg, errors.New("eip-2930 transactions require Berlin")
+	if err := ValidateTransactionType(&tx, rules); err != nil {
+		return msg, err
 	}
 
 	var err error
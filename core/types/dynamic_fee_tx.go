@@ -31,6 +31,21 @@ import (
 	"github.com/ledgerwatch/erigon/rlp"
 )
 
+func init() {
+	RegisterTransactionType(DynamicFeeTxType, func(s *rlp.Stream) (Transaction, error) {
+		tx := &DynamicFeeTransaction{}
+		if err := tx.DecodeRLP(s); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}, func(_ Transaction, rules *params.Rules) error {
+		if !rules.IsLondon {
+			return errors.New("eip-1559 transactions require London")
+		}
+		return nil
+	})
+}
+
 type DynamicFeeTransaction struct {
 	CommonTx
 	Tip        *uint256.Int
@@ -444,8 +459,8 @@ func (tx DynamicFeeTransaction) AsMessage(s Signer, baseFee *big.Int, rules *par
 		accessList: tx.AccessList,
 		checkNonce: true,
 	}
-	if !rules.IsLondon {
-		return msg, errors.New("eip-1559 transactions require London")
+	if err := ValidateTransactionType(&tx, rules); err != nil {
+		return msg, err
 	}
 	if baseFee != nil {
 		overflow := msg.gasPrice.SetFromBig(baseFee)
@@ -14,6 +14,21 @@ import (
 	"github.com/ledgerwatch/erigon/rlp"
 )
 
+func init() {
+	RegisterTransactionType(StarknetType, func(s *rlp.Stream) (Transaction, error) {
+		tx := &StarknetTransaction{}
+		if err := tx.DecodeRLP(s); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}, func(_ Transaction, rules *params.Rules) error {
+		if !rules.IsStarknet {
+			return errors.New("starknet tx not supported")
+		}
+		return nil
+	})
+}
+
 type StarknetTransaction struct {
 	CommonTx
 
@@ -133,8 +148,8 @@ func (tx StarknetTransaction) AsMessage(s Signer, baseFee *big.Int, rules *param
 		checkNonce: true,
 	}
 
-	if !rules.IsStarknet {
-		return msg, errors.New("starknet tx not supported")
+	if err := ValidateTransactionType(&tx, rules); err != nil {
+		return msg, err
 	}
 
 	if baseFee != nil {
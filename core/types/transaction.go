@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"sync/atomic"
 	"time"
@@ -38,6 +39,7 @@ var (
 	ErrUnexpectedProtection = errors.New("transaction type does not supported EIP-155 protected signatures")
 	ErrInvalidTxType        = errors.New("transaction type not valid in this context")
 	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
+	ErrNonceMax             = errors.New("nonce has max value")
 )
 
 // Transaction types.
@@ -116,6 +118,15 @@ func (tm TransactionMisc) From() *atomic.Value {
 	return &tm.from
 }
 
+// ValidateNonce - EIP-2681: nonce of the transaction must be less than 2^64-1, so incremented nonce of the sender
+// still fits into uint64. Returns ErrNonceMax otherwise
+func ValidateNonce(nonce uint64) error {
+	if nonce >= math.MaxUint64 {
+		return ErrNonceMax
+	}
+	return nil
+}
+
 func DecodeTransaction(s *rlp.Stream) (Transaction, error) {
 	kind, size, err := s.Kind()
 	if err != nil {
@@ -138,29 +149,14 @@ func DecodeTransaction(s *rlp.Stream) (Transaction, error) {
 	if len(b) != 1 {
 		return nil, fmt.Errorf("%w, got %d bytes", rlp.ErrWrongTxTypePrefix, len(b))
 	}
-	var tx Transaction
-	switch b[0] {
-	case AccessListTxType:
-		t := &AccessListTx{}
-		if err = t.DecodeRLP(s); err != nil {
-			return nil, err
-		}
-		tx = t
-	case DynamicFeeTxType:
-		t := &DynamicFeeTransaction{}
-		if err = t.DecodeRLP(s); err != nil {
-			return nil, err
-		}
-		tx = t
-	case StarknetType:
-		t := &StarknetTransaction{}
-		if err = t.DecodeRLP(s); err != nil {
-			return nil, err
-		}
-		tx = t
-	default:
+	h, ok := txTypes[b[0]]
+	if !ok {
 		return nil, fmt.Errorf("%w, got: %d", rlp.ErrUnknownTxTypePrefix, b[0])
 	}
+	tx, err := h.decoder(s)
+	if err != nil {
+		return nil, err
+	}
 	if kind == rlp.String {
 		if err = s.ListEnd(); err != nil {
 			return nil, err
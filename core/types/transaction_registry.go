@@ -0,0 +1,53 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// DecoderFunc decodes the payload of EIP-2718 transaction envelope, the type byte is already consumed
+type DecoderFunc func(s *rlp.Stream) (Transaction, error)
+
+// ValidatorFunc checks that the transaction is allowed by the chain rules (usually - that its fork is activated)
+type ValidatorFunc func(tx Transaction, rules *params.Rules) error
+
+type txTypeHandlers struct {
+	decoder   DecoderFunc
+	validator ValidatorFunc
+}
+
+// txTypes is populated by init functions only, so it's not protected by mutex
+var txTypes = map[byte]txTypeHandlers{}
+
+// RegisterTransactionType makes typed transaction known to DecodeTransaction and ValidateTransactionType.
+// Must be called from init function. Panics if the type is already registered or is not a valid EIP-2718 type.
+// validator can be nil if the type is valid under any rules
+func RegisterTransactionType(txType byte, decoder DecoderFunc, validator ValidatorFunc) {
+	if txType == LegacyTxType || txType > 0x7f {
+		panic(fmt.Sprintf("invalid transaction type: %d", txType))
+	}
+	if decoder == nil {
+		panic(fmt.Sprintf("nil decoder for transaction type: %d", txType))
+	}
+	if _, ok := txTypes[txType]; ok {
+		panic(fmt.Sprintf("transaction type registered twice: %d", txType))
+	}
+	txTypes[txType] = txTypeHandlers{decoder: decoder, validator: validator}
+}
+
+// ValidateTransactionType runs validator registered for the type of the transaction. Legacy transactions are always valid
+func ValidateTransactionType(tx Transaction, rules *params.Rules) error {
+	if tx.Type() == LegacyTxType {
+		return nil
+	}
+	h, ok := txTypes[tx.Type()]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrTxTypeNotSupported, tx.Type())
+	}
+	if h.validator == nil {
+		return nil
+	}
+	return h.validator(tx, rules)
+}
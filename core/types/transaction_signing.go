@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon/common"
@@ -68,6 +69,38 @@ func MakeSigner(config *params.ChainConfig, blockNumber uint64) *Signer {
 	return &signer
 }
 
+// ForkAwareSigner - same signers as MakeSigner, but made once per fork which changes them,
+// for code which needs signers of many blocks of the same chain. Safe for concurrent use
+type ForkAwareSigner struct {
+	from    []uint64 // first block of each signer, ascending, from[0] == 0
+	signers []*Signer
+}
+
+func NewForkAwareSigner(config *params.ChainConfig) *ForkAwareSigner {
+	from := []uint64{0}
+	for _, forkBlock := range []*big.Int{config.HomesteadBlock, config.SpuriousDragonBlock, config.BerlinBlock, config.LondonBlock} {
+		if forkBlock != nil && forkBlock.Sign() > 0 {
+			from = append(from, forkBlock.Uint64())
+		}
+	}
+	sort.Slice(from, func(i, j int) bool { return from[i] < from[j] })
+	s := &ForkAwareSigner{}
+	for i, blockNum := range from {
+		if i > 0 && blockNum == from[i-1] {
+			continue
+		}
+		s.from = append(s.from, blockNum)
+		s.signers = append(s.signers, MakeSigner(config, blockNum))
+	}
+	return s
+}
+
+// SignerForBlock - equal to MakeSigner(config, blockNumber). Returned signer is shared, it must not be modified
+func (s *ForkAwareSigner) SignerForBlock(blockNumber uint64) *Signer {
+	i := sort.Search(len(s.from), func(i int) bool { return s.from[i] > blockNumber })
+	return s.signers[i-1]
+}
+
 func MakeFrontierSigner() *Signer {
 	var signer Signer
 	signer.maleable = true
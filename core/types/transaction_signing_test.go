@@ -25,6 +25,7 @@ import (
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
 )
 
@@ -158,3 +159,62 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestLargeChainId(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	// chain ids above 2^64 which share lower 64 bits
+	chainId := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 64), big.NewInt(18))
+	otherChainId := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 65), big.NewInt(18))
+	signer := LatestSignerForChainID(chainId)
+	otherSigner := LatestSignerForChainID(otherChainId)
+	chainId256, _ := uint256.FromBig(chainId)
+	if signer.Equal(*otherSigner) {
+		t.Fatalf("signers for chain ids %s and %s are equal", chainId, otherChainId)
+	}
+
+	txs := []Transaction{
+		NewTransaction(0, addr, new(uint256.Int), 0, new(uint256.Int), nil),
+		&AccessListTx{LegacyTx: LegacyTx{CommonTx: CommonTx{To: &addr, Value: new(uint256.Int)}, GasPrice: new(uint256.Int)}, ChainID: chainId256},
+		NewEIP1559Transaction(*chainId256, 0, addr, new(uint256.Int), 0, new(uint256.Int), new(uint256.Int), new(uint256.Int), nil),
+	}
+	for _, tx := range txs {
+		tx, err := SignTx(tx, *signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.GetChainID().ToBig().Cmp(chainId) != 0 {
+			t.Errorf("expected chainId to be %s, got %s", chainId, tx.GetChainID())
+		}
+		from, err := tx.Sender(*signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if from != addr {
+			t.Errorf("exected from and address to be equal. Got %x want %x", from, addr)
+		}
+		// tx.Sender caches the sender, check the signature by the signer itself
+		if _, err = otherSigner.Sender(tx); err != ErrInvalidChainId {
+			t.Errorf("expected error: %v, got %v", ErrInvalidChainId, err)
+		}
+	}
+}
+
+func TestForkAwareSigner(t *testing.T) {
+	for _, config := range []*params.ChainConfig{params.MainnetChainConfig, params.GoerliChainConfig, params.TestChainConfig} {
+		signers := NewForkAwareSigner(config)
+		var blocks []uint64
+		for _, forkBlock := range []*big.Int{config.HomesteadBlock, config.SpuriousDragonBlock, config.BerlinBlock, config.LondonBlock} {
+			if forkBlock != nil && forkBlock.Sign() > 0 {
+				blocks = append(blocks, forkBlock.Uint64()-1, forkBlock.Uint64(), forkBlock.Uint64()+1)
+			}
+		}
+		blocks = append(blocks, 0, 1, 20_000_000)
+		for _, blockNum := range blocks {
+			if !signers.SignerForBlock(blockNum).Equal(*MakeSigner(config, blockNum)) {
+				t.Errorf("%s: signer mismatch at block %d", config.ChainName, blockNum)
+			}
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"reflect"
 	"testing"
@@ -32,6 +33,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/u256"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/stretchr/testify/assert"
 )
@@ -114,6 +116,47 @@ func TestDecodeEmptyTypedTx(t *testing.T) {
 	}
 }
 
+func TestTransactionTypeRegistry(t *testing.T) {
+	_, err := UnmarshalTransactionFromBinary([]byte{0x7e, 0xc0})
+	if !errors.Is(err, rlp.ErrUnknownTxTypePrefix) {
+		t.Fatal("wrong error:", err)
+	}
+
+	berlin := &params.Rules{IsBerlin: true}
+	assert.NoError(t, ValidateTransactionType(emptyTx, &params.Rules{}))
+	assert.NoError(t, ValidateTransactionType(emptyEip2718Tx, berlin))
+	assert.Error(t, ValidateTransactionType(emptyEip2718Tx, &params.Rules{}))
+	assert.Error(t, ValidateTransactionType(dynFeeTx, berlin))
+	assert.NoError(t, ValidateTransactionType(dynFeeTx, &params.Rules{IsBerlin: true, IsLondon: true}))
+
+	assert.Panics(t, func() { RegisterTransactionType(DynamicFeeTxType, txTypes[DynamicFeeTxType].decoder, nil) })
+	assert.Panics(t, func() { RegisterTransactionType(LegacyTxType, txTypes[DynamicFeeTxType].decoder, nil) })
+}
+
+func TestRegisterTransactionTypeBoundary(t *testing.T) {
+	// EIP-2718 types are in [0, 0x7f], 0x7f is the last valid one
+	decoder := func(s *rlp.Stream) (Transaction, error) {
+		if _, err := s.List(); err != nil {
+			return nil, err
+		}
+		return &AccessListTx{}, s.ListEnd()
+	}
+	assert.NotPanics(t, func() { RegisterTransactionType(0x7f, decoder, nil) })
+	defer delete(txTypes, 0x7f)
+	assert.Panics(t, func() { RegisterTransactionType(0x80, decoder, nil) })
+	assert.Panics(t, func() { RegisterTransactionType(0xff, decoder, nil) })
+
+	_, err := UnmarshalTransactionFromBinary([]byte{0x7f, 0xc0})
+	assert.NoError(t, err)
+}
+
+func TestValidateNonce(t *testing.T) {
+	tx := NewTransaction(math.MaxUint64, testAddr, uint256.NewInt(0), 21000, uint256.NewInt(1), nil)
+	assert.ErrorIs(t, ValidateNonce(tx.GetNonce()), ErrNonceMax)
+	assert.NoError(t, ValidateNonce(math.MaxUint64-1))
+	assert.NoError(t, ValidateNonce(0))
+}
+
 func TestTransactionSigHash(t *testing.T) {
 	if emptyTx.SigningHash(nil) != common.HexToHash("c775b99e7ad12f50d819fcd602390467e28141316969f4b57f0626f74fe3b386") {
 		t.Errorf("empty transaction hash mismatch, got %x", emptyTx.SigningHash(nil))
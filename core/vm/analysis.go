@@ -16,6 +16,34 @@
 
 package vm
 
+import (
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// JumpDestCache - results of JUMPDEST analysis (codeBitmap) by code hash, shared between EVMs, see Config.JumpDestCache.
+// Analysis depends only on the code, so the cache stays valid across blocks. Thread-safe
+type JumpDestCache struct {
+	cache *lru.Cache
+}
+
+func NewJumpDestCache(size int) *JumpDestCache {
+	cache, err := lru.New(size)
+	if err != nil {
+		panic(err)
+	}
+	return &JumpDestCache{cache: cache}
+}
+
+func (c *JumpDestCache) get(codeHash common.Hash, code []byte) []uint64 {
+	if analysis, ok := c.cache.Get(codeHash); ok {
+		return analysis.([]uint64)
+	}
+	analysis := codeBitmap(code)
+	c.cache.Add(codeHash, analysis)
+	return analysis
+}
+
 // codeBitmap collects data locations in code.
 func codeBitmap(code []byte) []uint64 {
 	// The bitmap is 4 bytes longer than necessary, in case the code
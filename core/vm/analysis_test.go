@@ -51,6 +51,33 @@ func TestJumpDestAnalysis(t *testing.T) {
 	}
 }
 
+func TestJumpDestCache(t *testing.T) {
+	code := []byte{byte(PUSH1), byte(JUMPDEST), byte(JUMPDEST)}
+	codeHash := crypto.Keccak256Hash(code)
+	cache := NewJumpDestCache(1)
+	c := NewContract(AccountRef(common.Address{}), AccountRef(common.Address{}), nil, 0, false, false)
+	c.SetCallCode(&common.Address{}, codeHash, code)
+	c.jumpDestCache = cache
+	if c.isCode(1) || !c.isCode(2) {
+		t.Fatal("wrong analysis")
+	}
+	analysis, ok := cache.cache.Get(codeHash)
+	if !ok {
+		t.Fatal("analysis is not cached")
+	}
+
+	// another top-level contract takes the analysis from the cache
+	c2 := NewContract(AccountRef(common.Address{}), AccountRef(common.Address{}), nil, 0, false, false)
+	c2.SetCallCode(&common.Address{}, codeHash, code)
+	c2.jumpDestCache = cache
+	if c2.isCode(1) || !c2.isCode(2) {
+		t.Fatal("wrong analysis")
+	}
+	if &c2.analysis[0] != &analysis.([]uint64)[0] {
+		t.Fatal("analysis is not reused")
+	}
+}
+
 func BenchmarkJumpdestAnalysisEmpty_1200k(bench *testing.B) {
 	// 1.4 ms
 	code := make([]byte, 1200000)
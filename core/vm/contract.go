@@ -49,6 +49,7 @@ type Contract struct {
 	caller        ContractRef
 	self          ContractRef
 	jumpdests     map[common.Hash][]uint64 // Aggregated result of JUMPDEST analysis.
+	jumpDestCache *JumpDestCache           // Analysis shared between EVMs, optional
 	analysis      []uint64                 // Locally cached result of JUMPDEST analysis
 	skipAnalysis  bool
 	vmType        VmType
@@ -122,9 +123,13 @@ func (c *Contract) isCode(udest uint64) bool {
 		// Does parent context have the analysis?
 		analysis, exist := c.jumpdests[c.CodeHash]
 		if !exist {
-			// Do the analysis and save in parent context
+			// Do the analysis (or take it from the shared cache) and save in parent context
 			// We do not need to store it in c.analysis
-			analysis = codeBitmap(c.Code)
+			if c.jumpDestCache != nil {
+				analysis = c.jumpDestCache.get(c.CodeHash, c.Code)
+			} else {
+				analysis = codeBitmap(c.Code)
+			}
 			c.jumpdests[c.CodeHash] = analysis
 		}
 		// Also stash it in current contract for faster access
@@ -65,6 +65,7 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, error) {
+	contract.jumpDestCache = evm.config.JumpDestCache
 	callback, err := selectInterpreter(evm, contract)
 	if err != nil {
 		return nil, err
@@ -38,6 +38,8 @@ type Config struct {
 	ReadOnly      bool   // Do no perform any block finalisation
 	EnableTEMV    bool   // true if execution with TEVM enable flag
 
+	JumpDestCache *JumpDestCache // Optional, reuses JUMPDEST analysis between EVMs
+
 	ExtraEips []int // Additional EIPS that are to be enabled
 }
 
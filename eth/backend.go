@@ -71,6 +71,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/freezer"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/ethstats"
@@ -139,6 +140,8 @@ type Ethereum struct {
 	notifyMiningAboutNewTxs chan struct{}
 
 	downloader *downloader.Downloader
+
+	freezer *freezer.Freezer // bodies and receipts of old blocks (--datadir.ancient), nil if not used
 }
 
 // New creates a new Ethereum object (including the
@@ -211,6 +214,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			Events:               privateapi.NewEvents(),
 			Accumulator:          shards.NewAccumulator(chainConfig),
 			StateChangesConsumer: kvRPC,
+			ChainHeadFeed:        &core.ChainHeadFeed{},
 		},
 	}
 	backend.gasPrice, _ = uint256.FromBig(config.Miner.GasPrice)
@@ -311,6 +315,9 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		return nil, err
 	}
 
+	if backend.freezer, err = setUpFreezer(chainKv, config); err != nil {
+		return nil, err
+	}
 	blockReader, allSnapshots, err := backend.setUpBlockReader(ctx, config.Snapshot.Enabled, config, stack)
 	if err != nil {
 		return nil, err
@@ -367,7 +374,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			stagedsync.StageMiningCreateBlockCfg(backend.chainDB, miner, *backend.chainConfig, backend.engine, backend.txPool2, backend.txPool2DB, nil, tmpdir),
 			stagedsync.StageMiningExecCfg(backend.chainDB, miner, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, nil),
 			stagedsync.StageHashStateCfg(backend.chainDB, tmpdir),
-			stagedsync.StageTrieCfg(backend.chainDB, false, true, tmpdir, blockReader),
+			stagedsync.StageTrieCfg(backend.chainDB, false, true, tmpdir, blockReader, nil),
 			stagedsync.StageMiningFinishCfg(backend.chainDB, *backend.chainConfig, backend.engine, miner, backend.miningSealingQuit),
 		), stagedsync.MiningUnwindOrder, stagedsync.MiningPruneOrder)
 
@@ -385,7 +392,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 				stagedsync.StageMiningCreateBlockCfg(backend.chainDB, miningStatePos, *backend.chainConfig, backend.engine, backend.txPool2, backend.txPool2DB, param, tmpdir),
 				stagedsync.StageMiningExecCfg(backend.chainDB, miningStatePos, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, interrupt),
 				stagedsync.StageHashStateCfg(backend.chainDB, tmpdir),
-				stagedsync.StageTrieCfg(backend.chainDB, false, true, tmpdir, blockReader),
+				stagedsync.StageTrieCfg(backend.chainDB, false, true, tmpdir, blockReader, nil),
 				stagedsync.StageMiningFinishCfg(backend.chainDB, *backend.chainConfig, backend.engine, miningStatePos, backend.miningSealingQuit),
 			), stagedsync.MiningUnwindOrder, stagedsync.MiningPruneOrder)
 		// We start the mining step
@@ -478,7 +485,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		headCh = make(chan *types.Block, 1)
 	}
 
-	backend.stagedSync, err = stages2.NewStagedSync(backend.sentryCtx, backend.log, backend.chainDB, stack.Config().P2P, *config, backend.sentriesClient, tmpdir, backend.notifications, backend.downloaderClient, allSnapshots, headCh)
+	backend.stagedSync, err = stages2.NewStagedSync(backend.sentryCtx, backend.log, backend.chainDB, stack.Config().P2P, *config, backend.sentriesClient, tmpdir, backend.notifications, backend.downloaderClient, allSnapshots, backend.freezer, headCh)
 	if err != nil {
 		return nil, err
 	}
@@ -516,7 +523,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	// start HTTP API
 	httpRpcCfg := stack.Config().Http
 	if httpRpcCfg.Enabled {
-		ethRpcClient, txPoolRpcClient, miningRpcClient, starkNetRpcClient, stateCache, ff, err := cli.EmbeddedServices(
+		ethRpcClient, txPoolRpcClient, miningRpcClient, starkNetRpcClient, stateCache, historyCache, ff, err := cli.EmbeddedServices(
 			ctx, chainKv, httpRpcCfg.StateCache, blockReader,
 			ethBackendRPC,
 			backend.txPool2GrpcServer,
@@ -530,7 +537,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		if casted, ok := backend.engine.(*bor.Bor); ok {
 			borDb = casted.DB
 		}
-		apiList := commands.APIList(chainKv, borDb, ethRpcClient, txPoolRpcClient, miningRpcClient, starkNetRpcClient, ff, stateCache, blockReader, httpRpcCfg)
+		apiList := commands.APIList(chainKv, borDb, ethRpcClient, txPoolRpcClient, miningRpcClient, starkNetRpcClient, ff, stateCache, historyCache, blockReader, httpRpcCfg)
 		go func() {
 			if err := cli.StartRpcServer(ctx, httpRpcCfg, apiList); err != nil {
 				log.Error(err.Error())
@@ -806,6 +813,9 @@ func (s *Ethereum) setUpBlockReader(ctx context.Context, isSnapshotEnabled bool,
 			return nil, nil, err
 		}
 		return blockReader, allSnapshots, nil
+	} else if s.freezer != nil {
+		blockReader := snapshotsync.NewBlockReaderWithFreezer(s.freezer)
+		return blockReader, nil, nil
 	} else {
 		blockReader := snapshotsync.NewBlockReader()
 		return blockReader, nil, nil
@@ -813,6 +823,49 @@ func (s *Ethereum) setUpBlockReader(ctx context.Context, isSnapshotEnabled bool,
 
 }
 
+// setUpFreezer - opens the freezer and records its directory in the db, so rpcdaemon and integration can find it.
+// Blocks, moved to the freezer, are not in the db anymore: node can't be started without it (or with another one)
+func setUpFreezer(db kv.RwDB, cfg *ethconfig.Config) (*freezer.Freezer, error) {
+	var recorded string
+	if err := db.View(context.Background(), func(tx kv.Tx) (err error) {
+		recorded, err = rawdb.ReadAncientDir(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if cfg.AncientDir == "" {
+		if recorded != "" {
+			return nil, fmt.Errorf("old blocks of the db were moved to %s, start with --datadir.ancient=%s", recorded, recorded)
+		}
+		return nil, nil
+	}
+	if cfg.Snapshot.Enabled {
+		return nil, fmt.Errorf("--datadir.ancient requires --snapshots=false")
+	}
+	dir, err := filepath.Abs(cfg.AncientDir)
+	if err != nil {
+		return nil, err
+	}
+	if recorded != "" && recorded != dir {
+		return nil, fmt.Errorf("old blocks of the db were moved to %s, but --datadir.ancient=%s", recorded, dir)
+	}
+	f, err := rawdb.OpenFreezer(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Update(context.Background(), func(tx kv.RwTx) error {
+		if err := rawdb.TruncateFreezer(tx, f); err != nil {
+			return err
+		}
+		return rawdb.WriteAncientDir(tx, dir)
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	log.Info("Freezer", "dir", dir, "keep_recent", cfg.AncientKeepRecent)
+	return f, nil
+}
+
 func (s *Ethereum) Peers(ctx context.Context) (*remote.PeersReply, error) {
 	var reply remote.PeersReply
 	for _, sentryClient := range s.sentriesClient.Sentries() {
@@ -880,6 +933,9 @@ func (s *Ethereum) Stop() error {
 		sentryServer.Close()
 	}
 	s.chainDB.Close()
+	if s.freezer != nil {
+		s.freezer.Close()
+	}
 	if s.txPool2DB != nil {
 		s.txPool2DB.Close()
 	}
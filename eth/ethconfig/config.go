@@ -67,6 +67,7 @@ var Defaults = Config{
 		BlockDownloaderWindow:      32768,
 		BodyDownloadTimeoutSeconds: 30,
 	},
+	AncientKeepRecent: params.FullImmutabilityThreshold,
 	Ethash: ethash.Config{
 		CachesInMem:      2,
 		CachesLockMmap:   false,
@@ -178,6 +179,11 @@ type Config struct {
 
 	Dirs datadir.Dirs
 
+	// Directory of the freezer, where bodies and receipts of old blocks are moved from the db. Empty - disabled
+	AncientDir string
+	// Amount of recent blocks, which stay in the db
+	AncientKeepRecent uint64
+
 	// Address to connect to external snapshot downloader
 	// empty if you want to use internal bittorrent snapshot downloader
 	ExternalSnapshotDownloaderAddr string
@@ -215,6 +221,9 @@ type Config struct {
 	// Enable WatchTheBurn stage
 	EnabledIssuance bool
 
+	// Execution stage records block of the last modification of every account, see rawdb.AccountVersion
+	TrackAccountVersions bool
+
 	// URL to connect to Heimdall node
 	HeimdallURL string
 
@@ -236,6 +245,9 @@ type Sync struct {
 
 	BlockDownloaderWindow      int
 	BodyDownloadTimeoutSeconds int // TODO: change to duration
+
+	// TrustedRootValidationURL - JSON-RPC url of a node which state roots are compared with computed ones, debug only
+	TrustedRootValidationURL string
 }
 
 // Chains where snapshots are enabled by default
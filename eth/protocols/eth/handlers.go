@@ -131,7 +131,7 @@ func AnswerGetBlockHeadersQuery(db kv.Tx, query *GetBlockHeadersPacket, blockRea
 	return headers, nil
 }
 
-func AnswerGetBlockBodiesQuery(db kv.Tx, query GetBlockBodiesPacket) []rlp.RawValue { //nolint:unparam
+func AnswerGetBlockBodiesQuery(db kv.Tx, query GetBlockBodiesPacket, blockReader services.BodyReader) []rlp.RawValue { //nolint:unparam
 	// Gather blocks until the fetch or network limits is reached
 	var (
 		bytes  int
@@ -152,7 +152,17 @@ func AnswerGetBlockBodiesQuery(db kv.Tx, query GetBlockBodiesPacket) []rlp.RawVa
 		}
 		var bodyRlP []byte
 		if canonicalHash == hash {
-			bodyRlP = rawdb.ReadBodyRLP(db, hash, *number)
+			// canonical bodies may already be moved to the freezer or snapshots
+			body, err := blockReader.BodyWithTransactions(context.Background(), db, hash, *number)
+			if err != nil {
+				break
+			}
+			if body == nil {
+				continue
+			}
+			if bodyRlP, err = rlp.EncodeToBytes(body); err != nil {
+				break
+			}
 		} else {
 			bodyRlP = rawdb.NonCanonicalBodyRLP(db, hash, *number)
 		}
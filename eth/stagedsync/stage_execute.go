@@ -29,6 +29,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/calltracer"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
 	"github.com/ledgerwatch/erigon/ethdb/olddb"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
@@ -58,6 +59,7 @@ type ExecuteBlockCfg struct {
 	vmConfig      *vm.Config
 	tmpdir        string
 	stateStream   bool
+	trackVersions bool // record block of the last modification of every account, see rawdb.AccountVersion
 	accumulator   *shards.Accumulator
 	blockReader   services.FullBlockReader
 }
@@ -72,6 +74,7 @@ func StageExecuteBlocksCfg(
 	vmConfig *vm.Config,
 	accumulator *shards.Accumulator,
 	stateStream bool,
+	trackVersions bool,
 	tmpdir string,
 	blockReader services.FullBlockReader,
 ) ExecuteBlockCfg {
@@ -86,6 +89,7 @@ func StageExecuteBlocksCfg(
 		tmpdir:        tmpdir,
 		accumulator:   accumulator,
 		stateStream:   stateStream,
+		trackVersions: trackVersions,
 		blockReader:   blockReader,
 	}
 }
@@ -103,7 +107,7 @@ func executeBlock(
 	initialCycle bool,
 ) error {
 	blockNum := block.NumberU64()
-	stateReader, stateWriter, err := newStateReaderWriter(batch, tx, block, writeChangesets, cfg.accumulator, initialCycle, cfg.stateStream)
+	stateReader, stateWriter, err := newStateReaderWriter(batch, tx, block, writeChangesets, cfg.accumulator, initialCycle, cfg.stateStream, cfg.trackVersions)
 	if err != nil {
 		return err
 	}
@@ -131,7 +135,12 @@ func executeBlock(
 	}
 
 	if writeReceipts {
-		if err = rawdb.AppendReceipts(tx, blockNum, receipts); err != nil {
+		if cfg.prune.ReceiptStorage.WriteLogs() {
+			err = rawdb.AppendReceipts(tx, blockNum, receipts)
+		} else {
+			err = rawdb.AppendReceiptsWithoutLogs(tx, blockNum, receipts)
+		}
+		if err != nil {
 			return err
 		}
 
@@ -162,6 +171,7 @@ func newStateReaderWriter(
 	accumulator *shards.Accumulator,
 	initialCycle bool,
 	stateStream bool,
+	trackVersions bool,
 ) (state.StateReader, state.WriterWithChangeSets, error) {
 
 	var stateReader state.StateReader
@@ -178,11 +188,16 @@ func newStateReaderWriter(
 	} else {
 		accumulator = nil
 	}
+	var plainStateWriter *state.PlainStateWriter
 	if writeChangesets {
-		stateWriter = state.NewPlainStateWriter(batch, tx, block.NumberU64()).SetAccumulator(accumulator)
+		plainStateWriter = state.NewPlainStateWriter(batch, tx, block.NumberU64()).SetAccumulator(accumulator)
 	} else {
-		stateWriter = state.NewPlainStateWriterNoHistory(batch).SetAccumulator(accumulator)
+		plainStateWriter = state.NewPlainStateWriterNoHistory(batch).SetAccumulator(accumulator)
 	}
+	if trackVersions {
+		plainStateWriter.SetVersionTracking(block.NumberU64())
+	}
+	stateWriter = plainStateWriter
 
 	return stateReader, stateWriter, nil
 }
@@ -198,6 +213,12 @@ func SpawnExecuteBlocksStage(s *StageState, u Unwinder, tx kv.RwTx, toBlock uint
 		defer tx.Rollback()
 	}
 
+	if cfg.trackVersions {
+		if err = checkAccountVersions(tx); err != nil {
+			return err
+		}
+	}
+
 	prevStageProgress, errStart := stages.GetStageProgress(tx, stages.Senders)
 	if errStart != nil {
 		return errStart
@@ -283,8 +304,8 @@ Loop:
 		}
 
 		// Incremental move of next stages depend on fully written ChangeSets, Receipts, CallTraceSet
-		writeChangeSets := nextStagesExpectData || blockNum > cfg.prune.History.PruneTo(to)
-		writeReceipts := nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to)
+		writeChangeSets := nextStagesExpectData || blockNum > cfg.prune.AccountHistoryAmount().PruneTo(to) || blockNum > cfg.prune.StorageHistoryAmount().PruneTo(to)
+		writeReceipts := cfg.prune.ReceiptStorage.WriteReceipts() && (nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to))
 		writeCallTraces := nextStagesExpectData || blockNum > cfg.prune.CallTraces.PruneTo(to)
 		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, contractHasTEVM, initialCycle); err != nil {
 			log.Warn(fmt.Sprintf("[%s] Execution failed", logPrefix), "block", blockNum, "hash", block.Hash().String(), "err", err)
@@ -417,6 +438,60 @@ func UnwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 	return nil
 }
 
+// checkAccountVersions - --state.track-versions needs AccountVersion, it's open only with rawdb.WithChaindataTables
+func checkAccountVersions(tx kv.RwTx) error {
+	has, err := rawdb.HasAccountVersions(tx)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return fmt.Errorf("--state.track-versions: table %s is not open in the db", rawdb.AccountVersion)
+	}
+	return nil
+}
+
+// unindexedAccountVersions - block of the last change up to `unwindPoint` of every account changed in blocks which
+// AccountHistoryIndex didn't index yet
+func unindexedAccountVersions(tx kv.Tx, unwindPoint uint64) (map[commonold.Address]uint64, error) {
+	indexed, err := stages.GetStageProgress(tx, stages.AccountHistoryIndex)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[commonold.Address]uint64{}
+	if indexed >= unwindPoint {
+		return versions, nil
+	}
+	if err = changeset.ForRange(tx, kv.AccountChangeSet, indexed+1, unwindPoint+1, func(blockN uint64, k, _ []byte) error {
+		versions[commonold.BytesToAddress(k)] = blockN // ascending, the last one wins
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// restoreAccountVersion - the account gets back the version it had at `unwindPoint`: block of its last change up to
+// `unwindPoint` in AccountChangeSet. Not indexed changes come from `unindexed`, the rest - from AccountsHistory.
+// The record is deleted if there is no such change (or it is pruned)
+func restoreAccountVersion(tx kv.RwTx, address commonold.Address, unwindPoint uint64, unindexed map[commonold.Address]uint64) error {
+	if version, ok := unindexed[address]; ok {
+		return rawdb.WriteAccountVersion(tx, address, version)
+	}
+	changes, err := bitmapdb.Get64(tx, kv.AccountsHistory, address[:], 0, unwindPoint)
+	if err != nil {
+		return err
+	}
+	n := changes.Rank(unwindPoint) // amount of changes <= unwindPoint
+	if n == 0 {
+		return rawdb.DeleteAccountVersion(tx, address)
+	}
+	version, err := changes.Select(n - 1)
+	if err != nil {
+		return err
+	}
+	return rawdb.WriteAccountVersion(tx, address, version)
+}
+
 func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan struct{}, cfg ExecuteBlockCfg, initialCycle bool) error {
 	logPrefix := s.LogPrefix()
 	stateBucket := kv.PlainState
@@ -437,6 +512,17 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan
 		accumulator.StartChange(u.UnwindPoint, hash, txs, true)
 	}
 
+	var unindexedVersions map[commonold.Address]uint64
+	if cfg.trackVersions {
+		if err := checkAccountVersions(tx); err != nil {
+			return err
+		}
+		var err error
+		if unindexedVersions, err = unindexedAccountVersions(tx, u.UnwindPoint); err != nil {
+			return err
+		}
+	}
+
 	changes := etl.NewCollector(logPrefix, cfg.tmpdir, etl.NewOldestEntryBuffer(etl.BufferOptimalSize))
 	defer changes.Close()
 	errRewind := changeset.RewindData(tx, s.BlockNumber, u.UnwindPoint, changes, quit)
@@ -472,6 +558,12 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan
 					}
 				}
 
+				if cfg.trackVersions {
+					if err := restoreAccountVersion(tx, address, u.UnwindPoint, unindexedVersions); err != nil {
+						return err
+					}
+				}
+
 				newV := make([]byte, acc.EncodingLengthForStorage())
 				acc.EncodeForStorage(newV)
 				if accumulator != nil {
@@ -481,9 +573,14 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, quit <-chan
 					return err
 				}
 			} else {
+				var address commonold.Address
+				copy(address[:], k)
+				if cfg.trackVersions {
+					if err := rawdb.DeleteAccountVersion(tx, address); err != nil {
+						return err
+					}
+				}
 				if accumulator != nil {
-					var address commonold.Address
-					copy(address[:], k)
 					accumulator.DeleteAccount(address)
 				}
 				if err := next(k, k, nil); err != nil {
@@ -574,11 +671,13 @@ func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx con
 	logEvery := time.NewTicker(logInterval)
 	defer logEvery.Stop()
 
-	if cfg.prune.History.Enabled() {
-		if err = PruneTableDupSort(tx, kv.AccountChangeSet, logPrefix, cfg.prune.History.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
+	if accHistory := cfg.prune.AccountHistoryAmount(); accHistory.Enabled() {
+		if err = PruneTableDupSort(tx, kv.AccountChangeSet, logPrefix, accHistory.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
 			return err
 		}
-		if err = PruneTableDupSort(tx, kv.StorageChangeSet, logPrefix, cfg.prune.History.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
+	}
+	if storageHistory := cfg.prune.StorageHistoryAmount(); storageHistory.Enabled() {
+		if err = PruneTableDupSort(tx, kv.StorageChangeSet, logPrefix, storageHistory.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
 			return err
 		}
 	}
@@ -5,10 +5,16 @@ import (
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,6 +79,51 @@ func TestUnwindExecutionStagePlainWithCodeChanges(t *testing.T) {
 	compareCurrentState(t, tx1, tx2, kv.PlainState, kv.PlainContractCode)
 }
 
+func TestUnwindExecutionStageAccountVersions(t *testing.T) {
+	ctx, assert := context.Background(), assert.New(t)
+	db := mdbx.NewMDBX(log.New()).InMem().WithTablessCfg(rawdb.WithChaindataTables).MustOpen()
+	t.Cleanup(db.Close)
+	tx, err := db.BeginRw(ctx)
+	assert.NoError(err)
+	t.Cleanup(tx.Rollback)
+
+	addr1 := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	addr2 := common.HexToAddress("0x1000000000000000000000000000000000000002")
+	addr3 := common.HexToAddress("0x1000000000000000000000000000000000000003")
+	changes := map[uint64][]common.Address{2: {addr3}, 3: {addr1}, 5: {addr3}, 8: {addr1, addr3}, 9: {addr2}}
+	latest := map[common.Address]*accounts.Account{addr1: {}, addr2: {}, addr3: {}}
+	for blockNum := uint64(1); blockNum <= 9; blockNum++ {
+		w := state.NewPlainStateWriter(tx, tx, blockNum).SetVersionTracking(blockNum)
+		for _, addr := range changes[blockNum] {
+			acc := accounts.NewAccount()
+			acc.Initialised = true
+			acc.Balance.SetUint64(blockNum)
+			assert.NoError(w.UpdateAccountData(addr, latest[addr], &acc))
+			latest[addr] = &acc
+		}
+		assert.NoError(w.WriteChangeSets())
+	}
+
+	// changes of blocks 1-4 are indexed, 5-9 are not
+	assert.NoError(stages.SaveStageProgress(tx, stages.Execution, 4))
+	assert.NoError(SpawnAccountHistoryIndex(&StageState{ID: stages.AccountHistoryIndex}, tx, StageHistoryCfg(db, prune.DefaultMode, t.TempDir()), ctx))
+	assert.NoError(stages.SaveStageProgress(tx, stages.Execution, 9))
+
+	u := &UnwindState{ID: stages.Execution, UnwindPoint: 6}
+	s := &StageState{ID: stages.Execution, BlockNumber: 9}
+	assert.NoError(UnwindExecutionStage(u, s, tx, ctx, ExecuteBlockCfg{trackVersions: true, tmpdir: t.TempDir()}, false))
+
+	for addr, expected := range map[common.Address]uint64{
+		addr1: 3, // from the index
+		addr2: 0, // didn't exist at block 6
+		addr3: 5, // from the changeset which is not indexed
+	} {
+		version, err := rawdb.ReadAccountVersion(tx, addr)
+		assert.NoError(err)
+		assert.Equal(expected, version, addr.Hex())
+	}
+}
+
 func TestPruneExecution(t *testing.T) {
 	ctx, assert := context.Background(), assert.New(t)
 	_, tx := memdb.NewTestTx(t)
@@ -69,7 +69,7 @@ func SpawnAccountHistoryIndex(s *StageState, tx kv.RwTx, cfg HistoryCfg, ctx con
 	}
 	stopChangeSetsLookupAt := endBlock + 1
 
-	pruneTo := cfg.prune.History.PruneTo(endBlock)
+	pruneTo := cfg.prune.AccountHistoryAmount().PruneTo(endBlock)
 	if startBlock < pruneTo {
 		startBlock = pruneTo
 	}
@@ -352,7 +352,7 @@ func truncateBitmaps64(tx kv.RwTx, bucket string, inMem map[string]struct{}, to
 }
 
 func PruneAccountHistoryIndex(s *PruneState, tx kv.RwTx, cfg HistoryCfg, ctx context.Context) (err error) {
-	if !cfg.prune.History.Enabled() {
+	if !cfg.prune.AccountHistoryAmount().Enabled() {
 		return nil
 	}
 	logPrefix := s.LogPrefix()
@@ -366,7 +366,7 @@ func PruneAccountHistoryIndex(s *PruneState, tx kv.RwTx, cfg HistoryCfg, ctx con
 		defer tx.Rollback()
 	}
 
-	pruneTo := cfg.prune.History.PruneTo(s.ForwardProgress)
+	pruneTo := cfg.prune.AccountHistoryAmount().PruneTo(s.ForwardProgress)
 	if err = pruneHistoryIndex(tx, kv.AccountChangeSet, logPrefix, cfg.tmpdir, pruneTo, ctx); err != nil {
 		return err
 	}
@@ -383,7 +383,7 @@ func PruneAccountHistoryIndex(s *PruneState, tx kv.RwTx, cfg HistoryCfg, ctx con
 }
 
 func PruneStorageHistoryIndex(s *PruneState, tx kv.RwTx, cfg HistoryCfg, ctx context.Context) (err error) {
-	if !cfg.prune.History.Enabled() {
+	if !cfg.prune.StorageHistoryAmount().Enabled() {
 		return nil
 	}
 	logPrefix := s.LogPrefix()
@@ -396,7 +396,7 @@ func PruneStorageHistoryIndex(s *PruneState, tx kv.RwTx, cfg HistoryCfg, ctx con
 		}
 		defer tx.Rollback()
 	}
-	pruneTo := cfg.prune.History.PruneTo(s.ForwardProgress)
+	pruneTo := cfg.prune.StorageHistoryAmount().PruneTo(s.ForwardProgress)
 	if err = pruneHistoryIndex(tx, kv.StorageChangeSet, logPrefix, cfg.tmpdir, pruneTo, ctx); err != nil {
 		return err
 	}
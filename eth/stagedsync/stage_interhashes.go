@@ -3,11 +3,11 @@ package stagedsync
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"math/bits"
 	"os"
 
-	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/VictoriaMetrics/metrics"
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
@@ -15,27 +15,52 @@ import (
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/rlphacks"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/trie"
 	"github.com/ledgerwatch/log/v3"
 	"golang.org/x/exp/slices"
 )
 
+var (
+	trieAccountsScanned   = metrics.GetOrCreateCounter("trie_accounts_scanned")
+	trieAccountHashesUsed = metrics.GetOrCreateCounter("trie_account_hashes_used")
+	trieStorageScanned    = metrics.GetOrCreateCounter("trie_storage_scanned")
+	trieStorageHashesUsed = metrics.GetOrCreateCounter("trie_storage_hashes_used")
+	trieIHSkipped         = metrics.GetOrCreateCounter("trie_ih_skipped")
+	trieScanTimer         = metrics.GetOrCreateSummary("trie_scan_seconds")
+	trieFinalizeTimer     = metrics.GetOrCreateSummary("trie_finalize_seconds")
+)
+
+// updateTrieMetrics - reports work done by the last CalcTrieRoot of `loader`, see trie.IterationStats
+func updateTrieMetrics(loader *trie.FlatDBTrieLoader) {
+	stats := loader.DumpStats()
+	trieAccountsScanned.Add(int(stats.AccountsScanned))
+	trieAccountHashesUsed.Add(int(stats.AccountHashesUsed))
+	trieStorageScanned.Add(int(stats.StorageItemsScanned))
+	trieStorageHashesUsed.Add(int(stats.StorageHashesUsed))
+	trieIHSkipped.Add(int(stats.IHSkippedCount))
+	trieScanTimer.Update(stats.ScanDuration.Seconds())
+	trieFinalizeTimer.Update(stats.FinalizeDuration.Seconds())
+}
+
 type TrieCfg struct {
 	db                kv.RwDB
 	checkRoot         bool
 	tmpDir            string
 	saveNewHashesToDB bool // no reason to save changes when calculating root for mining
 	blockReader       services.FullBlockReader
+	trustedRoots      *TrustedRootValidator // optional, checks only the last block of each stage run
 }
 
-func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB bool, tmpDir string, blockReader services.FullBlockReader) TrieCfg {
+func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB bool, tmpDir string, blockReader services.FullBlockReader, trustedRoots *TrustedRootValidator) TrieCfg {
 	return TrieCfg{
 		db:                db,
 		checkRoot:         checkRoot,
 		tmpDir:            tmpDir,
 		saveNewHashesToDB: saveNewHashesToDB,
 		blockReader:       blockReader,
+		trustedRoots:      trustedRoots,
 	}
 }
 
@@ -89,6 +114,13 @@ func SpawnIntermediateHashesStage(s *StageState, u Unwinder, tx kv.RwTx, cfg Tri
 	}
 
 	if err == nil {
+		// before the header comparison: a wrong header root must not hide the disagreement with the trusted node
+		if cfg.trustedRoots != nil {
+			if err = cfg.trustedRoots.Validate(ctx, to, root, expectedRootHash); err != nil {
+				log.Error(fmt.Sprintf("[%s] Trie root of block %d doesn't match trusted node", logPrefix, to), "err", err)
+				return trie.EmptyRoot, err
+			}
+		}
 		if cfg.checkRoot && root != expectedRootHash {
 			log.Error(fmt.Sprintf("[%s] Wrong trie root of block %d: %x, expected (from header): %x. Block hash: %x", logPrefix, to, root, expectedRootHash, headerHash))
 			if to > s.BlockNumber {
@@ -96,8 +128,10 @@ func SpawnIntermediateHashesStage(s *StageState, u Unwinder, tx kv.RwTx, cfg Tri
 				log.Warn("Unwinding due to incorrect root hash", "to", unwindTo)
 				u.UnwindTo(unwindTo, headerHash)
 			}
-		} else if err = s.Update(tx, to); err != nil {
-			return trie.EmptyRoot, err
+		} else {
+			if err = s.Update(tx, to); err != nil {
+				return trie.EmptyRoot, err
+			}
 		}
 	} else {
 		return trie.EmptyRoot, err
@@ -112,6 +146,16 @@ func SpawnIntermediateHashesStage(s *StageState, u Unwinder, tx kv.RwTx, cfg Tri
 	return root, err
 }
 
+// calcTrieRoot - loader.CalcTrieRoot of the whole state. Invalid storage values stop it: they are not written by
+// execution, so HashedStorage is corrupted and has to be rebuilt, the error says how
+func calcTrieRoot(logPrefix string, loader *trie.FlatDBTrieLoader, db kv.Tx, quit <-chan struct{}) (common.Hash, error) {
+	hash, err := loader.CalcTrieRoot(db, []byte{}, quit)
+	if errors.Is(err, rlphacks.ErrStorageValueNotTrimmed) || errors.Is(err, rlphacks.ErrStorageValueTooLarge) {
+		return trie.EmptyRoot, fmt.Errorf("[%s] HashedStorage is corrupted, rebuild it with `integration stage_hash_state --reset` and `integration stage_trie --reset`: %w", logPrefix, err)
+	}
+	return hash, err
+}
+
 func RegenerateIntermediateHashes(logPrefix string, db kv.RwTx, cfg TrieCfg, expectedRootHash common.Hash, quit <-chan struct{}) (common.Hash, error) {
 	log.Info(fmt.Sprintf("[%s] Regeneration trie hashes started", logPrefix))
 	defer log.Info(fmt.Sprintf("[%s] Regeneration ended", logPrefix))
@@ -120,20 +164,21 @@ func RegenerateIntermediateHashes(logPrefix string, db kv.RwTx, cfg TrieCfg, exp
 
 	accTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer accTrieCollector.Close()
-	accTrieCollectorFunc := accountTrieCollector(accTrieCollector)
+	accTrieCollectorFunc := trie.AccountTrieCollector(accTrieCollector)
 
 	stTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer stTrieCollector.Close()
-	stTrieCollectorFunc := storageTrieCollector(stTrieCollector)
+	stTrieCollectorFunc := trie.StorageTrieCollector(stTrieCollector)
 
 	loader := trie.NewFlatDBTrieLoader(logPrefix)
 	if err := loader.Reset(trie.NewRetainList(0), accTrieCollectorFunc, stTrieCollectorFunc, false); err != nil {
 		return trie.EmptyRoot, err
 	}
-	hash, err := loader.CalcTrieRoot(db, []byte{}, quit)
+	hash, err := calcTrieRoot(logPrefix, loader, db, quit)
 	if err != nil {
 		return trie.EmptyRoot, err
 	}
+	updateTrieMetrics(loader)
 
 	if cfg.checkRoot && hash != expectedRootHash {
 		return hash, nil
@@ -363,20 +408,21 @@ func incrementIntermediateHashes(logPrefix string, s *StageState, db kv.RwTx, to
 
 	accTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer accTrieCollector.Close()
-	accTrieCollectorFunc := accountTrieCollector(accTrieCollector)
+	accTrieCollectorFunc := trie.AccountTrieCollector(accTrieCollector)
 
 	stTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer stTrieCollector.Close()
-	stTrieCollectorFunc := storageTrieCollector(stTrieCollector)
+	stTrieCollectorFunc := trie.StorageTrieCollector(stTrieCollector)
 
 	loader := trie.NewFlatDBTrieLoader(logPrefix)
 	if err := loader.Reset(rl, accTrieCollectorFunc, stTrieCollectorFunc, false); err != nil {
 		return trie.EmptyRoot, err
 	}
-	hash, err := loader.CalcTrieRoot(db, []byte{}, quit)
+	hash, err := calcTrieRoot(logPrefix, loader, db, quit)
 	if err != nil {
 		return trie.EmptyRoot, err
 	}
+	updateTrieMetrics(loader)
 
 	if cfg.checkRoot && hash != expectedRootHash {
 		return hash, nil
@@ -443,20 +489,21 @@ func unwindIntermediateHashesStageImpl(logPrefix string, u *UnwindState, s *Stag
 
 	accTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer accTrieCollector.Close()
-	accTrieCollectorFunc := accountTrieCollector(accTrieCollector)
+	accTrieCollectorFunc := trie.AccountTrieCollector(accTrieCollector)
 
 	stTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer stTrieCollector.Close()
-	stTrieCollectorFunc := storageTrieCollector(stTrieCollector)
+	stTrieCollectorFunc := trie.StorageTrieCollector(stTrieCollector)
 
 	loader := trie.NewFlatDBTrieLoader(logPrefix)
 	if err := loader.Reset(rl, accTrieCollectorFunc, stTrieCollectorFunc, false); err != nil {
 		return err
 	}
-	hash, err := loader.CalcTrieRoot(db, []byte{}, quit)
+	hash, err := calcTrieRoot(logPrefix, loader, db, quit)
 	if err != nil {
 		return err
 	}
+	updateTrieMetrics(loader)
 	if hash != expectedRootHash {
 		return fmt.Errorf("wrong trie root: %x, expected (from header): %x", hash, expectedRootHash)
 	}
@@ -506,52 +553,6 @@ func ResetIH(tx kv.RwTx) error {
 	return nil
 }
 
-func assertSubset(a, b uint16) {
-	if (a & b) != a { // a & b == a - checks whether a is subset of b
-		panic(fmt.Errorf("invariant 'is subset' failed: %b, %b", a, b))
-	}
-}
-
-func accountTrieCollector(collector *etl.Collector) trie.HashCollector2 {
-	newV := make([]byte, 0, 1024)
-	return func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, _ []byte) error {
-		if len(keyHex) == 0 {
-			return nil
-		}
-		if hasState == 0 {
-			return collector.Collect(keyHex, nil)
-		}
-		if bits.OnesCount16(hasHash) != len(hashes)/length.Hash {
-			panic(fmt.Errorf("invariant bits.OnesCount16(hasHash) == len(hashes) failed: %d, %d", bits.OnesCount16(hasHash), len(hashes)/length.Hash))
-		}
-		assertSubset(hasTree, hasState)
-		assertSubset(hasHash, hasState)
-		newV = trie.MarshalTrieNode(hasState, hasTree, hasHash, hashes, nil, newV)
-		return collector.Collect(keyHex, newV)
-	}
-}
-
-func storageTrieCollector(collector *etl.Collector) trie.StorageHashCollector2 {
-	newK := make([]byte, 0, 128)
-	newV := make([]byte, 0, 1024)
-	return func(accWithInc []byte, keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
-		newK = append(append(newK[:0], accWithInc...), keyHex...)
-		if hasState == 0 {
-			return collector.Collect(newK, nil)
-		}
-		if len(keyHex) > 0 && hasHash == 0 && hasTree == 0 {
-			return nil
-		}
-		if bits.OnesCount16(hasHash) != len(hashes)/length.Hash {
-			panic(fmt.Errorf("invariant bits.OnesCount16(hasHash) == len(hashes) failed: %d, %d", bits.OnesCount16(hasHash), len(hashes)/length.Hash))
-		}
-		assertSubset(hasTree, hasState)
-		assertSubset(hasHash, hasState)
-		newV = trie.MarshalTrieNode(hasState, hasTree, hasHash, hashes, rootHash, newV)
-		return collector.Collect(newK, newV)
-	}
-}
-
 func PruneIntermediateHashesStage(s *PruneState, tx kv.RwTx, cfg TrieCfg, ctx context.Context) (err error) {
 	useExternalTx := tx != nil
 	if !useExternalTx {
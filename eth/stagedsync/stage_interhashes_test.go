@@ -11,6 +11,7 @@ import (
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/rlphacks"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/trie"
 
@@ -71,7 +72,7 @@ func TestAccountAndStorageTrie(t *testing.T) {
 	// ----------------------------------------------------------------
 
 	blockReader := snapshotsync.NewBlockReader()
-	cfg := StageTrieCfg(nil, false, true, t.TempDir(), blockReader)
+	cfg := StageTrieCfg(nil, false, true, t.TempDir(), blockReader, nil)
 	_, err := RegenerateIntermediateHashes("IH", tx, cfg, common.Hash{} /* expectedRootHash */, nil /* quit */)
 	assert.Nil(t, err)
 
@@ -191,7 +192,7 @@ func TestAccountTrieAroundExtensionNode(t *testing.T) {
 	assert.Nil(t, tx.Put(kv.HashedAccounts, hash6[:], encoded))
 
 	blockReader := snapshotsync.NewBlockReader()
-	_, err := RegenerateIntermediateHashes("IH", tx, StageTrieCfg(nil, false, true, t.TempDir(), blockReader), common.Hash{} /* expectedRootHash */, nil /* quit */)
+	_, err := RegenerateIntermediateHashes("IH", tx, StageTrieCfg(nil, false, true, t.TempDir(), blockReader, nil), common.Hash{} /* expectedRootHash */, nil /* quit */)
 	assert.Nil(t, err)
 
 	accountTrie := make(map[string][]byte)
@@ -253,7 +254,7 @@ func TestStorageDeletion(t *testing.T) {
 	// ----------------------------------------------------------------
 
 	blockReader := snapshotsync.NewBlockReader()
-	cfg := StageTrieCfg(nil, false, true, t.TempDir(), blockReader)
+	cfg := StageTrieCfg(nil, false, true, t.TempDir(), blockReader, nil)
 	_, err = RegenerateIntermediateHashes("IH", tx, cfg, common.Hash{} /* expectedRootHash */, nil /* quit */)
 	assert.Nil(t, err)
 
@@ -301,3 +302,16 @@ func TestStorageDeletion(t *testing.T) {
 
 	assert.Equal(t, 0, len(storageTrieB))
 }
+
+func TestCorruptedHashedStorage(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	hash := common.HexToHash("0xB000000000000000000000000000000000000000000000000000000000000000")
+	assert.NoError(t, addTestAccount(tx, hash, params.Ether, 1))
+	loc := common.HexToHash("0x1200000000000000000000000000000000000000000000000000000000000000")
+	assert.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash, 1, loc), []byte{0, 1}))
+
+	blockReader := snapshotsync.NewBlockReader()
+	_, err := RegenerateIntermediateHashes("IH", tx, StageTrieCfg(nil, false, true, t.TempDir(), blockReader, nil), common.Hash{} /* expectedRootHash */, nil /* quit */)
+	assert.ErrorIs(t, err, rlphacks.ErrStorageValueNotTrimmed)
+	assert.Contains(t, err.Error(), "stage_hash_state --reset")
+}
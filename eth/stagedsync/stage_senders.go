@@ -21,6 +21,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/freezer"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
@@ -41,9 +42,11 @@ type SendersCfg struct {
 	chainConfig       *params.ChainConfig
 	blockRetire       *snapshotsync.BlockRetire
 	snapshotHashesCfg *snapshothashes.Config
+	freezer           *freezer.Freezer // bodies and receipts of old blocks are moved there on prune, nil if not used
+	ancientKeepRecent uint64
 }
 
-func StageSendersCfg(db kv.RwDB, chainCfg *params.ChainConfig, tmpdir string, prune prune.Mode, br *snapshotsync.BlockRetire) SendersCfg {
+func StageSendersCfg(db kv.RwDB, chainCfg *params.ChainConfig, tmpdir string, prune prune.Mode, br *snapshotsync.BlockRetire, f *freezer.Freezer, ancientKeepRecent uint64) SendersCfg {
 	const sendersBatchSize = 10000
 	const sendersBlockSize = 4096
 
@@ -59,6 +62,8 @@ func StageSendersCfg(db kv.RwDB, chainCfg *params.ChainConfig, tmpdir string, pr
 		prune:             prune,
 		blockRetire:       br,
 		snapshotHashesCfg: snapshothashes.KnownConfig(chainCfg.ChainName),
+		freezer:           f,
+		ancientKeepRecent: ancientKeepRecent,
 	}
 }
 
@@ -301,6 +306,7 @@ type senderRecoveryJob struct {
 func recoverSenders(ctx context.Context, logPrefix string, cryptoContext *secp256k1.Context, config *params.ChainConfig, in, out chan *senderRecoveryJob, quit <-chan struct{}) {
 	var job *senderRecoveryJob
 	var ok bool
+	signers := types.NewForkAwareSigner(config)
 	for {
 		select {
 		case job, ok = <-in:
@@ -317,7 +323,7 @@ func recoverSenders(ctx context.Context, logPrefix string, cryptoContext *secp25
 		}
 
 		body := job.body
-		signer := types.MakeSigner(config, job.blockNumber)
+		signer := signers.SignerForBlock(job.blockNumber)
 		job.senders = make([]byte, len(body.Transactions)*length.Addr)
 		for i, tx := range body.Transactions {
 			from, err := signer.SenderWithContext(cryptoContext, tx)
@@ -398,6 +404,11 @@ func PruneSendersStage(s *PruneState, tx kv.RwTx, cfg SendersCfg, ctx context.Co
 			return err
 		}
 	}
+	if cfg.freezer != nil {
+		if err = freezeBlocks(s, tx, cfg); err != nil {
+			return err
+		}
+	}
 
 	if !useExternalTx {
 		if err = tx.Commit(); err != nil {
@@ -407,6 +418,26 @@ func PruneSendersStage(s *PruneState, tx kv.RwTx, cfg SendersCfg, ctx context.Co
 	return nil
 }
 
+// freezeBlocks - moves bodies and receipts of blocks older than --ancient.keep-recent to the freezer.
+// Not further than Finish stage: stages which read bodies and receipts are done with these blocks
+func freezeBlocks(s *PruneState, tx kv.RwTx, cfg SendersCfg) error {
+	finish, err := stages.GetStageProgress(tx, stages.Finish)
+	if err != nil {
+		return err
+	}
+	to := s.ForwardProgress
+	if finish < to {
+		to = finish
+	}
+	if to <= cfg.ancientKeepRecent {
+		return nil
+	}
+	if err = rawdb.FreezeBlocks(tx, cfg.freezer, to-cfg.ancientKeepRecent, 1_000); err != nil {
+		return fmt.Errorf("[%s] freeze blocks: %w", s.LogPrefix(), err)
+	}
+	return nil
+}
+
 func retireBlocksInSingleBackgroundThread(s *PruneState, cfg SendersCfg, ctx context.Context) (err error) {
 	// if something already happens in background - noop
 	if cfg.blockRetire.Working() {
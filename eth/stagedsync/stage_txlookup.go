@@ -13,6 +13,7 @@ import (
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/ethdb/freezer"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/log/v3"
@@ -23,6 +24,7 @@ type TxLookupCfg struct {
 	prune     prune.Mode
 	tmpdir    string
 	snapshots *snapshotsync.RoSnapshots
+	freezer   *freezer.Freezer
 	isBor     bool
 }
 
@@ -31,6 +33,7 @@ func StageTxLookupCfg(
 	prune prune.Mode,
 	tmpdir string,
 	snapshots *snapshotsync.RoSnapshots,
+	freezer *freezer.Freezer,
 	isBor bool,
 ) TxLookupCfg {
 	return TxLookupCfg{
@@ -38,6 +41,7 @@ func StageTxLookupCfg(
 		prune:     prune,
 		tmpdir:    tmpdir,
 		snapshots: snapshots,
+		freezer:   freezer,
 		isBor:     isBor,
 	}
 }
@@ -210,6 +214,12 @@ func deleteTxLookupRange(tx kv.RwTx, logPrefix string, blockFrom, blockTo uint64
 	return etl.Transform(logPrefix, tx, kv.HeaderCanonical, kv.TxLookup, cfg.tmpdir, func(k, v []byte, next etl.ExtractNextFunc) error {
 		blocknum, blockHash := binary.BigEndian.Uint64(k), common.CastToHash(v)
 		body := rawdb.ReadCanonicalBodyWithTransactions(tx, blockHash, blocknum)
+		if body == nil && cfg.freezer != nil {
+			var err error
+			if body, err = rawdb.ReadFrozenBody(cfg.freezer, blocknum); err != nil {
+				return err
+			}
+		}
 		if body == nil {
 			if cfg.snapshots != nil && cfg.snapshots.Cfg().Enabled && blocknum <= cfg.snapshots.BlocksAvailable() {
 				log.Warn("TxLookup pruning, empty block body", "height", blocknum)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
@@ -22,6 +23,7 @@ type Notifications struct {
 	Events               *privateapi.Events
 	Accumulator          *shards.Accumulator
 	StateChangesConsumer shards.StateChangeConsumer
+	ChainHeadFeed        *core.ChainHeadFeed
 }
 
 func MiningStages(
@@ -0,0 +1,58 @@
+package stagedsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+var ErrStateRootMismatch = errors.New("state root differs from trusted node")
+
+// TrustedRootValidator - compares state roots computed by IntermediateHashes stage with `stateRoot` returned by
+// eth_getBlockByNumber of a trusted node. For development and debugging only: every check is a network round trip
+type TrustedRootValidator struct {
+	url    string
+	client *rpc.Client
+}
+
+func NewTrustedRootValidator(url string) (*TrustedRootValidator, error) {
+	client, err := rpc.DialHTTP(url)
+	if err != nil {
+		return nil, fmt.Errorf("dial trusted node %s: %w", url, err)
+	}
+	return &TrustedRootValidator{url: url, client: client}, nil
+}
+
+// Validate - returns ErrStateRootMismatch if trusted node has different state root of block `blockNum` than `local`.
+// `header` - state root from the block header, zero if it's not known: the error says which of the roots disagree
+func (v *TrustedRootValidator) Validate(ctx context.Context, blockNum uint64, local, header common.Hash) error {
+	var block *struct {
+		StateRoot common.Hash `json:"stateRoot"`
+	}
+	if err := v.client.CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.Uint64(blockNum), false); err != nil {
+		return fmt.Errorf("get block %d from trusted node %s: %w", blockNum, v.url, err)
+	}
+	if block == nil {
+		return fmt.Errorf("trusted node %s doesn't have block %d", v.url, blockNum)
+	}
+	trusted := block.StateRoot
+	if trusted == local {
+		return nil
+	}
+	var which string
+	switch header {
+	case common.Hash{}:
+		which = "local differs from trusted"
+	case trusted:
+		which = "local differs from header and trusted"
+	case local:
+		which = "trusted differs from local and header"
+	default:
+		which = "local, header and trusted all differ"
+	}
+	return fmt.Errorf("%w: block %d, %s: local %x, header %x, trusted %x", ErrStateRootMismatch, blockNum, which, local, header, trusted)
+}
@@ -0,0 +1,33 @@
+package stagedsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedRootValidator(t *testing.T) {
+	trustedRoot := common.HexToHash("0x01")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"number":"0x5","stateRoot":"%s"}}`, trustedRoot.Hex())
+	}))
+	defer srv.Close()
+
+	v, err := NewTrustedRootValidator(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, v.Validate(context.Background(), 5, trustedRoot, common.Hash{}))
+	require.NoError(t, v.Validate(context.Background(), 5, trustedRoot, common.HexToHash("0x03")))
+	err = v.Validate(context.Background(), 5, common.HexToHash("0x02"), common.Hash{})
+	require.True(t, errors.Is(err, ErrStateRootMismatch))
+	err = v.Validate(context.Background(), 5, common.HexToHash("0x02"), trustedRoot)
+	require.ErrorContains(t, err, "local differs from header and trusted")
+	err = v.Validate(context.Background(), 5, common.HexToHash("0x02"), common.HexToHash("0x02"))
+	require.ErrorContains(t, err, "trusted differs from local and header")
+}
@@ -0,0 +1,330 @@
+package freezer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/DataDog/zstd"
+)
+
+// Freezer - append-only storage of old chain data outside of MDBX (--datadir.ancient).
+// Every table is a pair of files:
+// <table>.dat - items, each one compressed into an independent zstd frame, one after another;
+// <table>.idx - big-endian uint64 number of the first item, followed by big-endian uint64 end offset in .dat of every item.
+// Items are numbered without gaps (by block number). Other processes can read the files while the node appends
+// to them: item is written to .dat before its offset is written to .idx. Empty items are stored uncompressed,
+// zero bytes long. Compression is done by cgo bindings of the reference libzstd: erigon already requires cgo (mdbx,
+// secp256k1), and the frames stay readable by any zstd implementation
+type Freezer struct {
+	dir      string
+	readonly bool
+	lock     sync.RWMutex
+	tables   map[string]*table
+}
+
+type table struct {
+	lock     sync.Mutex // guards lazy opening of the files
+	idx, dat *os.File
+}
+
+// Open - opens (creates if not readonly) tables of the freezer in `dir`. Readonly freezer opens tables lazily,
+// they may be created later by the process which appends to them
+func Open(dir string, readonly bool, tables ...string) (*Freezer, error) {
+	if !readonly {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	f := &Freezer{dir: dir, readonly: readonly, tables: make(map[string]*table, len(tables))}
+	for _, name := range tables {
+		t := &table{}
+		f.tables[name] = t
+		if readonly {
+			continue
+		}
+		if err := f.openTable(name, t); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err := t.repair(); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("freezer %s: %w", name, err)
+		}
+	}
+	return f, nil
+}
+
+func (f *Freezer) Dir() string { return f.dir }
+
+func (f *Freezer) openTable(name string, t *table) (err error) {
+	flag := os.O_RDWR | os.O_CREATE
+	if f.readonly {
+		flag = os.O_RDONLY
+	}
+	if t.dat, err = os.OpenFile(filepath.Join(f.dir, name+".dat"), flag, 0644); err != nil {
+		return err
+	}
+	if t.idx, err = os.OpenFile(filepath.Join(f.dir, name+".idx"), flag, 0644); err != nil {
+		t.dat.Close()
+		t.dat = nil
+		return err
+	}
+	return nil
+}
+
+// table - nil if the table of readonly freezer is not created yet
+func (f *Freezer) table(name string) (*table, error) {
+	t, ok := f.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("freezer: unknown table %s", name)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.idx == nil {
+		if err := f.openTable(name, t); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Items - range [first, next) of numbers of the items in the table, first == next if it's empty
+func (f *Freezer) Items(name string) (first, next uint64, err error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	t, err := f.table(name)
+	if err != nil || t == nil {
+		return 0, 0, err
+	}
+	return t.items()
+}
+
+// Append - appends item `number`, which must be the next one. First item of the empty table can have any number
+func (f *Freezer) Append(name string, number uint64, item []byte) error {
+	if f.readonly {
+		return fmt.Errorf("freezer %s: append to readonly freezer", name)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	t, err := f.table(name)
+	if err != nil {
+		return err
+	}
+	first, next, err := t.items()
+	if err != nil {
+		return err
+	}
+	if first == next {
+		if err = t.writeFirst(number); err != nil {
+			return err
+		}
+		first, next = number, number
+	}
+	if number != next {
+		return fmt.Errorf("freezer %s: appending item %d, expected %d", name, number, next)
+	}
+	start, err := t.endOffset(first, next)
+	if err != nil {
+		return err
+	}
+	if len(item) > 0 {
+		if item, err = zstd.Compress(nil, item); err != nil {
+			return err
+		}
+	}
+	if _, err = t.dat.WriteAt(item, int64(start)); err != nil {
+		return err
+	}
+	var end [8]byte
+	binary.BigEndian.PutUint64(end[:], start+uint64(len(item)))
+	_, err = t.idx.WriteAt(end[:], int64(8+8*(number-first)))
+	return err
+}
+
+// Get - item `number`, nil if it's not in the table (or is empty)
+func (f *Freezer) Get(name string, number uint64) ([]byte, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	t, err := f.table(name)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	first, next, err := t.items()
+	if err != nil {
+		return nil, err
+	}
+	if number < first || number >= next {
+		return nil, nil
+	}
+	start, err := t.endOffset(first, number)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.endOffset(first, number+1)
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, nil
+	}
+	buf := make([]byte, end-start)
+	if _, err = t.dat.ReadAt(buf, int64(start)); err != nil {
+		return nil, fmt.Errorf("freezer %s: item %d: %w", name, number, err)
+	}
+	item, err := zstd.Decompress(nil, buf)
+	if err != nil {
+		return nil, fmt.Errorf("freezer %s: item %d: %w", name, number, err)
+	}
+	return item, nil
+}
+
+// Truncate - drops items of the table starting from `next`, all of them if `next` is not greater than the first one
+func (f *Freezer) Truncate(name string, next uint64) error {
+	if f.readonly {
+		return fmt.Errorf("freezer %s: truncate of readonly freezer", name)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	t, err := f.table(name)
+	if err != nil {
+		return err
+	}
+	first, tableNext, err := t.items()
+	if err != nil {
+		return err
+	}
+	if next >= tableNext {
+		return nil
+	}
+	if next <= first {
+		if err = t.idx.Truncate(0); err != nil {
+			return err
+		}
+		return t.dat.Truncate(0)
+	}
+	end, err := t.endOffset(first, next)
+	if err != nil {
+		return err
+	}
+	if err = t.idx.Truncate(int64(8 + 8*(next-first))); err != nil {
+		return err
+	}
+	return t.dat.Truncate(int64(end))
+}
+
+// Sync - flushes appended items to disk, must be called before deleting them from MDBX
+func (f *Freezer) Sync() error {
+	if f.readonly {
+		return nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, t := range f.tables {
+		if err := t.dat.Sync(); err != nil {
+			return err
+		}
+		if err := t.idx.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Freezer) Close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, t := range f.tables {
+		if t.dat != nil {
+			t.dat.Close()
+		}
+		if t.idx != nil {
+			t.idx.Close()
+		}
+	}
+}
+
+// items - first is read from the file every time: readonly freezer of other process doesn't see truncates of the writer
+func (t *table) items() (first, next uint64, err error) {
+	st, err := t.idx.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	if st.Size() < 8 {
+		return 0, 0, nil
+	}
+	var b [8]byte
+	if _, err = t.idx.ReadAt(b[:], 0); err != nil {
+		return 0, 0, err
+	}
+	first = binary.BigEndian.Uint64(b[:])
+	return first, first + uint64(st.Size()-8)/8, nil
+}
+
+func (t *table) writeFirst(number uint64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], number)
+	if _, err := t.idx.WriteAt(b[:], 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// endOffset - offset in .dat of the end of item `number-1`, which is the start of item `number`
+func (t *table) endOffset(first, number uint64) (uint64, error) {
+	if number == first {
+		return 0, nil
+	}
+	var b [8]byte
+	if _, err := t.idx.ReadAt(b[:], int64(8+8*(number-1-first))); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("item %d is not in the index", number-1)
+		}
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// repair - drops a partially written tail after a crash: incomplete index entries and items, which end
+// beyond the end of .dat
+func (t *table) repair() error {
+	st, err := t.idx.Stat()
+	if err != nil {
+		return err
+	}
+	if st.Size() < 8 {
+		if err = t.idx.Truncate(0); err != nil {
+			return err
+		}
+		return t.dat.Truncate(0)
+	}
+	first, next, err := t.items()
+	if err != nil {
+		return err
+	}
+	datSt, err := t.dat.Stat()
+	if err != nil {
+		return err
+	}
+	end, err := t.endOffset(first, next)
+	if err != nil {
+		return err
+	}
+	for next > first && end > uint64(datSt.Size()) {
+		next--
+		if end, err = t.endOffset(first, next); err != nil {
+			return err
+		}
+	}
+	if err = t.idx.Truncate(int64(8 + 8*(next-first))); err != nil {
+		return err
+	}
+	return t.dat.Truncate(int64(end))
+}
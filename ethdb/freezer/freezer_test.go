@@ -0,0 +1,171 @@
+package freezer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendGet(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Open(dir, false, "a")
+	require.NoError(t, err)
+	defer f.Close()
+
+	first, next, err := f.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, first, next)
+
+	require.NoError(t, f.Append("a", 5, []byte("five")))
+	require.NoError(t, f.Append("a", 6, nil))
+	require.NoError(t, f.Append("a", 7, []byte("seven")))
+	require.Error(t, f.Append("a", 9, []byte("gap")))
+	require.Error(t, f.Append("b", 0, nil))
+
+	first, next, err = f.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), first)
+	require.Equal(t, uint64(8), next)
+
+	v, err := f.Get("a", 5)
+	require.NoError(t, err)
+	require.Equal(t, []byte("five"), v)
+	v, err = f.Get("a", 6)
+	require.NoError(t, err)
+	require.Nil(t, v)
+	v, err = f.Get("a", 7)
+	require.NoError(t, err)
+	require.Equal(t, []byte("seven"), v)
+	v, err = f.Get("a", 4)
+	require.NoError(t, err)
+	require.Nil(t, v)
+	v, err = f.Get("a", 8)
+	require.NoError(t, err)
+	require.Nil(t, v)
+	require.NoError(t, f.Sync())
+
+	// other process reads appended items
+	ro, err := Open(dir, true, "a")
+	require.NoError(t, err)
+	defer ro.Close()
+	v, err = ro.Get("a", 7)
+	require.NoError(t, err)
+	require.Equal(t, []byte("seven"), v)
+	require.NoError(t, f.Append("a", 8, []byte("eight")))
+	v, err = ro.Get("a", 8)
+	require.NoError(t, err)
+	require.Equal(t, []byte("eight"), v)
+	require.Error(t, ro.Append("a", 9, nil))
+}
+
+func TestReadonlyNotCreated(t *testing.T) {
+	ro, err := Open(filepath.Join(t.TempDir(), "missing"), true, "a")
+	require.NoError(t, err)
+	defer ro.Close()
+	first, next, err := ro.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, first, next)
+	v, err := ro.Get("a", 0)
+	require.NoError(t, err)
+	require.Nil(t, v)
+}
+
+func TestRepair(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Open(dir, false, "a")
+	require.NoError(t, err)
+	require.NoError(t, f.Append("a", 1, []byte("one")))
+	require.NoError(t, f.Append("a", 2, []byte("two")))
+	require.NoError(t, f.Append("a", 3, []byte("three")))
+	f.Close()
+
+	// crash: last item didn't reach .dat, half of the next offset is written
+	dat := filepath.Join(dir, "a.dat")
+	st, err := os.Stat(dat)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(dat, st.Size()-1))
+	idx, err := os.OpenFile(filepath.Join(dir, "a.idx"), os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = idx.Write([]byte{0, 0, 0})
+	require.NoError(t, err)
+	require.NoError(t, idx.Close())
+
+	f, err = Open(dir, false, "a")
+	require.NoError(t, err)
+	defer f.Close()
+	first, next, err := f.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), first)
+	require.Equal(t, uint64(3), next)
+	v, err := f.Get("a", 2)
+	require.NoError(t, err)
+	require.Equal(t, []byte("two"), v)
+	require.NoError(t, f.Append("a", 3, []byte("three")))
+	v, err = f.Get("a", 3)
+	require.NoError(t, err)
+	require.Equal(t, []byte("three"), v)
+}
+
+func TestTruncate(t *testing.T) {
+	f, err := Open(t.TempDir(), false, "a")
+	require.NoError(t, err)
+	defer f.Close()
+	for n := uint64(3); n < 7; n++ {
+		require.NoError(t, f.Append("a", n, []byte{byte(n)}))
+	}
+
+	require.NoError(t, f.Truncate("a", 10))
+	require.NoError(t, f.Truncate("a", 5))
+	first, next, err := f.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), first)
+	require.Equal(t, uint64(5), next)
+	v, err := f.Get("a", 5)
+	require.NoError(t, err)
+	require.Nil(t, v)
+	require.NoError(t, f.Append("a", 5, []byte("five")))
+	v, err = f.Get("a", 5)
+	require.NoError(t, err)
+	require.Equal(t, []byte("five"), v)
+
+	// truncated to empty, next append chooses the first item again
+	require.NoError(t, f.Truncate("a", 1))
+	first, next, err = f.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, first, next)
+	require.NoError(t, f.Append("a", 1, []byte("one")))
+	first, next, err = f.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), first)
+	require.Equal(t, uint64(2), next)
+}
+
+func TestReadonlyAfterTruncate(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Open(dir, false, "a")
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, f.Append("a", 3, []byte("three")))
+	ro, err := Open(dir, true, "a")
+	require.NoError(t, err)
+	defer ro.Close()
+	v, err := ro.Get("a", 3)
+	require.NoError(t, err)
+	require.Equal(t, []byte("three"), v)
+
+	// writer truncates to empty and starts from the other item, reader sees the new first one
+	require.NoError(t, f.Truncate("a", 0))
+	require.NoError(t, f.Append("a", 1, []byte("one")))
+	first, next, err := ro.Items("a")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), first)
+	require.Equal(t, uint64(2), next)
+	v, err = ro.Get("a", 1)
+	require.NoError(t, err)
+	require.Equal(t, []byte("one"), v)
+	v, err = ro.Get("a", 3)
+	require.NoError(t, err)
+	require.Nil(t, v)
+}
@@ -0,0 +1,80 @@
+package prune
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// ReceiptStorageModeKey - key in kv.DatabaseInfo, absent for dbs created before the mode was introduced (full)
+var ReceiptStorageModeKey = []byte("receiptStorageMode")
+
+// ReceiptStorageMode - what execution stage writes to kv.Receipts and kv.Log
+type ReceiptStorageMode uint8
+
+const (
+	ReceiptStorageFull    ReceiptStorageMode = iota // receipts and logs
+	ReceiptStorageMinimal                           // receipts (status, cumulative gas used) without logs
+	ReceiptStorageNone                              // nothing
+)
+
+func ReceiptStorageModeFromString(s string) (ReceiptStorageMode, error) {
+	switch s {
+	case "full", "":
+		return ReceiptStorageFull, nil
+	case "minimal":
+		return ReceiptStorageMinimal, nil
+	case "none":
+		return ReceiptStorageNone, nil
+	default:
+		return ReceiptStorageFull, fmt.Errorf("unexpected receipt storage mode: %s", s)
+	}
+}
+
+func (m ReceiptStorageMode) String() string {
+	switch m {
+	case ReceiptStorageFull:
+		return "full"
+	case ReceiptStorageMinimal:
+		return "minimal"
+	case ReceiptStorageNone:
+		return "none"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(m))
+	}
+}
+
+// WriteReceipts - false if receipts are not stored at all
+func (m ReceiptStorageMode) WriteReceipts() bool { return m != ReceiptStorageNone }
+
+// WriteLogs - false if receipts are stored without logs
+func (m ReceiptStorageMode) WriteLogs() bool { return m == ReceiptStorageFull }
+
+func GetReceiptStorageMode(db kv.Getter) (ReceiptStorageMode, error) {
+	v, err := db.GetOne(kv.DatabaseInfo, ReceiptStorageModeKey)
+	if err != nil {
+		return ReceiptStorageFull, err
+	}
+	if len(v) == 0 {
+		return ReceiptStorageFull, nil
+	}
+	if len(v) != 1 || v[0] > uint8(ReceiptStorageNone) {
+		return ReceiptStorageFull, fmt.Errorf("unexpected receipt storage mode in db: %x", v)
+	}
+	return ReceiptStorageMode(v[0]), nil
+}
+
+func setReceiptStorageMode(db kv.Putter, m ReceiptStorageMode) error {
+	return db.Put(kv.DatabaseInfo, ReceiptStorageModeKey, []byte{uint8(m)})
+}
+
+func setReceiptStorageModeOnEmpty(db kv.GetPut, m ReceiptStorageMode) error {
+	v, err := db.GetOne(kv.DatabaseInfo, ReceiptStorageModeKey)
+	if err != nil {
+		return err
+	}
+	if len(v) == 0 {
+		return setReceiptStorageMode(db, m)
+	}
+	return nil
+}
@@ -19,8 +19,16 @@ var DefaultMode = Mode{
 	TxIndex:     Distance(math.MaxUint64),
 	CallTraces:  Distance(math.MaxUint64),
 	Experiments: Experiments{}, // all off
+
+	AccountHistory: Before(0), // same as History
+	StorageHistory: Before(0), // same as History
 }
 
+var (
+	PruneAccountHistoryKey = []byte("pruneAccountHistory")
+	PruneStorageHistoryKey = []byte("pruneStorageHistory")
+)
+
 type Experiments struct {
 	TEVM bool
 }
@@ -131,12 +139,33 @@ func Get(db kv.Getter) (Mode, error) {
 		prune.CallTraces = blockAmount
 	}
 
+	blockAmount, err = get(db, PruneAccountHistoryKey)
+	if err != nil {
+		return prune, err
+	}
+	if blockAmount != nil {
+		prune.AccountHistory = blockAmount
+	}
+
+	blockAmount, err = get(db, PruneStorageHistoryKey)
+	if err != nil {
+		return prune, err
+	}
+	if blockAmount != nil {
+		prune.StorageHistory = blockAmount
+	}
+
 	v, err := db.GetOne(kv.DatabaseInfo, kv.StorageModeTEVM)
 	if err != nil {
 		return prune, err
 	}
 	prune.Experiments.TEVM = len(v) == 1 && v[0] == 1
 
+	prune.ReceiptStorage, err = GetReceiptStorageMode(db)
+	if err != nil {
+		return prune, err
+	}
+
 	return prune, nil
 }
 
@@ -147,6 +176,29 @@ type Mode struct {
 	TxIndex     BlockAmount
 	CallTraces  BlockAmount
 	Experiments Experiments
+
+	ReceiptStorage ReceiptStorageMode
+
+	// AccountHistory, StorageHistory - if enabled, used instead of History for account or storage
+	// ChangeSets and HistoryIndices. Only Before is supported by flags
+	AccountHistory BlockAmount
+	StorageHistory BlockAmount
+}
+
+// AccountHistoryAmount - pruning of AccountChangeSet and AccountsHistory
+func (m Mode) AccountHistoryAmount() BlockAmount {
+	if m.AccountHistory != nil && m.AccountHistory.Enabled() {
+		return m.AccountHistory
+	}
+	return m.History
+}
+
+// StorageHistoryAmount - pruning of StorageChangeSet and StorageHistory
+func (m Mode) StorageHistoryAmount() BlockAmount {
+	if m.StorageHistory != nil && m.StorageHistory.Enabled() {
+		return m.StorageHistory
+	}
+	return m.History
 }
 
 type BlockAmount interface {
@@ -230,9 +282,18 @@ func (m Mode) String() string {
 			long += fmt.Sprintf(" --prune.c.%s=%d", m.CallTraces.dbType(), m.CallTraces.toValue())
 		}
 	}
+	if m.AccountHistory != nil && m.AccountHistory.Enabled() {
+		long += fmt.Sprintf(" --prune.account-history.before=%d", m.AccountHistory.toValue())
+	}
+	if m.StorageHistory != nil && m.StorageHistory.Enabled() {
+		long += fmt.Sprintf(" --prune.storage-history.before=%d", m.StorageHistory.toValue())
+	}
 	if m.Experiments.TEVM {
 		long += " --experiments.tevm=enabled"
 	}
+	if m.ReceiptStorage != ReceiptStorageFull {
+		long += " --receipt-storage-mode=" + m.ReceiptStorage.String()
+	}
 
 	return strings.TrimLeft(short+long, " ")
 }
@@ -262,11 +323,28 @@ func Override(db kv.RwTx, sm Mode) error {
 		return err
 	}
 
+	for key, blockAmount := range map[string]BlockAmount{
+		string(PruneAccountHistoryKey): sm.AccountHistory,
+		string(PruneStorageHistoryKey): sm.StorageHistory,
+	} {
+		if blockAmount == nil {
+			blockAmount = Before(0)
+		}
+		if err = set(db, []byte(key), blockAmount); err != nil {
+			return err
+		}
+	}
+
 	err = setMode(db, kv.StorageModeTEVM, sm.Experiments.TEVM)
 	if err != nil {
 		return err
 	}
 
+	err = setReceiptStorageMode(db, sm.ReceiptStorage)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -300,13 +378,18 @@ func setIfNotExist(db kv.GetPut, pm Mode) error {
 	}
 
 	pruneDBData := map[string]BlockAmount{
-		string(kv.PruneHistory):    pm.History,
-		string(kv.PruneReceipts):   pm.Receipts,
-		string(kv.PruneTxIndex):    pm.TxIndex,
-		string(kv.PruneCallTraces): pm.CallTraces,
+		string(kv.PruneHistory):        pm.History,
+		string(kv.PruneReceipts):       pm.Receipts,
+		string(kv.PruneTxIndex):        pm.TxIndex,
+		string(kv.PruneCallTraces):     pm.CallTraces,
+		string(PruneAccountHistoryKey): pm.AccountHistory,
+		string(PruneStorageHistoryKey): pm.StorageHistory,
 	}
 
 	for key, value := range pruneDBData {
+		if value == nil {
+			value = Before(0)
+		}
 		err = setOnEmpty(db, []byte(key), value)
 		if err != nil {
 			return err
@@ -318,6 +401,11 @@ func setIfNotExist(db kv.GetPut, pm Mode) error {
 		return err
 	}
 
+	err = setReceiptStorageModeOnEmpty(db, pm.ReceiptStorage)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
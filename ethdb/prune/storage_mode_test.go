@@ -15,16 +15,16 @@ func TestSetStorageModeIfNotExist(t *testing.T) {
 	prune, err := Get(tx)
 	assert.NoError(t, err)
 	assert.Equal(t, Mode{true, Distance(math.MaxUint64), Distance(math.MaxUint64),
-		Distance(math.MaxUint64), Distance(math.MaxUint64), Experiments{TEVM: false}}, prune)
+		Distance(math.MaxUint64), Distance(math.MaxUint64), Experiments{TEVM: false}, ReceiptStorageFull, Before(0), Before(0)}, prune)
 
 	err = setIfNotExist(tx, Mode{true, Distance(1), Distance(2),
-		Before(3), Before(4), Experiments{TEVM: false}})
+		Before(3), Before(4), Experiments{TEVM: false}, ReceiptStorageMinimal, Before(5), nil})
 	assert.NoError(t, err)
 
 	prune, err = Get(tx)
 	assert.NoError(t, err)
 	assert.Equal(t, Mode{true, Distance(1), Distance(2),
-		Before(3), Before(4), Experiments{TEVM: false}}, prune)
+		Before(3), Before(4), Experiments{TEVM: false}, ReceiptStorageMinimal, Before(5), Before(0)}, prune)
 }
 
 var distanceTests = []struct {
@@ -73,3 +73,41 @@ func TestBeforePruneTo(t *testing.T) {
 		})
 	}
 }
+
+func TestReceiptStorageModeNotChanged(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	minimal := DefaultMode
+	minimal.ReceiptStorage = ReceiptStorageMinimal
+
+	pm, err := EnsureNotChanged(tx, minimal)
+	assert.NoError(t, err)
+	assert.Equal(t, ReceiptStorageMinimal, pm.ReceiptStorage)
+
+	// mode of the db is used if flags are not set
+	pm, err = EnsureNotChanged(tx, Mode{})
+	assert.NoError(t, err)
+	assert.Equal(t, ReceiptStorageMinimal, pm.ReceiptStorage)
+
+	_, err = EnsureNotChanged(tx, DefaultMode)
+	assert.Error(t, err)
+
+	for _, s := range []string{"full", "minimal", "none"} {
+		m, err := ReceiptStorageModeFromString(s)
+		assert.NoError(t, err)
+		assert.Equal(t, s, m.String())
+	}
+	_, err = ReceiptStorageModeFromString("logs")
+	assert.Error(t, err)
+}
+
+func TestHistoryAmounts(t *testing.T) {
+	m := DefaultMode
+	m.History = Distance(100)
+	assert.Equal(t, Distance(100), m.AccountHistoryAmount())
+	assert.Equal(t, Distance(100), m.StorageHistoryAmount())
+
+	m.AccountHistory = Before(1_000)
+	assert.Equal(t, Before(1_000), m.AccountHistoryAmount())
+	assert.Equal(t, Distance(100), m.StorageHistoryAmount())
+	assert.Equal(t, "--prune.h.older=100 --prune.account-history.before=1000", m.String())
+}
@@ -3,6 +3,7 @@ module github.com/ledgerwatch/erigon
 go 1.18
 
 require (
+	github.com/DataDog/zstd v1.5.2
 	github.com/RoaringBitmap/roaring v1.2.0
 	github.com/VictoriaMetrics/fastcache v1.10.0
 	github.com/VictoriaMetrics/metrics v1.18.1
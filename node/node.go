@@ -37,6 +37,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/migrations"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/rpc"
@@ -497,6 +498,9 @@ func OpenDatabase(config *nodecfg.Config, logger log.Logger, label kv.Label) (kv
 	}
 	var db kv.RwDB
 	if config.Dirs.DataDir == "" {
+		if label == kv.ChainDB {
+			return mdbx.NewMDBX(logger).InMem().WithTablessCfg(rawdb.WithChaindataTables).Open()
+		}
 		db = memdb.New()
 		return db, nil
 	}
@@ -510,7 +514,7 @@ func OpenDatabase(config *nodecfg.Config, logger log.Logger, label kv.Label) (kv
 			opts = opts.Exclusive()
 		}
 		if label == kv.ChainDB {
-			opts = opts.PageSize(config.MdbxPageSize.Bytes()).MapSize(8 * datasize.TB)
+			opts = opts.PageSize(config.MdbxPageSize.Bytes()).MapSize(8 * datasize.TB).WithTablessCfg(rawdb.WithChaindataTables)
 		}
 		return opts.Open()
 	}
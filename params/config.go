@@ -569,7 +569,7 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 // CheckConfigForkOrder checks that we don't "skip" any forks, geth isn't pluggable enough
 // to guarantee that forks can be implemented in a different order than on official networks
 func (c *ChainConfig) CheckConfigForkOrder() error {
-	if c != nil && c.ChainID != nil && c.ChainID.Uint64() == 77 {
+	if c != nil && c.ChainID != nil && c.ChainID.Cmp(big.NewInt(77)) == 0 {
 		return nil
 	}
 	type fork struct {
@@ -34,6 +34,8 @@ type API struct {
 	Version   string      // api version for DApp's
 	Service   interface{} // receiver instance which holds the methods
 	Public    bool        // indication if the methods must be considered safe for public use
+	// Authenticated - methods are served only by the JWT-protected engine endpoint
+	Authenticated bool
 }
 
 // Error wraps RPC errors, which contain an error code in addition to the message.
@@ -94,8 +94,8 @@ func (tt *TransactionTest) Run(chainID *big.Int) error {
 		}
 
 		// EIP-2681: Limit account nonce to 2^64-1
-		if msg.Nonce()+1 < msg.Nonce() {
-			return nil, nil, requiredGas, fmt.Errorf("%w: nonce: %d", core.ErrNonceMax, msg.Nonce())
+		if err := types.ValidateNonce(msg.Nonce()); err != nil {
+			return nil, nil, requiredGas, fmt.Errorf("%w: nonce: %d", err, msg.Nonce())
 		}
 		h := tx.Hash()
 		return &sender, &h, requiredGas, nil
@@ -9,6 +9,8 @@ import (
 // DefaultFlags contains all flags that are used and supported by Erigon binary.
 var DefaultFlags = []cli.Flag{
 	utils.DataDirFlag,
+	utils.AncientFlag,
+	utils.AncientKeepRecentFlag,
 	utils.EthashDatasetDirFlag,
 	utils.SnapshotFlag,
 	utils.TxPoolDisableFlag,
@@ -32,6 +34,9 @@ var DefaultFlags = []cli.Flag{
 	PruneReceiptBeforeFlag,
 	PruneTxIndexBeforeFlag,
 	PruneCallTracesBeforeFlag,
+	PruneAccountHistoryBeforeFlag,
+	PruneStorageHistoryBeforeFlag,
+	ReceiptStorageModeFlag,
 	BatchSizeFlag,
 	BlockDownloaderWindowFlag,
 	DatabaseVerbosityFlag,
@@ -45,6 +50,7 @@ var DefaultFlags = []cli.Flag{
 	StateStreamDisableFlag,
 	SyncLoopThrottleFlag,
 	BadBlockFlag,
+	TrustValidateURLFlag,
 
 	utils.HTTPEnabledFlag,
 	utils.HTTPListenAddrFlag,
@@ -64,6 +70,7 @@ var DefaultFlags = []cli.Flag{
 	utils.RpcAccessListFlag,
 	utils.RpcTraceCompatFlag,
 	utils.RpcGasCapFlag,
+	utils.RpcGasOraclePercentileFlag,
 	utils.StarknetGrpcAddressFlag,
 	utils.TevmFlag,
 	utils.TxpoolApiAddrFlag,
@@ -109,6 +116,7 @@ var DefaultFlags = []cli.Flag{
 	utils.CliqueSnapshotInmemorySignaturesFlag,
 	utils.CliqueDataDirFlag,
 	utils.EnabledIssuance,
+	utils.TrackAccountVersionsFlag,
 	utils.MiningEnabledFlag,
 	utils.ProposingDisableFlag,
 	utils.MinerNotifyFlag,
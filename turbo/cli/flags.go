@@ -100,6 +100,23 @@ var (
 		Name:  "prune.c.before",
 		Usage: `Prune data before this block`,
 	}
+	PruneAccountHistoryBeforeFlag = cli.Uint64Flag{
+		Name:  "prune.account-history.before",
+		Usage: `Prune account history (used by eth_getBalance and similar at historical blocks) before this block, overrides --prune.h.* for accounts`,
+	}
+	PruneStorageHistoryBeforeFlag = cli.Uint64Flag{
+		Name:  "prune.storage-history.before",
+		Usage: `Prune storage history (used by eth_getStorageAt at historical blocks) before this block, overrides --prune.h.* for storage`,
+	}
+
+	ReceiptStorageModeFlag = cli.StringFlag{
+		Name: "receipt-storage-mode",
+		Usage: `What execution stores for eth_getTransactionReceipt and eth_getLogs, can't be changed after the db is created:
+* full - receipts and logs
+* minimal - only status and gas used of receipts, no logs
+* none - nothing`,
+		Value: "full",
+	}
 
 	ExperimentsFlag = cli.StringFlag{
 		Name: "experiments",
@@ -146,6 +163,12 @@ var (
 		Value: "",
 	}
 
+	TrustValidateURLFlag = cli.StringFlag{
+		Name:  "debug.trust-validate-url",
+		Usage: "JSON-RPC url of a trusted node: state root computed by IntermediateHashes stage is compared with its eth_getBlockByNumber, sync stops on mismatch. The stage computes the root only of the last block of each run (batch), so blocks in between are not checked. For debugging only",
+		Value: "",
+	}
+
 	HealthCheckFlag = cli.BoolFlag{
 		Name:  "healthcheck",
 		Usage: "Enable grpc health check",
@@ -168,6 +191,12 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 	if err != nil {
 		utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
 	}
+	mode.ReceiptStorage, err = prune.ReceiptStorageModeFromString(ctx.GlobalString(ReceiptStorageModeFlag.Name))
+	if err != nil {
+		utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
+	}
+	mode.AccountHistory = prune.Before(ctx.GlobalUint64(PruneAccountHistoryBeforeFlag.Name))
+	mode.StorageHistory = prune.Before(ctx.GlobalUint64(PruneStorageHistoryBeforeFlag.Name))
 	cfg.Prune = mode
 	if ctx.GlobalString(BatchSizeFlag.Name) != "" {
 		err := cfg.BatchSize.UnmarshalText([]byte(ctx.GlobalString(BatchSizeFlag.Name)))
@@ -197,6 +226,8 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		cfg.Sync.LoopThrottle = syncLoopThrottle
 	}
 
+	cfg.Sync.TrustedRootValidationURL = ctx.GlobalString(TrustValidateURLFlag.Name)
+
 	if ctx.GlobalString(BadBlockFlag.Name) != "" {
 		bytes, err := hexutil.Decode(ctx.GlobalString(BadBlockFlag.Name))
 		if err != nil {
@@ -246,6 +277,18 @@ func ApplyFlagsForEthConfigCobra(f *pflag.FlagSet, cfg *ethconfig.Config) {
 		if err != nil {
 			utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
 		}
+		if v := f.String(ReceiptStorageModeFlag.Name, ReceiptStorageModeFlag.Value, ReceiptStorageModeFlag.Usage); v != nil {
+			mode.ReceiptStorage, err = prune.ReceiptStorageModeFromString(*v)
+			if err != nil {
+				utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
+			}
+		}
+		if v := f.Uint64(PruneAccountHistoryBeforeFlag.Name, PruneAccountHistoryBeforeFlag.Value, PruneAccountHistoryBeforeFlag.Usage); v != nil {
+			mode.AccountHistory = prune.Before(*v)
+		}
+		if v := f.Uint64(PruneStorageHistoryBeforeFlag.Name, PruneStorageHistoryBeforeFlag.Value, PruneStorageHistoryBeforeFlag.Usage); v != nil {
+			mode.StorageHistory = prune.Before(*v)
+		}
 		cfg.Prune = mode
 	}
 	if v := f.String(BatchSizeFlag.Name, BatchSizeFlag.Value, BatchSizeFlag.Usage); v != nil {
@@ -303,6 +346,7 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 		DBReadConcurrency:    ctx.GlobalInt(utils.DBReadConcurrencyFlag.Name),
 		RpcAllowListFilePath: ctx.GlobalString(utils.RpcAccessListFlag.Name),
 		Gascap:               ctx.GlobalUint64(utils.RpcGasCapFlag.Name),
+		GasOraclePercentile:  ctx.GlobalInt(utils.RpcGasOraclePercentileFlag.Name),
 		MaxTraces:            ctx.GlobalUint64(utils.TraceMaxtracesFlag.Name),
 		TraceCompatibility:   ctx.GlobalBool(utils.RpcTraceCompatFlag.Name),
 		StarknetGRPCAddress:  ctx.GlobalString(utils.StarknetGrpcAddressFlag.Name),
@@ -0,0 +1,23 @@
+package rlphacks
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrStorageValueTooLarge   = errors.New("storage value is longer than 32 bytes")
+	ErrStorageValueNotTrimmed = errors.New("storage value has leading zeros")
+)
+
+// ValidateStorageValue - storage values are stored as big-endian uint256 without leading zeros,
+// zero values are deleted instead of being stored. Anything else means corrupted state
+func ValidateStorageValue(v []byte) error {
+	if len(v) > 32 {
+		return fmt.Errorf("%w: %d bytes", ErrStorageValueTooLarge, len(v))
+	}
+	if len(v) == 0 || v[0] == 0 {
+		return fmt.Errorf("%w: %x", ErrStorageValueNotTrimmed, v)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package rlphacks
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestValidateStorageValue(t *testing.T) {
+	for _, tc := range []struct {
+		v   []byte
+		err error
+	}{
+		{v: []byte{1}},
+		{v: []byte{0x80, 0}},
+		{v: bytes.Repeat([]byte{0xff}, 32)},
+		{v: bytes.Repeat([]byte{0xff}, 33), err: ErrStorageValueTooLarge},
+		{v: nil, err: ErrStorageValueNotTrimmed},
+		{v: []byte{0}, err: ErrStorageValueNotTrimmed},
+		{v: []byte{0, 1}, err: ErrStorageValueNotTrimmed},
+	} {
+		err := ValidateStorageValue(tc.v)
+		if tc.err == nil && err != nil {
+			t.Errorf("%x: unexpected error %v", tc.v, err)
+		}
+		if tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("%x: expected %v, got %v", tc.v, tc.err, err)
+		}
+	}
+}
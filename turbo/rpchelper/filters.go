@@ -46,6 +46,7 @@ type Filters struct {
 	logsSubs         *LogsFilterAggregator
 	logsRequestor    atomic.Value
 	onNewSnapshot    func()
+	done             <-chan struct{}
 
 	storeMu            sync.Mutex
 	logsStores         map[LogsSubID][]*types.Log
@@ -63,6 +64,7 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 		pendingBlockSubs:   make(map[PendingBlockSubID]chan *types.Block),
 		logsSubs:           NewLogsFilterAggregator(),
 		onNewSnapshot:      onNewSnapshot,
+		done:               ctx.Done(),
 		logsStores:         make(map[LogsSubID][]*types.Log),
 		pendingBlockStores: make(map[PendingBlockSubID][]*types.Block),
 		pendingTxsStores:   make(map[PendingTxsSubID][][]types.Transaction),
@@ -302,6 +304,11 @@ func (ff *Filters) HandlePendingLogs(reply *txpool.OnPendingLogsReply) {
 	}
 }
 
+// Done - closed when the context of the filters is cancelled, long-lived subscribers unsubscribe then
+func (ff *Filters) Done() <-chan struct{} {
+	return ff.done
+}
+
 func (ff *Filters) SubscribeNewHeads(out chan *types.Header) HeadsSubID {
 	ff.mu.Lock()
 	defer ff.mu.Unlock()
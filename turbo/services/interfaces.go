@@ -55,3 +55,14 @@ type FullBlockReader interface {
 	TxnReader
 	CanonicalReader
 }
+
+// FrozenReceiptsReader - block reader which can read receipts moved from the db to the freezer (--datadir.ancient)
+type FrozenReceiptsReader interface {
+	FrozenReceipts(ctx context.Context, block *types.Block, senders []common.Address) (types.Receipts, error)
+}
+
+// FrozenBodyReader - block reader which can read ids of transactions of bodies moved from the db to the freezer,
+// like rawdb.ReadBody. ok is false if the body is not in the freezer
+type FrozenBodyReader interface {
+	FrozenBodyTxIds(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (baseTxId uint64, txAmount uint32, ok bool, err error)
+}
@@ -0,0 +1,164 @@
+package shards
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+)
+
+// CacheEvictionPolicy decides which reads are evicted from StateCache when it goes over the limit.
+// Reads are kept in the heap ordered by priority (stored as the item's sequence), lowest priority is evicted first.
+// Writes cannot be evicted, but they are still touched, because they turn into reads after the commit
+type CacheEvictionPolicy interface {
+	// Touch is called every time item is inserted into the cache or found in it. Returns new priority of the item.
+	// sequence is incremented after every touch of any item
+	Touch(item CacheItem, sequence int) int
+	// Admit is called when a new read doesn't fit into the cache, victim is the read with the lowest priority.
+	// Returning false means the new read is not cached and nothing is evicted
+	Admit(item, victim CacheItem) bool
+	// Evicted is called after the item was removed from the cache
+	Evicted(item CacheItem)
+}
+
+const (
+	LRUEvictionPolicy     = "lru"
+	LFUEvictionPolicy     = "lfu"
+	TinyLFUEvictionPolicy = "tinylfu"
+)
+
+// NewCacheEvictionPolicy creates policy by its name, sampleSize is only used by TinyLFU
+func NewCacheEvictionPolicy(name string, sampleSize int) (CacheEvictionPolicy, error) {
+	switch name {
+	case LRUEvictionPolicy:
+		return NewLRU(), nil
+	case LFUEvictionPolicy:
+		return NewLFU(), nil
+	case TinyLFUEvictionPolicy:
+		return NewTinyLFU(sampleSize), nil
+	default:
+		return nil, fmt.Errorf("unknown cache eviction policy: %s, expected one of: %s, %s, %s", name, LRUEvictionPolicy, LFUEvictionPolicy, TinyLFUEvictionPolicy)
+	}
+}
+
+// LRU - evicts the read which was not accessed for the longest time
+type LRU struct{}
+
+func NewLRU() *LRU { return &LRU{} }
+
+func (LRU) Touch(_ CacheItem, sequence int) int { return sequence }
+func (LRU) Admit(_, _ CacheItem) bool           { return true }
+func (LRU) Evicted(CacheItem)                   {}
+
+// LFU - evicts the read which was accessed least number of times since it was cached
+type LFU struct {
+	freq map[CacheItem]int
+}
+
+func NewLFU() *LFU { return &LFU{freq: map[CacheItem]int{}} }
+
+func (p *LFU) Touch(item CacheItem, _ int) int {
+	p.freq[item]++
+	return p.freq[item]
+}
+func (p *LFU) Admit(_, _ CacheItem) bool { return true }
+func (p *LFU) Evicted(item CacheItem)    { delete(p.freq, item) }
+
+// TinyLFU - reads are evicted in LRU order, but a new read is cached only if it was accessed at least as often as
+// the read it would evict. Frequencies of all keys (cached or not) are estimated by the count-min sketch,
+// which is aged (all counters halved) every sampleSize touches, so frequencies reflect the recent history.
+// This protects hot items from scans of keys which are used only once.
+// See "TinyLFU: A Highly Efficient Cache Admission Policy" by G. Einziger, R. Friedman, B. Manes
+type TinyLFU struct {
+	sketch     [4][]uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+const tinyLFUMaxCount = 15
+
+func NewTinyLFU(sampleSize int) *TinyLFU {
+	if sampleSize < 16 {
+		sampleSize = 16
+	}
+	width := 1
+	for width < sampleSize {
+		width <<= 1
+	}
+	p := &TinyLFU{mask: uint64(width - 1), sampleSize: sampleSize}
+	for i := range p.sketch {
+		p.sketch[i] = make([]uint8, width)
+	}
+	return p
+}
+
+func (p *TinyLFU) Touch(item CacheItem, sequence int) int {
+	h := cacheItemKeyHash(item)
+	for i := range p.sketch {
+		if idx := p.index(h, i); p.sketch[i][idx] < tinyLFUMaxCount {
+			p.sketch[i][idx]++
+		}
+	}
+	p.additions++
+	if p.additions >= p.sampleSize {
+		p.reset()
+	}
+	return sequence
+}
+
+func (p *TinyLFU) Admit(item, victim CacheItem) bool {
+	return p.Estimate(item) >= p.Estimate(victim)
+}
+
+func (p *TinyLFU) Evicted(CacheItem) {}
+
+// Estimate - approximate number of recent touches of the item's key
+func (p *TinyLFU) Estimate(item CacheItem) int {
+	h := cacheItemKeyHash(item)
+	min := uint8(tinyLFUMaxCount)
+	for i := range p.sketch {
+		if c := p.sketch[i][p.index(h, i)]; c < min {
+			min = c
+		}
+	}
+	return int(min)
+}
+
+func (p *TinyLFU) index(h uint64, i int) uint64 {
+	return (h + uint64(i)*((h>>32)|1)) & p.mask
+}
+
+func (p *TinyLFU) reset() {
+	for i := range p.sketch {
+		for j := range p.sketch[i] {
+			p.sketch[i][j] >>= 1
+		}
+	}
+	p.additions /= 2
+}
+
+// cacheItemKeyHash - hash of the item's key (not value), keys of the state items are already keccak hashes
+func cacheItemKeyHash(item CacheItem) uint64 {
+	switch i := item.(type) {
+	case *AccountItem:
+		return binary.BigEndian.Uint64(i.addrHash[:])
+	case *StorageItem:
+		return binary.BigEndian.Uint64(i.addrHash[:]) ^ binary.BigEndian.Uint64(i.locHash[:]) ^ i.incarnation
+	case *CodeItem:
+		return binary.BigEndian.Uint64(i.addrHash[:]) ^ i.incarnation ^ 0xc0de
+	case *AccountHashItem:
+		h := fnv.New64a()
+		//nolint:errcheck
+		h.Write(i.addrHashPrefix)
+		return h.Sum64()
+	case *StorageHashItem:
+		h := fnv.New64a()
+		//nolint:errcheck
+		h.Write(i.addrHash[:])
+		//nolint:errcheck
+		h.Write(i.locHashPrefix)
+		return h.Sum64() ^ i.incarnation
+	default:
+		panic(fmt.Sprintf("unexpected type: %T", item))
+	}
+}
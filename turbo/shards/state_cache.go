@@ -353,6 +353,8 @@ type StateCache struct {
 	writeSize   int
 	sequence    int                // Current sequence assigned to any item that has been "touched" (created, deleted, read). Incremented after every touch
 	unprocQueue [5]UnprocessedHeap // Priority queue of items appeared since last root calculation processing (sorted by the keys - addrHash, incarnation, locHash)
+
+	policy CacheEvictionPolicy // If nil, reads are evicted in the order they were added to the cache
 }
 
 func id(a interface{}) uint8 {
@@ -391,6 +393,33 @@ func NewStateCache(degree int, limit datasize.ByteSize) *StateCache {
 	return &sc
 }
 
+// SetEvictionPolicy - with the policy set, reads found in the cache are touched too, so Get* methods modify the
+// read queue. Clones don't inherit the policy
+func (sc *StateCache) SetEvictionPolicy(policy CacheEvictionPolicy) *StateCache {
+	sc.policy = policy
+	return sc
+}
+
+// touch assigns new sequence (eviction priority) to the item
+func (sc *StateCache) touch(item CacheItem) {
+	if sc.policy == nil {
+		item.SetSequence(sc.sequence)
+	} else {
+		item.SetSequence(sc.policy.Touch(item, sc.sequence))
+	}
+	sc.sequence++
+}
+
+// evict removes the read with the lowest priority
+func (sc *StateCache) evict(id uint8) {
+	cacheItem := heap.Pop(&sc.readQueue[id]).(CacheItem)
+	sc.readSize -= cacheItem.GetSize()
+	sc.readWrites[id].Delete(cacheItem)
+	if sc.policy != nil {
+		sc.policy.Evicted(cacheItem)
+	}
+}
+
 // Clone creates a clone cache which can be modified independently, but it shares the parts of the cache that are common
 func (sc *StateCache) Clone() *StateCache {
 	var clone StateCache
@@ -411,6 +440,14 @@ func (sc *StateCache) get(key btree.Item) (CacheItem, bool) {
 		return nil, false
 	}
 	cacheItem := item.(CacheItem)
+	if sc.policy != nil && !cacheItem.HasFlag(ModifiedFlag) {
+		// Only reads are in the queue, and items shared with the clone may be missing from it
+		id := id(key)
+		if pos := cacheItem.GetQueuePos(); pos < sc.readQueue[id].Len() && sc.readQueue[id].items[pos] == cacheItem {
+			sc.touch(cacheItem)
+			heap.Fix(&sc.readQueue[id], pos)
+		}
+	}
 	if cacheItem.HasFlag(DeletedFlag) || cacheItem.HasFlag(AbsentFlag) {
 		return nil, true
 	}
@@ -523,8 +560,7 @@ func (sc *StateCache) setRead(item CacheItem, absent bool) {
 	if sc.readWrites[id].Get(item) != nil {
 		panic(fmt.Sprintf("item must not be present in the cache before doing setRead: %s", item))
 	}
-	item.SetSequence(sc.sequence)
-	sc.sequence++
+	sc.touch(item)
 	item.ClearFlags(ModifiedFlag | DeletedFlag)
 	if absent {
 		item.SetFlags(AbsentFlag)
@@ -533,11 +569,12 @@ func (sc *StateCache) setRead(item CacheItem, absent bool) {
 	}
 
 	if sc.limit != 0 && sc.readSize+item.GetSize() > int(sc.limit) {
+		if sc.policy != nil && sc.readQueue[id].Len() > 0 && !sc.policy.Admit(item, sc.readQueue[id].items[0]) {
+			return
+		}
 		for sc.readQueue[id].Len() > 0 && sc.readSize+item.GetSize() > int(sc.limit) {
 			// Read queue cannot grow anymore, need to evict one element
-			cacheItem := heap.Pop(&sc.readQueue[id]).(CacheItem)
-			sc.readSize -= cacheItem.GetSize()
-			sc.readWrites[id].Delete(cacheItem)
+			sc.evict(id)
 		}
 	}
 	// Push new element on the read queue
@@ -632,8 +669,7 @@ func (sc *StateCache) setWrite(item CacheItem, writeItem CacheWriteItem, delete
 			cacheItem.CopyValueFrom(item)
 			cacheItem.ClearFlags(DeletedFlag)
 		}
-		cacheItem.SetSequence(sc.sequence)
-		sc.sequence++
+		sc.touch(cacheItem)
 		return
 	}
 	// Now see if there is such item in the readWrite B-tree - then we replace read entry with write entry
@@ -653,8 +689,7 @@ func (sc *StateCache) setWrite(item CacheItem, writeItem CacheWriteItem, delete
 			cacheItem.CopyValueFrom(item)
 			cacheItem.ClearFlags(DeletedFlag)
 		}
-		cacheItem.SetSequence(sc.sequence)
-		sc.sequence++
+		sc.touch(cacheItem)
 		writeItem.SetCacheItem(cacheItem)
 		sc.writes[id].ReplaceOrInsert(writeItem)
 		sc.writeSize += writeItem.GetSize()
@@ -663,13 +698,10 @@ func (sc *StateCache) setWrite(item CacheItem, writeItem CacheWriteItem, delete
 	if sc.limit != 0 && sc.readSize+item.GetSize() > int(sc.limit) {
 		for sc.readQueue[id].Len() > 0 && sc.readSize+item.GetSize() > int(sc.limit) {
 			// There is no space available, need to evict one read element
-			cacheItem := heap.Pop(&sc.readQueue[id]).(CacheItem)
-			sc.readWrites[id].Delete(cacheItem)
-			sc.readSize -= cacheItem.GetSize()
+			sc.evict(id)
 		}
 	}
-	item.SetSequence(sc.sequence)
-	sc.sequence++
+	sc.touch(item)
 	item.SetFlags(ModifiedFlag)
 	item.ClearFlags(AbsentFlag)
 	if delete {
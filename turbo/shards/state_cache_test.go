@@ -2,7 +2,9 @@ package shards
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/c2h5oh/datasize"
@@ -564,3 +566,121 @@ func TestWriteCodeExceedLimit(t *testing.T) {
 		sc.SetCodeWrite(addr.Bytes(), 1, code)
 	}
 }
+
+func evictionTestAddr(i int) []byte {
+	var addr common.Address
+	binary.BigEndian.PutUint64(addr[:], uint64(i))
+	return addr.Bytes()
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	var account accounts.Account
+	cached := func(sc *StateCache, i int) bool {
+		_, ok := sc.GetAccount(evictionTestAddr(i))
+		return ok
+	}
+	fill := func(policy CacheEvictionPolicy) *StateCache {
+		sc := NewStateCache(32, datasize.ByteSize(4*accountItemSize)).SetEvictionPolicy(policy)
+		for i := 1; i <= 4; i++ {
+			sc.SetAccountRead(evictionTestAddr(i), &account)
+		}
+		return sc
+	}
+
+	// LRU: access moves item to the end of the queue
+	sc := fill(NewLRU())
+	assert.True(t, cached(sc, 1))
+	sc.SetAccountRead(evictionTestAddr(5), &account)
+	assert.True(t, cached(sc, 1))
+	assert.False(t, cached(sc, 2))
+
+	// LFU: least accessed item is evicted
+	sc = fill(NewLFU())
+	for i := 1; i <= 3; i++ {
+		for j := 0; j < i; j++ {
+			assert.True(t, cached(sc, i))
+		}
+	}
+	sc.SetAccountRead(evictionTestAddr(5), &account)
+	assert.False(t, cached(sc, 4))
+	for i := 1; i <= 3; i++ {
+		assert.True(t, cached(sc, i))
+	}
+
+	// TinyLFU: scan of new keys doesn't evict frequently used ones
+	sc = fill(NewTinyLFU(1000))
+	lru := fill(NewLRU())
+	for j := 0; j < 3; j++ {
+		for i := 1; i <= 4; i++ {
+			cached(sc, i)
+			cached(lru, i)
+		}
+	}
+	for i := 100; i < 200; i++ {
+		sc.SetAccountRead(evictionTestAddr(i), &account)
+		lru.SetAccountRead(evictionTestAddr(i), &account)
+	}
+	for i := 1; i <= 4; i++ {
+		assert.True(t, cached(sc, i))
+		assert.False(t, cached(lru, i))
+	}
+	assert.Equal(t, 4, sc.TotalCount())
+
+	_, err := NewCacheEvictionPolicy("fifo", 0)
+	assert.Error(t, err)
+}
+
+// BenchmarkEvictionPolicies reports hit ratio of the cache holding 1000 accounts on two access patterns:
+// friendly - zipf distribution over 100k accounts; adversarial - half of accesses go to 500 hot accounts,
+// another half is the scan of accounts which are never accessed again
+func BenchmarkEvictionPolicies(b *testing.B) {
+	const cacheSize = 1000
+	patterns := map[string]func(r *rand.Rand) func() int{
+		"friendly": func(r *rand.Rand) func() int {
+			z := rand.NewZipf(r, 1.1, 1, 100_000)
+			return func() int { return int(z.Uint64()) }
+		},
+		"adversarial": func(r *rand.Rand) func() int {
+			scan := 1_000_000
+			return func() int {
+				if r.Intn(2) == 0 {
+					return r.Intn(cacheSize / 2)
+				}
+				scan++
+				return scan
+			}
+		},
+	}
+	policies := []string{"", LRUEvictionPolicy, LFUEvictionPolicy, TinyLFUEvictionPolicy}
+	var account accounts.Account
+	for patternName, pattern := range patterns {
+		for _, policyName := range policies {
+			name := policyName
+			if name == "" {
+				name = "default"
+			}
+			b.Run(fmt.Sprintf("%s/%s", patternName, name), func(b *testing.B) {
+				sc := NewStateCache(32, datasize.ByteSize(cacheSize*accountItemSize))
+				if policyName != "" {
+					policy, err := NewCacheEvictionPolicy(policyName, 10*cacheSize)
+					if err != nil {
+						b.Fatal(err)
+					}
+					sc.SetEvictionPolicy(policy)
+				}
+				next := pattern(rand.New(rand.NewSource(1))) //nolint:gosec
+				var hits int
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					addr := evictionTestAddr(next())
+					if _, ok := sc.GetAccount(addr); ok {
+						hits++
+						continue
+					}
+					sc.SetAccountRead(addr, &account)
+				}
+				b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+			})
+		}
+	}
+}
@@ -13,17 +13,57 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/freezer"
 	"github.com/ledgerwatch/erigon/rlp"
 )
 
 // BlockReader can read blocks from db and snapshots
 type BlockReader struct {
+	freezer *freezer.Freezer // bodies and receipts of old blocks, moved from the db (--datadir.ancient). nil if not used
 }
 
 func NewBlockReader() *BlockReader {
 	return &BlockReader{}
 }
 
+// NewBlockReaderWithFreezer - reads canonical bodies and receipts from the freezer, when they are not in the db
+func NewBlockReaderWithFreezer(f *freezer.Freezer) *BlockReader {
+	return &BlockReader{freezer: f}
+}
+
+// frozenBody - nil if the block is not canonical or is not in the freezer
+func (back *BlockReader) frozenBody(tx kv.Getter, hash common.Hash, blockHeight uint64) (*types.Body, error) {
+	if back.freezer == nil {
+		return nil, nil
+	}
+	canonicalHash, err := rawdb.ReadCanonicalHash(tx, blockHeight)
+	if err != nil {
+		return nil, err
+	}
+	if canonicalHash != hash {
+		return nil, nil
+	}
+	return rawdb.ReadFrozenBody(back.freezer, blockHeight)
+}
+
+func (back *BlockReader) FrozenReceipts(ctx context.Context, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	if back.freezer == nil {
+		return nil, nil
+	}
+	return rawdb.ReadFrozenReceipts(back.freezer, block, senders)
+}
+
+func (back *BlockReader) FrozenBodyTxIds(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (uint64, uint32, bool, error) {
+	if back.freezer == nil {
+		return 0, 0, false, nil
+	}
+	canonicalHash, err := rawdb.ReadCanonicalHash(tx, blockHeight)
+	if err != nil || canonicalHash != hash {
+		return 0, 0, false, err
+	}
+	return rawdb.ReadFrozenBodyTxIds(back.freezer, blockHeight)
+}
+
 func (back *BlockReader) CanonicalHash(ctx context.Context, tx kv.Getter, blockHeight uint64) (common.Hash, error) {
 	return rawdb.ReadCanonicalHash(tx, blockHeight)
 }
@@ -35,11 +75,18 @@ func (back *BlockReader) Header(ctx context.Context, tx kv.Getter, hash common.H
 
 func (back *BlockReader) Body(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (body *types.Body, err error) {
 	body, _, _ = rawdb.ReadBody(tx, hash, blockHeight)
+	if body == nil {
+		return back.frozenBody(tx, hash, blockHeight)
+	}
 	return body, nil
 }
 
 func (back *BlockReader) BodyWithTransactions(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (body *types.Body, err error) {
-	return rawdb.ReadBodyWithTransactions(tx, hash, blockHeight)
+	body, err = rawdb.ReadBodyWithTransactions(tx, hash, blockHeight)
+	if err != nil || body != nil {
+		return body, err
+	}
+	return back.frozenBody(tx, hash, blockHeight)
 }
 
 func (back *BlockReader) BodyRlp(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (bodyRlp rlp.RawValue, err error) {
@@ -73,6 +120,9 @@ func (back *BlockReader) BlockWithSenders(ctx context.Context, tx kv.Getter, has
 		if err != nil {
 			return nil, nil, err
 		}
+		if block == nil && back.freezer != nil {
+			return back.frozenBlockWithSenders(tx, hash, blockHeight)
+		}
 		return block, senders, nil
 	}
 
@@ -102,7 +152,17 @@ func (back *BlockReader) TxnByIdxInBlock(ctx context.Context, tx kv.Getter, bloc
 		return nil, err
 	}
 	if b == nil {
-		return nil, nil
+		if back.freezer == nil {
+			return nil, nil
+		}
+		body, err := rawdb.ReadFrozenBody(back.freezer, blockNum)
+		if err != nil || body == nil {
+			return nil, err
+		}
+		if i < 0 || i >= len(body.Transactions) {
+			return nil, nil
+		}
+		return body.Transactions[i], nil
 	}
 
 	txn, err = rawdb.CanonicalTxnByID(tx, b.BaseTxId+1+uint64(i))
@@ -112,6 +172,28 @@ func (back *BlockReader) TxnByIdxInBlock(ctx context.Context, tx kv.Getter, bloc
 	return txn, nil
 }
 
+// frozenBlockWithSenders - canonical block, header and senders of which are in the db, body - in the freezer
+func (back *BlockReader) frozenBlockWithSenders(tx kv.Getter, hash common.Hash, blockHeight uint64) (*types.Block, []common.Address, error) {
+	header := rawdb.ReadHeader(tx, hash, blockHeight)
+	if header == nil {
+		return nil, nil, nil
+	}
+	body, err := rawdb.ReadFrozenBody(back.freezer, blockHeight)
+	if err != nil || body == nil {
+		return nil, nil, err
+	}
+	senders, err := rawdb.ReadSenders(tx, hash, blockHeight)
+	if err != nil {
+		return nil, nil, err
+	}
+	block := types.NewBlockFromStorage(hash, header, body.Transactions, body.Uncles)
+	if len(senders) != len(body.Transactions) {
+		return block, senders, nil // no senders is fine - will recover them on the fly
+	}
+	block.SendersToTxs(senders)
+	return block, senders, nil
+}
+
 type RemoteBlockReader struct {
 	client remote.ETHBACKENDClient
 }
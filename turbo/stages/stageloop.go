@@ -16,12 +16,14 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/sentry/sentry"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/freezer"
 	"github.com/ledgerwatch/erigon/ethdb/olddb"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/p2p"
@@ -118,6 +120,7 @@ func StageLoopStep(
 	}() // avoid crash because Erigon's core does many things
 
 	var origin, finishProgressBefore uint64
+	var headBlockHashBefore common.Hash
 	if err := db.View(ctx, func(tx kv.Tx) error {
 		origin, err = stages.GetStageProgress(tx, stages.Headers)
 		if err != nil {
@@ -127,6 +130,7 @@ func StageLoopStep(
 		if err != nil {
 			return err
 		}
+		headBlockHashBefore = rawdb.ReadHeadBlockHash(tx)
 		return nil
 	}); err != nil {
 		return headBlockHash, err
@@ -193,6 +197,14 @@ func StageLoopStep(
 	}
 	updateHead(ctx, head, headHash, headTd256)
 
+	if notifications != nil && notifications.ChainHeadFeed != nil && headBlockHash != headBlockHashBefore {
+		if headNumber := rawdb.ReadHeaderNumber(rotx, headBlockHash); headNumber != nil {
+			if block := rawdb.ReadBlock(rotx, headBlockHash, *headNumber); block != nil {
+				notifications.ChainHeadFeed.Send(core.ChainHeadEvent{Block: block})
+			}
+		}
+	}
+
 	if notifications != nil && notifications.Accumulator != nil {
 		header := rawdb.ReadCurrentHeader(rotx)
 		if header != nil {
@@ -246,15 +258,25 @@ func NewStagedSync(
 	notifications *stagedsync.Notifications,
 	snapshotDownloader proto_downloader.DownloaderClient,
 	snapshots *snapshotsync.RoSnapshots,
+	freezer *freezer.Freezer,
 	headCh chan *types.Block,
 ) (*stagedsync.Sync, error) {
 	var blockReader services.FullBlockReader
 	if cfg.Snapshot.Enabled {
 		blockReader = snapshotsync.NewBlockReaderWithSnapshots(snapshots)
+	} else if freezer != nil {
+		blockReader = snapshotsync.NewBlockReaderWithFreezer(freezer)
 	} else {
 		blockReader = snapshotsync.NewBlockReader()
 	}
 	blockRetire := snapshotsync.NewBlockRetire(1, tmpdir, snapshots, db, snapshotDownloader, notifications.Events)
+	var trustedRoots *stagedsync.TrustedRootValidator
+	if cfg.Sync.TrustedRootValidationURL != "" {
+		var err error
+		if trustedRoots, err = stagedsync.NewTrustedRootValidator(cfg.Sync.TrustedRootValidationURL); err != nil {
+			return nil, err
+		}
+	}
 
 	// During Import we don't want other services like header requests, body requests etc. to be running.
 	// Hence we run it in the test mode.
@@ -278,7 +300,7 @@ func NewStagedSync(
 				blockReader,
 			),
 			stagedsync.StageIssuanceCfg(db, controlServer.ChainConfig, blockReader, cfg.EnabledIssuance),
-			stagedsync.StageSendersCfg(db, controlServer.ChainConfig, tmpdir, cfg.Prune, blockRetire),
+			stagedsync.StageSendersCfg(db, controlServer.ChainConfig, tmpdir, cfg.Prune, blockRetire, freezer, cfg.AncientKeepRecent),
 			stagedsync.StageExecuteBlocksCfg(
 				db,
 				cfg.Prune,
@@ -289,16 +311,17 @@ func NewStagedSync(
 				&vm.Config{EnableTEMV: cfg.Prune.Experiments.TEVM},
 				notifications.Accumulator,
 				cfg.StateStream,
+				cfg.TrackAccountVersions,
 				tmpdir,
 				blockReader,
 			),
 			stagedsync.StageTranspileCfg(db, cfg.BatchSize, controlServer.ChainConfig),
 			stagedsync.StageHashStateCfg(db, tmpdir),
-			stagedsync.StageTrieCfg(db, true, true, tmpdir, blockReader),
+			stagedsync.StageTrieCfg(db, true, true, tmpdir, blockReader, trustedRoots),
 			stagedsync.StageHistoryCfg(db, cfg.Prune, tmpdir),
 			stagedsync.StageLogIndexCfg(db, cfg.Prune, tmpdir),
 			stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, tmpdir),
-			stagedsync.StageTxLookupCfg(db, cfg.Prune, tmpdir, snapshots, isBor),
+			stagedsync.StageTxLookupCfg(db, cfg.Prune, tmpdir, snapshots, freezer, isBor),
 			stagedsync.StageFinishCfg(db, tmpdir, logger, headCh), runInTestMode),
 		stagedsync.DefaultUnwindOrder,
 		stagedsync.DefaultPruneOrder,
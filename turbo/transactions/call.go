@@ -24,6 +24,10 @@ import (
 
 const callTimeout = 5 * time.Minute
 
+// callJumpDestCache - JUMPDEST analysis shared by all DoCall executions, so binary search of eth_estimateGas
+// and repeated eth_call don't analyse the same code again
+var callJumpDestCache = vm.NewJumpDestCache(4096)
+
 func DoCall(
 	ctx context.Context,
 	args ethapi.CallArgs,
@@ -87,7 +91,7 @@ func DoCall(
 	}
 	blockCtx, txCtx := GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, tx, contractHasTEVM, headerReader)
 
-	evm := vm.NewEVM(blockCtx, txCtx, state, chainConfig, vm.Config{NoBaseFee: true})
+	evm := vm.NewEVM(blockCtx, txCtx, state, chainConfig, vm.Config{NoBaseFee: true, JumpDestCache: callJumpDestCache})
 
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
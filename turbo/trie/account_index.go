@@ -0,0 +1,61 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/ethdb"
+)
+
+// AccountIndex - in-memory set of existing accounts (by addrHash) under some prefix of HashedAccounts.
+// Keys are kept as sorted array: it's the most compact exact representation (32 bytes per account,
+// no per-node overhead) and sorted keys give the same answers as the prefix-trie of addrHashes
+type AccountIndex struct {
+	prefix    []byte
+	fixedbits int
+	keys      []common.Hash
+}
+
+// BuildAccountIndex - reads all accounts whose addrHash matches first `fixedbits` bits of `prefix`
+// (all accounts if fixedbits is 0). Values are not decoded
+func BuildAccountIndex(tx kv.Tx, prefix []byte, fixedbits int) (*AccountIndex, error) {
+	if fixedbits < 0 || len(prefix)*8 < fixedbits {
+		return nil, fmt.Errorf("BuildAccountIndex: %d fixed bits of %d-byte prefix", fixedbits, len(prefix))
+	}
+	c, err := tx.Cursor(kv.HashedAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	idx := &AccountIndex{prefix: common.CopyBytes(prefix), fixedbits: fixedbits}
+	if err := ethdb.Walk(c, prefix, fixedbits, func(k, _ []byte) (bool, error) {
+		idx.keys = append(idx.keys, common.BytesToHash(k))
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Has - whether the account exists. Accounts outside of the index prefix are reported as not existing
+func (idx *AccountIndex) Has(addrHash common.Hash) bool {
+	i := sort.Search(len(idx.keys), func(i int) bool { return bytes.Compare(idx.keys[i][:], addrHash[:]) >= 0 })
+	return i < len(idx.keys) && idx.keys[i] == addrHash
+}
+
+// Covers - whether the account is under the index prefix, so Has is meaningful for it
+func (idx *AccountIndex) Covers(addrHash common.Hash) bool {
+	fixedbytes, mask := ethdb.Bytesmask(idx.fixedbits)
+	if fixedbytes == 0 {
+		return true
+	}
+	return bytes.Equal(addrHash[:fixedbytes-1], idx.prefix[:fixedbytes-1]) && addrHash[fixedbytes-1]&mask == idx.prefix[fixedbytes-1]&mask
+}
+
+// Len - number of accounts in the index
+func (idx *AccountIndex) Len() int {
+	return len(idx.keys)
+}
@@ -0,0 +1,55 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAccountIndex(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	acc := accounts.NewAccount()
+	encoded := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(encoded)
+	hashes := []common.Hash{
+		common.HexToHash("0x1000000000000000000000000000000000000000000000000000000000000000"),
+		common.HexToHash("0xB000000000000000000000000000000000000000000000000000000000000000"),
+		common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000"),
+		common.HexToHash("0xC000000000000000000000000000000000000000000000000000000000000000"),
+	}
+	for _, h := range hashes {
+		require.NoError(t, tx.Put(kv.HashedAccounts, h[:], encoded))
+	}
+	missing := common.HexToHash("0xB040000000000000000000000000000000000000000000000000000000000000")
+
+	all, err := BuildAccountIndex(tx, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, len(hashes), all.Len())
+	for _, h := range hashes {
+		require.True(t, all.Has(h))
+		require.True(t, all.Covers(h))
+	}
+	require.False(t, all.Has(missing))
+
+	// first nibble is B
+	idx, err := BuildAccountIndex(tx, []byte{0xB0}, 4)
+	require.NoError(t, err)
+	require.Equal(t, 2, idx.Len())
+	require.True(t, idx.Has(hashes[1]))
+	require.True(t, idx.Has(hashes[2]))
+	require.False(t, idx.Has(missing))
+	require.True(t, idx.Covers(missing))
+	require.False(t, idx.Has(hashes[0]))
+	require.False(t, idx.Covers(hashes[0]))
+	require.False(t, idx.Covers(hashes[3]))
+
+	// prefix shorter than fixed bits
+	_, err = BuildAccountIndex(tx, nil, 8)
+	require.Error(t, err)
+	_, err = BuildAccountIndex(tx, []byte{0xB0}, 9)
+	require.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package trie
+
+import (
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+// FlatDBStreamIterator - StreamIterator over the hashed state: accounts of HashedAccounts, each followed by its storage
+// from HashedStorage, in the order of trie keys. Keys are hex (one nibble per byte, without terminator): 64 nibbles
+// for an account, 144 (account, incarnation, location) for a storage item.
+// It walks only the flat state - intermediate hashes are not used, so AHashStreamItem and SHashStreamItem are never
+// returned. Returned slices and account are valid until the next call of Next.
+// Next returns NoItem at the end of the state or on error, check Err after the iteration
+type FlatDBStreamIterator struct {
+	accounts kv.Cursor
+	storage  kv.CursorDupSort
+
+	started    bool
+	inStorage  bool // storage of the last returned account is not iterated yet or partially iterated
+	storageAt  bool // storage cursor is positioned at storage of the last returned account
+	accWithInc []byte
+	prefixHex  []byte // hex of accWithInc
+	hex        []byte
+	acc        accounts.Account
+	err        error
+}
+
+func NewFlatDBStreamIterator(tx kv.Tx) (*FlatDBStreamIterator, error) {
+	accs, err := tx.Cursor(kv.HashedAccounts)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := tx.CursorDupSort(kv.HashedStorage)
+	if err != nil {
+		accs.Close()
+		return nil, err
+	}
+	return &FlatDBStreamIterator{accounts: accs, storage: storage}, nil
+}
+
+// Next delivers the next item of the state
+func (it *FlatDBStreamIterator) Next() (itemType StreamItem, hex1 []byte, aValue *accounts.Account, hash []byte, value []byte) {
+	if it.err != nil {
+		return NoItem, nil, nil, nil, nil
+	}
+	if it.inStorage {
+		var v []byte
+		if it.storageAt {
+			_, v, it.err = it.storage.NextDup()
+		} else {
+			v, it.err = it.storage.SeekBothRange(it.accWithInc, nil)
+			it.storageAt = true
+		}
+		if it.err != nil {
+			return NoItem, nil, nil, nil, nil
+		}
+		if v != nil {
+			it.hex = append(it.hex[:0], it.prefixHex...)
+			for _, b := range v[:32] {
+				it.hex = append(it.hex, b>>4, b&0x0F)
+			}
+			return StorageStreamItem, it.hex, nil, nil, v[32:]
+		}
+		it.inStorage = false
+	}
+
+	var k, v []byte
+	if it.started {
+		k, v, it.err = it.accounts.Next()
+	} else {
+		k, v, it.err = it.accounts.First()
+		it.started = true
+	}
+	if it.err != nil || k == nil {
+		return NoItem, nil, nil, nil, nil
+	}
+	it.acc.Reset()
+	if it.err = it.acc.DecodeForStorage(v); it.err != nil {
+		return NoItem, nil, nil, nil, nil
+	}
+	hexutil.DecompressNibbles(k, &it.hex)
+	if it.acc.Incarnation > 0 {
+		it.accWithInc = dbutils.GenerateStoragePrefix(k, it.acc.Incarnation)
+		hexutil.DecompressNibbles(it.accWithInc, &it.prefixHex)
+		it.inStorage, it.storageAt = true, false
+	}
+	return AccountStreamItem, it.hex, &it.acc, nil, nil
+}
+
+// Err - error which stopped the iteration, if any
+func (it *FlatDBStreamIterator) Err() error {
+	return it.err
+}
+
+func (it *FlatDBStreamIterator) Close() {
+	it.accounts.Close()
+	it.storage.Close()
+}
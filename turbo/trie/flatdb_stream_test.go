@@ -0,0 +1,76 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatDBStreamIterator(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	putAccount := func(addrHash common.Hash, balance, incarnation uint64) {
+		acc := accounts.NewAccount()
+		acc.Balance.SetUint64(balance)
+		acc.Incarnation = incarnation
+		encoded := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(encoded)
+		require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+	}
+	putStorage := func(addrHash common.Hash, incarnation uint64, loc common.Hash, value uint64) {
+		k := dbutils.GenerateCompositeStorageKey(addrHash, incarnation, loc)
+		require.NoError(t, tx.Put(kv.HashedStorage, k, uint256.NewInt(value).Bytes()))
+	}
+	a1, a2, a3 := common.Hash{0x01}, common.Hash{0x02}, common.Hash{0x03}
+	putAccount(a1, 1, 0)
+	putAccount(a2, 2, 1)
+	putStorage(a2, 1, common.Hash{0x20}, 5)
+	putStorage(a2, 1, common.Hash{0x10}, 4)
+	putAccount(a3, 3, 2)
+	putStorage(a3, 1, common.Hash{0x30}, 6) // storage of previous incarnation
+	putStorage(a3, 2, common.Hash{0x40}, 7)
+
+	type item struct {
+		itemType StreamItem
+		hex      []byte
+		balance  uint64
+		value    []byte
+	}
+	hex := func(b ...[]byte) []byte {
+		var res []byte
+		for _, part := range b {
+			res = append(res, keybytesToHex(part)[:2*len(part)]...)
+		}
+		return res
+	}
+	inc := func(incarnation uint64) []byte { return dbutils.EncodeBlockNumber(incarnation) }
+	expected := []item{
+		{AccountStreamItem, hex(a1[:]), 1, nil},
+		{AccountStreamItem, hex(a2[:]), 2, nil},
+		{StorageStreamItem, hex(a2[:], inc(1), []byte{0x10}, make([]byte, 31)), 0, []byte{4}},
+		{StorageStreamItem, hex(a2[:], inc(1), []byte{0x20}, make([]byte, 31)), 0, []byte{5}},
+		{AccountStreamItem, hex(a3[:]), 3, nil},
+		{StorageStreamItem, hex(a3[:], inc(2), []byte{0x40}, make([]byte, 31)), 0, []byte{7}},
+	}
+
+	it, err := NewFlatDBStreamIterator(tx)
+	require.NoError(t, err)
+	defer it.Close()
+	var got []item
+	for itemType, hex1, aValue, _, value := it.Next(); itemType != NoItem; itemType, hex1, aValue, _, value = it.Next() {
+		i := item{itemType: itemType, hex: common.CopyBytes(hex1), value: common.CopyBytes(value)}
+		if aValue != nil {
+			i.balance = aValue.Balance.Uint64()
+		}
+		got = append(got, i)
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, expected, got)
+	itemType, _, _, _, _ := it.Next()
+	require.Equal(t, NoItem, itemType)
+}
@@ -17,6 +17,7 @@
 package trie
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/holiman/uint256"
@@ -50,6 +51,13 @@ type StorageHashCollector func(accWithInc []byte, keyHex []byte, hash []byte) er
 type HashCollector2 func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error
 type StorageHashCollector2 func(accWithInc []byte, keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error
 
+// ErrTrieDepthExceeded - GenStructStep got more prefix groups than the deepest supported key can produce
+var ErrTrieDepthExceeded = errors.New("trie depth exceeded")
+
+// maxGenStructStepDepth - nibbles of the longest keys fed to GenStructStep: account hash, incarnation and storage
+// key hash (embedded storage), with terminator
+const maxGenStructStepDepth = 4*common.HashLength + 2*common.IncarnationLength + 1
+
 func calcPrecLen(groups []uint16) int {
 	if len(groups) == 0 {
 		return 0
@@ -110,6 +118,9 @@ func GenStructStep(
 	hasHash []uint16,
 	trace bool,
 ) ([]uint16, []uint16, []uint16, error) {
+	if len(groups) > maxGenStructStepDepth || len(curr) > maxGenStructStepDepth {
+		return nil, nil, nil, fmt.Errorf("%w: %d groups, key %x", ErrTrieDepthExceeded, len(groups), curr)
+	}
 	for precLen, buildExtensions := calcPrecLen(groups), false; precLen >= 0; precLen, buildExtensions = calcPrecLen(groups), true {
 		var precExists = len(groups) > 0
 		// Calculate the prefix of the smallest prefix group containing curr
@@ -124,9 +135,16 @@ func GenStructStep(
 		} else {
 			maxLen = succLen
 		}
-		if trace || maxLen >= len(curr) {
+		if trace {
 			fmt.Printf("curr: %x, succ: %x, maxLen %d, groups: %b, precLen: %d, succLen: %d, buildExtensions: %t\n", curr, succ, maxLen, groups, precLen, succLen, buildExtensions)
 		}
+		// keys are not in strict ascending order, one is a prefix of another (e.g. duplicates), or not nibbles
+		if maxLen >= len(curr) {
+			return nil, nil, nil, fmt.Errorf("malformed keys: curr %x, succ %x, groups %b", curr, succ, groups)
+		}
+		if curr[maxLen] > 15 {
+			return nil, nil, nil, fmt.Errorf("malformed keys: curr %x has no nibble at %d, succ %x", curr, maxLen, succ)
+		}
 
 		// Add the digit immediately following the max common prefix and compute length of remainder length
 		extraDigit := curr[maxLen]
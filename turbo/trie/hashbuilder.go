@@ -39,6 +39,7 @@ type HashBuilder struct {
 	trace     bool // Set to true when HashBuilder is required to print trace information for diagnostics
 
 	topHashesCopy []byte
+	nodeSizes     *NodeSizeHistogram // optional
 }
 
 // NewHashBuilder creates a new HashBuilder
@@ -50,6 +51,12 @@ func NewHashBuilder(trace bool) *HashBuilder {
 	}
 }
 
+// SetNodeSizeHistogram - makes HashBuilder record RLP size of every node it hashes (or embeds) into `h`.
+// nil disables recording
+func (hb *HashBuilder) SetNodeSizeHistogram(h *NodeSizeHistogram) {
+	hb.nodeSizes = h
+}
+
 // Reset makes the HashBuilder suitable for reuse
 func (hb *HashBuilder) Reset() {
 	if len(hb.hashStack) > 0 {
@@ -133,6 +140,9 @@ func (hb *HashBuilder) leafHashWithKeyVal(key []byte, val rlphacks.RlpSerializab
 func (hb *HashBuilder) completeLeafHash(kp, kl, compactLen int, key []byte, compact0 byte, ni int, val rlphacks.RlpSerializable) error {
 	totalLen := kp + kl + val.DoubleRLPLen()
 	pt := rlphacks.GenerateStructLen(hb.lenPrefix[:], totalLen)
+	if hb.nodeSizes != nil {
+		hb.nodeSizes.add(totalLen + pt)
+	}
 
 	var writer io.Writer
 	var reader io.Reader
@@ -396,6 +406,9 @@ func (hb *HashBuilder) extensionHash(key []byte) error {
 	}
 	totalLen := kp + kl + 33
 	pt := rlphacks.GenerateStructLen(hb.lenPrefix[:], totalLen)
+	if hb.nodeSizes != nil {
+		hb.nodeSizes.add(totalLen + pt)
+	}
 	hb.sha.Reset()
 	if _, err := hb.sha.Write(hb.lenPrefix[:pt]); err != nil {
 		return err
@@ -494,6 +507,9 @@ func (hb *HashBuilder) branchHash(set uint16) error {
 	}
 	hb.sha.Reset()
 	pt := rlphacks.GenerateStructLen(hb.lenPrefix[:], totalSize)
+	if hb.nodeSizes != nil {
+		hb.nodeSizes.add(totalSize + pt)
+	}
 	if _, err := hb.sha.Write(hb.lenPrefix[:pt]); err != nil {
 		return err
 	}
@@ -548,6 +564,9 @@ func (hb *HashBuilder) hash(hash []byte) error {
 	if hb.trace {
 		fmt.Printf("HASH\n")
 	}
+	if len(hash) != common.HashLength { // otherwise prefix pushed below doesn't match the length and corrupts hashStack
+		return fmt.Errorf("hash should be %d bytes long, got %d", common.HashLength, len(hash))
+	}
 	hb.hashStack = append(hb.hashStack, 0x80+common.HashLength)
 	hb.hashStack = append(hb.hashStack, hash...)
 	hb.nodeStack = append(hb.nodeStack, nil)
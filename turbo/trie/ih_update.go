@@ -0,0 +1,126 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+)
+
+func assertSubset(a, b uint16) {
+	if (a & b) != a { // a & b == a - checks whether a is subset of b
+		panic(fmt.Errorf("invariant 'is subset' failed: %b, %b", a, b))
+	}
+}
+
+// AccountTrieCollector - HashCollector2 which collects TrieOfAccounts updates into `collector`,
+// they are applied by collector.Load(tx, kv.TrieOfAccounts, etl.IdentityLoadFunc, ...)
+func AccountTrieCollector(collector *etl.Collector) HashCollector2 {
+	newV := make([]byte, 0, 1024)
+	return func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, _ []byte) error {
+		if len(keyHex) == 0 {
+			return nil
+		}
+		if hasState == 0 {
+			return collector.Collect(keyHex, nil)
+		}
+		if bits.OnesCount16(hasHash) != len(hashes)/length.Hash {
+			panic(fmt.Errorf("invariant bits.OnesCount16(hasHash) == len(hashes) failed: %d, %d", bits.OnesCount16(hasHash), len(hashes)/length.Hash))
+		}
+		assertSubset(hasTree, hasState)
+		assertSubset(hasHash, hasState)
+		newV = MarshalTrieNode(hasState, hasTree, hasHash, hashes, nil, newV)
+		return collector.Collect(keyHex, newV)
+	}
+}
+
+// StorageTrieCollector - StorageHashCollector2 which collects TrieOfStorage updates into `collector`,
+// see AccountTrieCollector
+func StorageTrieCollector(collector *etl.Collector) StorageHashCollector2 {
+	newK := make([]byte, 0, 128)
+	newV := make([]byte, 0, 1024)
+	return func(accWithInc []byte, keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+		newK = append(append(newK[:0], accWithInc...), keyHex...)
+		if hasState == 0 {
+			return collector.Collect(newK, nil)
+		}
+		if len(keyHex) > 0 && hasHash == 0 && hasTree == 0 {
+			return nil
+		}
+		if bits.OnesCount16(hasHash) != len(hashes)/length.Hash {
+			panic(fmt.Errorf("invariant bits.OnesCount16(hasHash) == len(hashes) failed: %d, %d", bits.OnesCount16(hasHash), len(hashes)/length.Hash))
+		}
+		assertSubset(hasTree, hasState)
+		assertSubset(hasHash, hasState)
+		newV = MarshalTrieNode(hasState, hasTree, hasHash, hashes, rootHash, newV)
+		return collector.Collect(newK, newV)
+	}
+}
+
+// IHUpdate - keys of hashed state changed since intermediate hashes were last updated, see UpdateIntermediateHashes
+type IHUpdate struct {
+	rl              *RetainList
+	deletedAccounts [][]byte
+}
+
+func NewIHUpdate() *IHUpdate {
+	return &IHUpdate{rl: NewRetainList(0)}
+}
+
+// AddAccount - account was changed, `created` - it didn't exist before the changes
+func (u *IHUpdate) AddAccount(addrHash common.Hash, created bool) {
+	u.rl.AddKeyWithMarker(addrHash[:], created)
+}
+
+// DeleteStorage - storage of all incarnations of the account was dropped (self-destruct or incarnation went down),
+// its storage intermediate hashes will be removed. The account itself must be added by AddAccount as well
+func (u *IHUpdate) DeleteStorage(addrHash common.Hash) {
+	u.deletedAccounts = append(u.deletedAccounts, common.CopyBytes(addrHash[:]))
+}
+
+// AddStorage - storage slot was changed, `created` - it was empty before the changes
+func (u *IHUpdate) AddStorage(addrHash common.Hash, incarnation uint64, locHash common.Hash, created bool) {
+	u.rl.AddKeyWithMarker(dbutils.GenerateCompositeStorageKey(addrHash, incarnation, locHash), created)
+}
+
+// UpdateIntermediateHashes - calculates state root after the changes in `u` and updates only TrieOfAccounts and
+// TrieOfStorage entries on the paths of changed keys, the rest of intermediate hashes is used as is. It's what
+// IntermediateHashes stage does incrementally, for callers which know changed keys (for example of one block at the
+// chain tip). HashedAccounts and HashedStorage must already contain the changes
+func UpdateIntermediateHashes(logPrefix string, tx kv.RwTx, u *IHUpdate, tmpdir string, quit <-chan struct{}) (common.Hash, error) {
+	sort.Slice(u.deletedAccounts, func(i, j int) bool { return bytes.Compare(u.deletedAccounts[i], u.deletedAccounts[j]) < 0 })
+	for _, addrHash := range u.deletedAccounts {
+		if err := tx.ForPrefix(kv.TrieOfStorage, addrHash, func(k, v []byte) error {
+			return tx.Delete(kv.TrieOfStorage, k, v)
+		}); err != nil {
+			return EmptyRoot, err
+		}
+	}
+
+	accTrieCollector := etl.NewCollector(logPrefix, tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer accTrieCollector.Close()
+	stTrieCollector := etl.NewCollector(logPrefix, tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer stTrieCollector.Close()
+
+	loader := NewFlatDBTrieLoader(logPrefix)
+	if err := loader.Reset(u.rl, AccountTrieCollector(accTrieCollector), StorageTrieCollector(stTrieCollector), false); err != nil {
+		return EmptyRoot, err
+	}
+	hash, err := loader.CalcTrieRoot(tx, []byte{}, quit)
+	if err != nil {
+		return EmptyRoot, err
+	}
+	if err := accTrieCollector.Load(tx, kv.TrieOfAccounts, etl.IdentityLoadFunc, etl.TransformArgs{Quit: quit}); err != nil {
+		return EmptyRoot, err
+	}
+	if err := stTrieCollector.Load(tx, kv.TrieOfStorage, etl.IdentityLoadFunc, etl.TransformArgs{Quit: quit}); err != nil {
+		return EmptyRoot, err
+	}
+	return hash, nil
+}
@@ -0,0 +1,49 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// AuditIncarnations - distinct incarnations present in HashedStorage for the account, in ascending order.
+// HashedStorage is DupSort with addrHash+incarnation keys, so it's one seek per incarnation, not per slot.
+// Storage of the previous incarnations may legitimately remain in the db, but skipped numbers (see MissingIncarnations)
+// mean the storage was written for an incarnation which the account never had
+func AuditIncarnations(tx kv.Tx, addrHash common.Hash) ([]uint64, error) {
+	c, err := tx.CursorDupSort(kv.HashedStorage)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var incarnations []uint64
+	for k, _, err := c.Seek(addrHash[:]); k != nil; k, _, err = c.NextNoDup() {
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(k, addrHash[:]) {
+			break
+		}
+		incarnations = append(incarnations, binary.BigEndian.Uint64(k[common.HashLength:]))
+	}
+	return incarnations, nil
+}
+
+// IncarnationGap - range [From, To) of incarnations skipped between two present ones
+type IncarnationGap struct {
+	From, To uint64
+}
+
+// MissingIncarnations - ranges skipped in the ascending sequence of incarnations returned by AuditIncarnations.
+// Ranges, not numbers: one corrupted huge incarnation would make a list of numbers as long as itself
+func MissingIncarnations(incarnations []uint64) []IncarnationGap {
+	var missing []IncarnationGap
+	for i := 1; i < len(incarnations); i++ {
+		if incarnations[i] > incarnations[i-1]+1 {
+			missing = append(missing, IncarnationGap{From: incarnations[i-1] + 1, To: incarnations[i]})
+		}
+	}
+	return missing
+}
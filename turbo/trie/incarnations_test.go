@@ -0,0 +1,34 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditIncarnations(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	addrHash := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	next := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000001")
+	for _, inc := range []uint64{1, 3, 6} {
+		for i := byte(0); i < 3; i++ {
+			require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(addrHash, inc, common.Hash{i}), []byte{i + 1}))
+		}
+	}
+	require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(next, 2, common.Hash{}), []byte{1}))
+
+	incarnations, err := AuditIncarnations(tx, addrHash)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 3, 6}, incarnations)
+	require.Equal(t, []IncarnationGap{{2, 3}, {4, 6}}, MissingIncarnations(incarnations))
+	require.Equal(t, []IncarnationGap{{2, 1 << 62}}, MissingIncarnations([]uint64{1, 1 << 62}))
+
+	incarnations, err = AuditIncarnations(tx, common.Hash{})
+	require.NoError(t, err)
+	require.Empty(t, incarnations)
+	require.Empty(t, MissingIncarnations(incarnations))
+}
@@ -0,0 +1,79 @@
+package trie
+
+import "math/bits"
+
+// maxTrackedNodeSize - branch node with 16 hashes is 532 bytes, leaves with long values may be larger
+const maxTrackedNodeSize = 1024
+
+// NodeSizeHistogram - distribution of RLP sizes of trie nodes (leaves, account leaves, extensions and branches,
+// including embedded ones) produced by HashBuilder, see HashBuilder.SetNodeSizeHistogram
+type NodeSizeHistogram struct {
+	counts [maxTrackedNodeSize + 1]uint64 // by size in bytes, last one is for all larger nodes
+	total  uint64
+}
+
+// NodeSizeStats - see NodeSizeHistogram.HistogramStats
+type NodeSizeStats struct {
+	Count   uint64         `json:"count"`
+	P50     int            `json:"p50"`
+	P90     int            `json:"p90"`
+	P99     int            `json:"p99"`
+	Buckets map[int]uint64 `json:"buckets"` // upper bound (power of 2, inclusive) -> amount of nodes
+}
+
+func (h *NodeSizeHistogram) add(size int) {
+	if size > maxTrackedNodeSize {
+		size = maxTrackedNodeSize
+	}
+	h.counts[size]++
+	h.total++
+}
+
+func (h *NodeSizeHistogram) Reset() {
+	*h = NodeSizeHistogram{}
+}
+
+func (h *NodeSizeHistogram) Count() uint64 {
+	return h.total
+}
+
+// Percentile - smallest size which is not less than sizes of `p` (0..1) part of nodes.
+// Nodes larger than maxTrackedNodeSize are counted as maxTrackedNodeSize
+func (h *NodeSizeHistogram) Percentile(p float64) int {
+	if h.total == 0 {
+		return 0
+	}
+	need := uint64(p * float64(h.total))
+	if need == 0 {
+		need = 1
+	}
+	var seen uint64
+	for size, n := range h.counts {
+		seen += n
+		if seen >= need {
+			return size
+		}
+	}
+	return maxTrackedNodeSize
+}
+
+func (h *NodeSizeHistogram) HistogramStats() NodeSizeStats {
+	stats := NodeSizeStats{
+		Count:   h.total,
+		P50:     h.Percentile(0.5),
+		P90:     h.Percentile(0.9),
+		P99:     h.Percentile(0.99),
+		Buckets: map[int]uint64{},
+	}
+	for size, n := range h.counts {
+		if n == 0 {
+			continue
+		}
+		upper := 1
+		if size > 1 {
+			upper = 1 << bits.Len(uint(size-1))
+		}
+		stats.Buckets[upper] += n
+	}
+	return stats
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
 )
 
 // Prove constructs a merkle proof for key. The result contains all encoded nodes
@@ -15,6 +17,18 @@ import (
 // nodes of the longest existing prefix of the key (at least the root node), ending
 // with the node that proves the absence of the key.
 func (t *Trie) Prove(key []byte, fromLevel int, storage bool) ([][]byte, error) {
+	return t.prove(key, fromLevel, storage, false)
+}
+
+// ProveWithoutValue - same as Prove, but the value of the storage leaf at key is replaced by empty placeholder,
+// for clients which already have the value and need only the path. The verifier must supply the value back,
+// see RestoreProofValue, otherwise hashes don't match. Account leaves and proofs of absence are not changed.
+// Leaves shorter than 32 bytes are embedded into their parents, so their values are still in the proof
+func (t *Trie) ProveWithoutValue(key []byte, fromLevel int, storage bool) ([][]byte, error) {
+	return t.prove(key, fromLevel, storage, true)
+}
+
+func (t *Trie) prove(key []byte, fromLevel int, storage bool, omitValue bool) ([][]byte, error) {
 	var proof [][]byte
 	hasher := newHasher(false)
 	defer returnHasherToPool(hasher)
@@ -25,17 +39,24 @@ func (t *Trie) Prove(key []byte, fromLevel int, storage bool) ([][]byte, error)
 	for len(key) > 0 && tn != nil {
 		switch n := tn.(type) {
 		case *shortNode:
-			if fromLevel == 0 {
+			nKey := n.Key
+			if nKey[len(nKey)-1] == 16 {
+				nKey = nKey[:len(nKey)-1]
+			}
+			if _, isValue := n.Val.(valueNode); fromLevel == 0 && isValue && omitValue && bytes.Equal(nKey, key) {
+				// leaf with empty value as placeholder, real values of storage are never empty
+				leaf, err := rlp.EncodeToBytes([][]byte{hexToCompact(n.Key), nil})
+				if err != nil {
+					return nil, err
+				}
+				proof = append(proof, leaf)
+			} else if fromLevel == 0 {
 				if rlp, err := hasher.hashChildren(n, 0); err == nil {
 					proof = append(proof, common.CopyBytes(rlp))
 				} else {
 					return nil, err
 				}
 			}
-			nKey := n.Key
-			if nKey[len(nKey)-1] == 16 {
-				nKey = nKey[:len(nKey)-1]
-			}
 			if len(key) < len(nKey) || !bytes.Equal(nKey, key[:len(nKey)]) {
 				// The trie doesn't contain the key.
 				tn = nil
@@ -95,3 +116,206 @@ func (t *Trie) Prove(key []byte, fromLevel int, storage bool) ([][]byte, error)
 	}
 	return proof, nil
 }
+
+// RestoreProofValue - puts `value` of the storage leaf back into the last node of the proof made by
+// ProveWithoutValue, so the proof can be verified as usual. Returns a new proof, `proof` is not modified
+func RestoreProofValue(proof [][]byte, value []byte) ([][]byte, error) {
+	if len(proof) == 0 {
+		return nil, fmt.Errorf("empty proof")
+	}
+	content, _, err := rlp.SplitList(proof[len(proof)-1])
+	if err != nil {
+		return nil, err
+	}
+	if count, err := rlp.CountValues(content); err != nil || count != 2 { // not a leaf/extension node
+		return nil, fmt.Errorf("last node of the proof is not a leaf without value")
+	}
+	compactKey, rest, err := rlp.SplitString(content)
+	if err != nil {
+		return nil, err
+	}
+	placeholder, _, err := rlp.SplitString(rest)
+	if err != nil || len(placeholder) != 0 {
+		return nil, fmt.Errorf("last node of the proof is not a leaf without value")
+	}
+	hasher := newHasher(false)
+	defer returnHasherToPool(hasher)
+	leaf, err := hasher.hashChildren(&shortNode{Key: compactToHex(compactKey), Val: valueNode(value)}, 0)
+	if err != nil {
+		return nil, err
+	}
+	restored := make([][]byte, len(proof))
+	copy(restored, proof)
+	restored[len(restored)-1] = common.CopyBytes(leaf)
+	return restored, nil
+}
+
+// ProofNodeSet collects RLP of all nodes of the selected sub-tries (indices in Hashes), keyed by node hash,
+// so nodes shared by several proofs are sent only once. Nodes shorter than 32 bytes are embedded into their
+// parents and aren't included, except roots of sub-tries and of storage tries, which are always referenced by hash
+func (st SubTries) ProofNodeSet(prefixes []int) (map[common.Hash][]byte, error) {
+	hasher := newHasher(false)
+	defer returnHasherToPool(hasher)
+	set := make(map[common.Hash][]byte)
+	for _, i := range prefixes {
+		if i < 0 || i >= len(st.roots) {
+			return nil, fmt.Errorf("sub-trie %d is out of range, have %d", i, len(st.roots))
+		}
+		if err := collectProofNodes(hasher, st.roots[i], true, set); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func collectProofNodes(hasher *hasher, nd node, force bool, set map[common.Hash][]byte) error {
+	switch n := nd.(type) {
+	case nil, hashNode, valueNode:
+		return nil
+	case *accountNode:
+		// account is the value of the leaf, not a separate node
+		return collectProofNodes(hasher, n.storage, true, set)
+	case *shortNode:
+		if err := collectProofNodes(hasher, n.Val, false, set); err != nil {
+			return err
+		}
+	case *duoNode:
+		if err := collectProofNodes(hasher, n.child1, false, set); err != nil {
+			return err
+		}
+		if err := collectProofNodes(hasher, n.child2, false, set); err != nil {
+			return err
+		}
+	case *fullNode:
+		for _, child := range n.Children {
+			if err := collectProofNodes(hasher, child, false, set); err != nil {
+				return err
+			}
+		}
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", nd, nd))
+	}
+	rlp, err := hasher.hashChildren(nd, 0)
+	if err != nil {
+		return err
+	}
+	if len(rlp) < common.HashLength && !force {
+		return nil
+	}
+	set[crypto.Keccak256Hash(rlp)] = common.CopyBytes(rlp)
+	return nil
+}
+
+// CompressedStorageProof - merkle proofs of several storage slots of one account, where nodes shared with the proof
+// of the previous slot are not repeated. Proofs of keys sorted by hash share the most nodes: for adjacent keys
+// only few last nodes differ. Fields are RLP-friendly, see DecompressStorageProof for decoding
+type CompressedStorageProof struct {
+	Keys  []common.Hash // Hashed keys, in the order of Steps
+	Steps []CompressedProofStep
+}
+
+// CompressedProofStep - proof of one key as a delta from the proof of the previous key
+type CompressedProofStep struct {
+	Shared uint64   // Amount of first nodes of the previous proof, which are also the first nodes of this proof
+	Nodes  [][]byte // RLP of the rest of the nodes of this proof
+}
+
+// CompressedStorageProof - proofs of hashed storage `keys` of the account `addrHash`, from its storage root, in the
+// form of CompressedStorageProof. Account and its storage on the paths to the keys must be loaded into one of the
+// sub-tries. Sub-tries don't keep their prefixes, so the account is looked up at every depth of every sub-trie
+func (st SubTries) CompressedStorageProof(addrHash common.Hash, keys []common.Hash) (*CompressedStorageProof, error) {
+	acc := st.findAccount(addrHash)
+	if acc == nil {
+		return nil, fmt.Errorf("account %x is not found in sub-tries", addrHash)
+	}
+	storage := &Trie{root: acc.storage}
+	result := &CompressedStorageProof{Keys: keys, Steps: make([]CompressedProofStep, len(keys))}
+	var prev [][]byte
+	for i, key := range keys {
+		proof, err := storage.Prove(key[:], 0, false)
+		if err != nil {
+			return nil, fmt.Errorf("proof of key %x of account %x: %w", key, addrHash, err)
+		}
+		shared := 0
+		for shared < len(prev) && shared < len(proof) && bytes.Equal(prev[shared], proof[shared]) {
+			shared++
+		}
+		result.Steps[i] = CompressedProofStep{Shared: uint64(shared), Nodes: proof[shared:]}
+		prev = proof
+	}
+	return result, nil
+}
+
+// DecompressStorageProof - restores full proof of every key of CompressedStorageProof, in the order of its Keys
+func DecompressStorageProof(p *CompressedStorageProof) ([][][]byte, error) {
+	if len(p.Keys) != len(p.Steps) {
+		return nil, fmt.Errorf("compressed proof is inconsistent: %d keys, %d steps", len(p.Keys), len(p.Steps))
+	}
+	proofs := make([][][]byte, len(p.Steps))
+	var prev [][]byte
+	for i, step := range p.Steps {
+		if step.Shared > uint64(len(prev)) {
+			return nil, fmt.Errorf("proof of key %x shares %d nodes, previous proof has only %d", p.Keys[i], step.Shared, len(prev))
+		}
+		proof := make([][]byte, 0, int(step.Shared)+len(step.Nodes))
+		proof = append(append(proof, prev[:step.Shared]...), step.Nodes...)
+		proofs[i] = proof
+		prev = proof
+	}
+	return proofs, nil
+}
+
+func (st SubTries) findAccount(addrHash common.Hash) *accountNode {
+	hex := keybytesToHex(addrHash[:])
+	hex = hex[:len(hex)-1] // Remove terminator
+	for _, root := range st.roots {
+		for depth := 0; depth < len(hex); depth++ {
+			if acc := findAccountNode(root, hex[depth:]); acc != nil {
+				return acc
+			}
+		}
+	}
+	return nil
+}
+
+func findAccountNode(nd node, hex []byte) *accountNode {
+	for {
+		switch n := nd.(type) {
+		case *accountNode:
+			if len(hex) != 0 {
+				return nil
+			}
+			return n
+		case *shortNode:
+			nKey := n.Key
+			if len(nKey) > 0 && nKey[len(nKey)-1] == 16 {
+				nKey = nKey[:len(nKey)-1]
+			}
+			if !bytes.HasPrefix(hex, nKey) {
+				return nil
+			}
+			nd, hex = n.Val, hex[len(nKey):]
+		case *duoNode:
+			if len(hex) == 0 {
+				return nil
+			}
+			i1, i2 := n.childrenIdx()
+			switch hex[0] {
+			case i1:
+				nd = n.child1
+			case i2:
+				nd = n.child2
+			default:
+				return nil
+			}
+			hex = hex[1:]
+		case *fullNode:
+			if len(hex) == 0 {
+				return nil
+			}
+			nd, hex = n.Children[hex[0]], hex[1:]
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package trie
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofNodeSet(t *testing.T) {
+	tr := New(EmptyRoot)
+	var keys [][]byte
+	for i := 0; i < 50; i++ {
+		key := crypto.Keccak256([]byte{byte(i)})
+		keys = append(keys, key)
+		tr.Update(key, common.LeftPadBytes([]byte{byte(i)}, 32))
+	}
+	root := tr.Hash()
+	st := SubTries{Hashes: []common.Hash{root, root}, roots: []node{tr.root, tr.root}}
+
+	set, err := st.ProofNodeSet([]int{0})
+	require.NoError(t, err)
+	require.Contains(t, set, root)
+	for _, key := range keys {
+		proof, err := tr.Prove(key, 0, false)
+		require.NoError(t, err)
+		for _, n := range proof {
+			if len(n) < common.HashLength {
+				continue
+			}
+			require.Equal(t, n, set[crypto.Keccak256Hash(n)])
+		}
+	}
+
+	// shared nodes appear once
+	both, err := st.ProofNodeSet([]int{0, 1})
+	require.NoError(t, err)
+	require.Equal(t, set, both)
+
+	_, err = st.ProofNodeSet([]int{2})
+	require.Error(t, err)
+}
+
+func TestCompressedStorageProof(t *testing.T) {
+	addrHash := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	tr := New(EmptyRoot)
+	tr.UpdateAccount(addrHash[:], &accounts.Account{Incarnation: 1, Root: EmptyRoot, CodeHash: emptyState})
+	tr.UpdateAccount(common.HexToHash("0xB1").Bytes(), &accounts.Account{Incarnation: 1, Root: EmptyRoot, CodeHash: emptyState})
+	storage := New(EmptyRoot)
+	var keys []common.Hash
+	for i := 0; i < 200; i++ {
+		key := crypto.Keccak256Hash([]byte{byte(i)})
+		value := common.LeftPadBytes([]byte{byte(i + 1)}, 32)
+		tr.Update(dbutils.GenerateCompositeTrieKey(addrHash, key), value)
+		storage.Update(key[:], value)
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	keys = append(keys, common.HexToHash("0x01")) // absent key
+	st := SubTries{Hashes: []common.Hash{tr.Hash()}, roots: []node{tr.root}}
+
+	compressed, err := st.CompressedStorageProof(addrHash, keys)
+	require.NoError(t, err)
+	proofs, err := DecompressStorageProof(compressed)
+	require.NoError(t, err)
+	require.Equal(t, len(keys), len(proofs))
+	var full, shrunk int
+	for i, key := range keys {
+		expected, err := storage.Prove(key[:], 0, false)
+		require.NoError(t, err)
+		require.Equal(t, expected, proofs[i])
+		for _, n := range expected {
+			full += len(n)
+		}
+		for _, n := range compressed.Steps[i].Nodes {
+			shrunk += len(n)
+		}
+	}
+	require.Less(t, shrunk, full/2)
+
+	_, err = st.CompressedStorageProof(common.HexToHash("0xB2"), keys)
+	require.Error(t, err)
+	compressed.Steps[1].Shared = 100
+	_, err = DecompressStorageProof(compressed)
+	require.Error(t, err)
+}
+
+func TestProveWithoutValue(t *testing.T) {
+	tr := New(EmptyRoot)
+	var keys [][]byte
+	for i := 0; i < 20; i++ {
+		key := crypto.Keccak256([]byte{byte(i)})
+		keys = append(keys, key)
+		tr.Update(key, crypto.Keccak256(key)) // 32-byte values
+	}
+
+	for _, key := range keys {
+		full, err := tr.Prove(key, 0, false)
+		require.NoError(t, err)
+		short, err := tr.ProveWithoutValue(key, 0, false)
+		require.NoError(t, err)
+		require.Equal(t, len(full), len(short))
+		require.Equal(t, full[:len(full)-1], short[:len(short)-1])
+		require.Less(t, len(short[len(short)-1]), len(full[len(full)-1])-32)
+
+		restored, err := RestoreProofValue(short, crypto.Keccak256(key))
+		require.NoError(t, err)
+		require.Equal(t, full, restored)
+		wrong, err := RestoreProofValue(short, []byte{1})
+		require.NoError(t, err)
+		require.NotEqual(t, full, wrong)
+	}
+
+	// proof of absence has no value to omit
+	absent := crypto.Keccak256([]byte("absent"))
+	full, err := tr.Prove(absent, 0, false)
+	require.NoError(t, err)
+	short, err := tr.ProveWithoutValue(absent, 0, false)
+	require.NoError(t, err)
+	require.Equal(t, full, short)
+	_, err = RestoreProofValue(short, []byte{1})
+	require.Error(t, err)
+}
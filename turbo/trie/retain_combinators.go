@@ -0,0 +1,80 @@
+package trie
+
+import (
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// RetainUnion - retains a prefix (and treats code as touched) if any of deciders does.
+// For example: retain list of touched keys together with a decider keeping top levels of the trie
+type RetainUnion struct {
+	deciders []RetainDecider
+}
+
+func NewRetainUnion(deciders ...RetainDecider) *RetainUnion {
+	return &RetainUnion{deciders: deciders}
+}
+
+func (d *RetainUnion) Retain(prefix []byte) bool {
+	for _, decider := range d.deciders {
+		if decider.Retain(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *RetainUnion) IsCodeTouched(codeHash common.Hash) bool {
+	for _, decider := range d.deciders {
+		if decider.IsCodeTouched(codeHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetainIntersection - retains a prefix (and treats code as touched) only if all deciders do. Without deciders
+// retains nothing
+type RetainIntersection struct {
+	deciders []RetainDecider
+}
+
+func NewRetainIntersection(deciders ...RetainDecider) *RetainIntersection {
+	return &RetainIntersection{deciders: deciders}
+}
+
+func (d *RetainIntersection) Retain(prefix []byte) bool {
+	for _, decider := range d.deciders {
+		if !decider.Retain(prefix) {
+			return false
+		}
+	}
+	return len(d.deciders) > 0
+}
+
+func (d *RetainIntersection) IsCodeTouched(codeHash common.Hash) bool {
+	for _, decider := range d.deciders {
+		if !decider.IsCodeTouched(codeHash) {
+			return false
+		}
+	}
+	return len(d.deciders) > 0
+}
+
+// RetainPrefixLimited - retains only prefixes not longer than maxLen nibbles which `decider` retains,
+// deeper sub-tries are replaced by their hashes. Code touches are decided by `decider`
+type RetainPrefixLimited struct {
+	decider RetainDecider
+	maxLen  int
+}
+
+func NewRetainPrefixLimited(decider RetainDecider, maxLen int) *RetainPrefixLimited {
+	return &RetainPrefixLimited{decider: decider, maxLen: maxLen}
+}
+
+func (d *RetainPrefixLimited) Retain(prefix []byte) bool {
+	return len(prefix) <= d.maxLen && d.decider.Retain(prefix)
+}
+
+func (d *RetainPrefixLimited) IsCodeTouched(codeHash common.Hash) bool {
+	return d.decider.IsCodeTouched(codeHash)
+}
@@ -35,6 +35,39 @@ type RetainDeciderWithMarker interface {
 	RetainWithMarker(prefix []byte) (retain bool, nextMarkedKey []byte)
 }
 
+// TwoLevelRetainDecider - asks the precise decider only about prefixes accepted by the cheap coarse check,
+// so whole sub-tries rejected at shallow prefixes never reach the precise check.
+// coarse may over-retain (return true where precise returns false), but must never reject a prefix which precise retains.
+// Precise decider must not have marked keys (AddKeyWithMarker with marker=true, used for created accounts):
+// next marked key is known only to the precise decider, and prefixes rejected by coarse report none
+type TwoLevelRetainDecider struct {
+	coarse  func(prefix []byte) bool
+	precise RetainDeciderWithMarker
+}
+
+func NewTwoLevelRetainDecider(coarse func(prefix []byte) bool, precise RetainDeciderWithMarker) *TwoLevelRetainDecider {
+	return &TwoLevelRetainDecider{coarse: coarse, precise: precise}
+}
+
+func (d *TwoLevelRetainDecider) Retain(prefix []byte) bool {
+	return d.coarse(prefix) && d.precise.Retain(prefix)
+}
+
+func (d *TwoLevelRetainDecider) RetainWithMarker(prefix []byte) (bool, []byte) {
+	if !d.coarse(prefix) {
+		return false, nil
+	}
+	return d.precise.RetainWithMarker(prefix)
+}
+
+func (d *TwoLevelRetainDecider) IsCodeTouched(codeHash common.Hash) bool {
+	return d.precise.IsCodeTouched(codeHash)
+}
+
+func (d *TwoLevelRetainDecider) AddKeyWithMarker(key []byte, marker bool) {
+	d.precise.AddKeyWithMarker(key, marker)
+}
+
 // RetainList encapsulates the list of keys that are required to be fully available, or loaded
 // (by using `BRANCH` opcode instead of `HASHER`) after processing of the sequence of key-value
 // pairs
@@ -0,0 +1,167 @@
+package trie
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+type countingRetainDecider struct {
+	RetainDeciderWithMarker
+	calls int
+}
+
+func (d *countingRetainDecider) RetainWithMarker(prefix []byte) (bool, []byte) {
+	d.calls++
+	return d.RetainDeciderWithMarker.RetainWithMarker(prefix)
+}
+
+func TestTwoLevelRetainDecider(t *testing.T) {
+	rl := NewRetainList(0)
+	key := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	rl.AddKey(key[:])
+	precise := &countingRetainDecider{RetainDeciderWithMarker: rl}
+	// everything under first nibble B
+	d := NewTwoLevelRetainDecider(func(prefix []byte) bool { return len(prefix) == 0 || prefix[0] == 0xb }, precise)
+
+	retain, next := d.RetainWithMarker([]byte{0x1})
+	require.False(t, retain)
+	require.Nil(t, next)
+	require.Equal(t, 0, precise.calls)
+
+	retain, _ = d.RetainWithMarker([]byte{0xb, 0x0, 0x4})
+	require.True(t, retain)
+	retain, _ = d.RetainWithMarker([]byte{0xb, 0x0, 0x5})
+	require.False(t, retain)
+	require.Equal(t, 2, precise.calls)
+
+	require.True(t, d.Retain([]byte{0xb, 0x0}))
+	require.False(t, d.Retain([]byte{0xc}))
+
+	// gives same answers as precise decider alone
+	for _, prefix := range [][]byte{{}, {0x1}, {0xb}, {0xb, 0x0, 0x4, 0x1}, {0xb, 0x1}, {0xc, 0x0}} {
+		require.Equal(t, rl.Retain(prefix), d.Retain(prefix), "prefix %x", prefix)
+	}
+}
+
+func retainTestHexes(n int) [][]byte {
+	hexes := make([][]byte, n)
+	for i := range hexes {
+		hexes[i] = keybytesToHex(crypto.Keccak256([]byte{byte(i), byte(i >> 8), byte(i >> 16)}))
+		hexes[i] = hexes[i][:len(hexes[i])-1]
+	}
+	sort.Slice(hexes, func(i, j int) bool { return bytes.Compare(hexes[i], hexes[j]) < 0 })
+	return hexes
+}
+
+func TestPatriciaRetainDecider(t *testing.T) {
+	hexes := retainTestHexes(1000)
+	d, err := NewPatriciaRetainDecider(hexes)
+	require.NoError(t, err)
+	rl := NewRetainList(0)
+	for _, hex := range hexes {
+		rl.AddHex(hex)
+	}
+	// same answers as RetainList for prefixes of retained paths and for other paths
+	others := retainTestHexes(2000)[1000:]
+	for _, hex := range append(hexes, others...) {
+		for l := 0; l <= len(hex); l++ {
+			require.Equal(t, rl.Retain(hex[:l]), d.Retain(hex[:l]), "%x", hex[:l])
+		}
+	}
+	// whole sub-trie under a retained path is retained
+	require.True(t, d.Retain(append(common.CopyBytes(hexes[0]), 1, 2)))
+
+	short, err := NewPatriciaRetainDecider([][]byte{{1, 2}, {1, 2, 3, 4}, {1, 5}})
+	require.NoError(t, err)
+	require.True(t, short.Retain(nil))
+	require.True(t, short.Retain([]byte{1}))
+	require.True(t, short.Retain([]byte{1, 2, 7}))
+	require.False(t, short.Retain([]byte{1, 3}))
+	require.False(t, short.Retain([]byte{2}))
+
+	empty, err := NewPatriciaRetainDecider(nil)
+	require.NoError(t, err)
+	require.False(t, empty.Retain(nil))
+
+	_, err = NewPatriciaRetainDecider([][]byte{{2}, {1}})
+	require.Error(t, err)
+}
+
+func TestRetainCombinators(t *testing.T) {
+	a, err := NewPatriciaRetainDecider([][]byte{{1, 2, 3}})
+	require.NoError(t, err)
+	b, err := NewPatriciaRetainDecider([][]byte{{1, 4}})
+	require.NoError(t, err)
+	code1, code2 := common.HexToHash("0x01"), common.HexToHash("0x02")
+	a.AddCodeTouch(code1)
+	a.AddCodeTouch(code2)
+	b.AddCodeTouch(code2)
+
+	union := NewRetainUnion(a, b)
+	intersection := NewRetainIntersection(a, b)
+	for _, tc := range []struct {
+		prefix              []byte
+		union, intersection bool
+	}{
+		{nil, true, true},
+		{[]byte{1}, true, true},
+		{[]byte{1, 2}, true, false},
+		{[]byte{1, 4}, true, false},
+		{[]byte{1, 5}, false, false},
+		{[]byte{2}, false, false},
+	} {
+		require.Equal(t, tc.union, union.Retain(tc.prefix), "union %x", tc.prefix)
+		require.Equal(t, tc.intersection, intersection.Retain(tc.prefix), "intersection %x", tc.prefix)
+	}
+	require.True(t, union.IsCodeTouched(code1))
+	require.False(t, intersection.IsCodeTouched(code1))
+	require.True(t, intersection.IsCodeTouched(code2))
+	require.False(t, NewRetainUnion().Retain(nil))
+	require.False(t, NewRetainIntersection().Retain(nil))
+
+	limited := NewRetainPrefixLimited(union, 2)
+	require.True(t, limited.Retain([]byte{1, 2}))
+	require.False(t, limited.Retain([]byte{1, 2, 3}))
+	require.False(t, limited.Retain([]byte{2}))
+	require.True(t, limited.IsCodeTouched(code1))
+}
+
+func BenchmarkRetainDeciders(b *testing.B) {
+	hexes := retainTestHexes(10_000)
+	queries := retainTestHexes(20_000)
+	for i := range queries {
+		queries[i] = queries[i][:8]
+	}
+	rl := NewRetainList(0)
+	for _, hex := range hexes {
+		rl.AddHex(hex)
+	}
+	d, err := NewPatriciaRetainDecider(hexes)
+	require.NoError(b, err)
+	shuffled := make([][]byte, len(queries))
+	copy(shuffled, queries)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	for _, bm := range []struct {
+		name    string
+		decider RetainDecider
+		queries [][]byte
+	}{
+		{"RetainList/ascending", rl, queries},
+		{"RetainList/random", rl, shuffled},
+		{"Patricia/ascending", d, queries},
+		{"Patricia/random", d, shuffled},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bm.decider.Retain(bm.queries[i%len(bm.queries)])
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// PatriciaRetainDecider - RetainDecider which keeps retained paths (in HEX encoding) in a simple in-memory
+// patricia trie, so Retain takes O(len(prefix)) regardless of amount of paths and order of queries
+// (RetainList is optimised for ascending queries only).
+// Unlike RetainList, retained path also retains the whole sub-trie under it: Retain returns true if prefix
+// leads to any retained path, or if any retained path is a prefix of it
+type PatriciaRetainDecider struct {
+	root        patriciaNode
+	codeTouches map[common.Hash]struct{}
+}
+
+type patriciaNode struct {
+	key      []byte          // nibbles on the edge leading to this node, not empty except the root
+	children []*patriciaNode // sorted by the first nibble of the key
+	retained bool            // some retained path ends here
+}
+
+// NewPatriciaRetainDecider - builds the trie from paths sorted in ascending order, in O(len(hexes)*pathLen):
+// because of the order, each path shares prefix only with the last inserted branch of the trie
+func NewPatriciaRetainDecider(hexes [][]byte) (*PatriciaRetainDecider, error) {
+	d := &PatriciaRetainDecider{codeTouches: make(map[common.Hash]struct{})}
+	for i, hex := range hexes {
+		if i > 0 && bytes.Compare(hexes[i-1], hex) > 0 {
+			return nil, fmt.Errorf("retained paths are not sorted: %x after %x", hex, hexes[i-1])
+		}
+		d.add(common.CopyBytes(hex))
+	}
+	return d, nil
+}
+
+func (d *PatriciaRetainDecider) add(hex []byte) {
+	n := &d.root
+	for len(hex) > 0 {
+		var last *patriciaNode
+		if len(n.children) > 0 {
+			last = n.children[len(n.children)-1]
+		}
+		if last == nil || last.key[0] != hex[0] {
+			n.children = append(n.children, &patriciaNode{key: hex, retained: true})
+			return
+		}
+		shared := 0
+		for shared < len(last.key) && shared < len(hex) && last.key[shared] == hex[shared] {
+			shared++
+		}
+		if shared < len(last.key) { // split the edge
+			mid := &patriciaNode{key: last.key[:shared], children: []*patriciaNode{last}}
+			last.key = last.key[shared:]
+			n.children[len(n.children)-1] = mid
+			last = mid
+		}
+		n, hex = last, hex[shared:]
+	}
+	n.retained = true
+}
+
+func (d *PatriciaRetainDecider) Retain(prefix []byte) bool {
+	n := &d.root
+	for {
+		if n.retained {
+			return true
+		}
+		if len(prefix) == 0 {
+			return len(n.children) > 0
+		}
+		var next *patriciaNode
+		for _, child := range n.children {
+			if child.key[0] == prefix[0] {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return false
+		}
+		if len(prefix) <= len(next.key) {
+			return bytes.HasPrefix(next.key, prefix)
+		}
+		if !bytes.HasPrefix(prefix, next.key) {
+			return false
+		}
+		n, prefix = next, prefix[len(next.key):]
+	}
+}
+
+// AddCodeTouch adds a new code touch into the resolve set
+func (d *PatriciaRetainDecider) AddCodeTouch(codeHash common.Hash) {
+	d.codeTouches[codeHash] = struct{}{}
+}
+
+func (d *PatriciaRetainDecider) IsCodeTouched(codeHash common.Hash) bool {
+	_, ok := d.codeTouches[codeHash]
+	return ok
+}
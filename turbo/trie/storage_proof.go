@@ -0,0 +1,48 @@
+package trie
+
+import (
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+)
+
+// StorageProof - merkle proof of one storage slot, in the form returned by eth_getProof
+type StorageProof struct {
+	Key   common.Hash  // Slot, not hashed
+	Value *uint256.Int // Zero if the slot is empty
+	Proof [][]byte     // RLP of the nodes on the path from the storage root to the leaf (or to the node proving absence)
+}
+
+// GenerateStorageProofs - builds the storage trie of the account from HashedStorage and proves given slots against it.
+// Works only for the current state: HashedStorage has no history. Intermediate hashes are not used,
+// the whole storage of the account is read, so it's linear in the size of the storage.
+// Returns the storage root, which must be equal to the storage root of the account proof
+func GenerateStorageProofs(tx kv.Tx, addrHash common.Hash, incarnation uint64, keys []common.Hash) (common.Hash, []StorageProof, error) {
+	t := New(EmptyRoot)
+	prefix := dbutils.GenerateStoragePrefix(addrHash[:], incarnation)
+	if err := tx.ForPrefix(kv.HashedStorage, prefix, func(k, v []byte) error {
+		// HashedStorage is AutoDupSort: ForPrefix returns full key addrHash+incarnation+locHash and bare value
+		t.Update(common.CopyBytes(k[len(prefix):]), common.CopyBytes(v))
+		return nil
+	}); err != nil {
+		return common.Hash{}, nil, err
+	}
+
+	proofs := make([]StorageProof, len(keys))
+	for i, key := range keys {
+		keyHash, err := common.HashData(key[:])
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+		proof, err := t.Prove(keyHash[:], 0, false)
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+		proofs[i] = StorageProof{Key: key, Value: new(uint256.Int), Proof: proof}
+		if v, ok := t.Get(keyHash[:]); ok {
+			proofs[i].Value.SetBytes(v)
+		}
+	}
+	return t.Hash(), proofs, nil
+}
@@ -0,0 +1,69 @@
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateStorageProofs(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	addrHash := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	acc := accounts.NewAccount()
+	acc.Incarnation = 1
+	encoded := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(encoded)
+	require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+
+	keys := []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03"), common.HexToHash("0x04")}
+	for i, key := range keys[:3] {
+		keyHash, err := common.HashData(key[:])
+		require.NoError(t, err)
+		require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(addrHash, 1, keyHash), []byte{byte(i + 1)}))
+	}
+
+	var expectedRoot common.Hash
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	loader.SetStorageRootHandler(func(_ common.Hash, _ uint64, storageRoot common.Hash) error {
+		expectedRoot = storageRoot
+		return nil
+	})
+	_, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(t, err)
+
+	root, proofs, err := GenerateStorageProofs(tx, addrHash, 1, keys)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	require.Equal(t, len(keys), len(proofs))
+	for i, p := range proofs {
+		require.Equal(t, keys[i], p.Key)
+		require.NotEmpty(t, p.Proof)
+		// every node is referenced by its parent, the first one by the root
+		require.Equal(t, root, crypto.Keccak256Hash(p.Proof[0]))
+		for j := 1; j < len(p.Proof); j++ {
+			ref := p.Proof[j]
+			if len(ref) >= 32 {
+				ref = crypto.Keccak256(ref)
+			}
+			require.True(t, bytes.Contains(p.Proof[j-1], ref))
+		}
+	}
+	require.Equal(t, uint256.NewInt(2), proofs[1].Value)
+	require.True(t, proofs[3].Value.IsZero())
+
+	// other incarnation has no storage
+	root, proofs, err = GenerateStorageProofs(tx, addrHash, 2, keys[:1])
+	require.NoError(t, err)
+	require.Equal(t, EmptyRoot, root)
+	require.True(t, proofs[0].Value.IsZero())
+}
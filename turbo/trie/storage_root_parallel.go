@@ -0,0 +1,168 @@
+package trie
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/turbo/rlphacks"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// storageRootAttempts - StorageRootParallel gives up if the db is modified during so many attempts in a row
+const storageRootAttempts = 3
+
+// StorageRootParallel - calculates storage root of one account from HashedStorage, splitting its storage by the
+// first nibble of location hash into 16 parts, which are processed by up to `workers` goroutines, each in own
+// read transaction. Intermediate hashes are not used.
+// For contracts with huge storage, which dominate time of CalcTrieRoot - it processes storage of an account serially.
+// Parts must be read from the same snapshot: if the db is modified while they are read, the calculation is
+// repeated, so on the db with frequent commits prefer the serial calculation in one transaction
+func StorageRootParallel(ctx context.Context, db kv.RoDB, addrHash common.Hash, incarnation uint64, workers int) (common.Hash, error) {
+	for attempt := 1; ; attempt++ {
+		root, ok, err := storageRootParallel(ctx, db, addrHash, incarnation, workers)
+		if err != nil || ok {
+			return root, err
+		}
+		if attempt == storageRootAttempts {
+			return EmptyRoot, fmt.Errorf("storage root of %x: db was modified during %d attempts", addrHash, attempt)
+		}
+	}
+}
+
+// storageRootParallel - ok is false if the parts were read from different snapshots of the db
+func storageRootParallel(ctx context.Context, db kv.RoDB, addrHash common.Hash, incarnation uint64, workers int) (common.Hash, bool, error) {
+	accWithInc := dbutils.GenerateStoragePrefix(addrHash[:], incarnation)
+	if workers < 1 {
+		workers = 1
+	}
+
+	var children [16][]byte // hashStack item of the sub-trie under each nibble, nil if there is no storage under it
+	var views [16]uint64    // snapshot each nibble was read from
+	sem := semaphore.NewWeighted(int64(workers))
+	g, gctx := errgroup.WithContext(ctx)
+	for nibble := 0; nibble < 16; nibble++ {
+		nibble := nibble
+		if err := sem.Acquire(gctx, 1); err != nil {
+			break // one of workers failed or ctx is cancelled, error is checked below
+		}
+		g.Go(func() error {
+			defer sem.Release(1)
+			return db.View(gctx, func(tx kv.Tx) error {
+				views[nibble] = tx.ViewID()
+				hb := NewHashBuilder(false)
+				found, err := genStorageStructure(tx, accWithInc, nibble, hb)
+				if err != nil || !found {
+					return err
+				}
+				children[nibble] = common.CopyBytes(hb.hashStack[len(hb.hashStack)-hashStackStride:])
+				return nil
+			})
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return EmptyRoot, false, err
+	}
+	if err := ctx.Err(); err != nil {
+		return EmptyRoot, false, err
+	}
+	for _, view := range views[1:] {
+		if view != views[0] {
+			return EmptyRoot, false, nil
+		}
+	}
+
+	hb := NewHashBuilder(false)
+	var set uint16
+	for nibble, child := range children {
+		if child == nil {
+			continue
+		}
+		set |= 1 << nibble
+		hb.hashStack = append(hb.hashStack, child...)
+		hb.nodeStack = append(hb.nodeStack, nil)
+	}
+	switch bits.OnesCount16(set) {
+	case 0:
+		return EmptyRoot, true, nil
+	case 1: // root is not a branch, but the only sub-trie with the first nibble added to its key - just calculate it again
+		var root common.Hash
+		var ok bool
+		if err := db.View(ctx, func(tx kv.Tx) error {
+			if ok = tx.ViewID() == views[0]; !ok {
+				return nil
+			}
+			hb := NewHashBuilder(false)
+			if _, err := genStorageStructure(tx, accWithInc, -1, hb); err != nil {
+				return err
+			}
+			root = hb.rootHash()
+			return nil
+		}); err != nil {
+			return EmptyRoot, false, err
+		}
+		return root, ok, nil
+	}
+	if err := hb.branchHash(set); err != nil {
+		return EmptyRoot, false, err
+	}
+	return hb.rootHash(), true, nil
+}
+
+// genStorageStructure - feeds storage of the account with location hashes starting with `nibble` (-1 - whole storage)
+// to GenStructStep, returns false if there is no such storage. Storage of a nibble is finished as if storage of the
+// next nibble followed, so `hb` ends up with the node at depth 1 - child of the root branch, as in serial calculation
+func genStorageStructure(tx kv.Tx, accWithInc []byte, nibble int, hb *HashBuilder) (bool, error) {
+	c, err := tx.CursorDupSort(kv.HashedStorage)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+
+	var seek []byte
+	if nibble >= 0 {
+		seek = []byte{byte(nibble) << 4}
+	}
+	retainNothing := func(_ []byte) bool { return false }
+	var curr, succ, value []byte
+	var groups, hasTree, hasHash []uint16
+	var leafData GenStructStepLeafData
+	step := func() error {
+		if err := rlphacks.ValidateStorageValue(value); err != nil {
+			return fmt.Errorf("account %x, storage %x: %w", accWithInc, curr, err)
+		}
+		leafData.Value = rlphacks.RlpSerializableBytes(value)
+		groups, hasTree, hasHash, err = GenStructStep(retainNothing, curr, succ, hb, nil, &leafData, groups, hasTree, hasHash, false)
+		return err
+	}
+	for v, err := c.SeekBothRange(accWithInc, seek); v != nil; _, v, err = c.NextDup() {
+		if err != nil {
+			return false, err
+		}
+		if nibble >= 0 && int(v[0]>>4) != nibble {
+			break
+		}
+		curr, succ = succ, curr
+		hexutil.DecompressNibbles(v[:32], &succ)
+		succ = append(succ, 16)
+		if len(curr) > 0 {
+			if err := step(); err != nil {
+				return false, err
+			}
+		}
+		value = append(value[:0], v[32:]...)
+	}
+	if len(succ) == 0 {
+		return false, nil
+	}
+	curr, succ = succ, curr[:0]
+	if nibble >= 0 {
+		succ = append(succ, byte(nibble)+1)
+	}
+	return true, step()
+}
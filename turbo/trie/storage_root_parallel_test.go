@@ -0,0 +1,78 @@
+package trie
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageRootParallel(t *testing.T) {
+	const incarnation = 1
+	for _, tc := range []struct {
+		name  string
+		slots []common.Hash
+	}{
+		{"empty", nil},
+		{"one slot", []common.Hash{common.HexToHash("0x12")}},
+		{"one nibble", []common.Hash{{0x10}, {0x12}, {0x1f, 0x01}}},
+		{"two nibbles", []common.Hash{{0x10}, {0xf0}}},
+		{"large", func() (slots []common.Hash) {
+			var i [8]byte
+			for n := uint64(0); n < 20_000; n++ {
+				binary.BigEndian.PutUint64(i[:], n)
+				slots = append(slots, crypto.Keccak256Hash(i[:]))
+			}
+			return slots
+		}()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := memdb.NewTestDB(t)
+			addrHash := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+			require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+				acc := accounts.NewAccount()
+				acc.Incarnation = incarnation
+				encoded := make([]byte, acc.EncodingLengthForStorage())
+				acc.EncodeForStorage(encoded)
+				if err := tx.Put(kv.HashedAccounts, addrHash[:], encoded); err != nil {
+					return err
+				}
+				for i, loc := range tc.slots {
+					k := dbutils.GenerateCompositeStorageKey(addrHash, incarnation, loc)
+					if err := tx.Put(kv.HashedStorage, k, uint256.NewInt(uint64(i+1)).Bytes()); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+
+			expected := EmptyRoot
+			require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+				loader := NewFlatDBTrieLoader("test")
+				if err := loader.Reset(NewRetainList(0), nil, nil, false); err != nil {
+					return err
+				}
+				loader.SetStorageRootHandler(func(_ common.Hash, _ uint64, storageRoot common.Hash) error {
+					expected = storageRoot
+					return nil
+				})
+				_, err := loader.CalcTrieRoot(tx, nil, nil)
+				return err
+			}))
+
+			for _, workers := range []int{1, 4, 16} {
+				root, err := StorageRootParallel(context.Background(), db, addrHash, incarnation, workers)
+				require.NoError(t, err)
+				require.Equal(t, expected, root, "workers: %d", workers)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package trie
+
+import (
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+// StorageValuesReceiver - passes every stream item to the wrapped receiver and, on the way,
+// collects values of storage leaves decoded into uint256 (HashedStorage keeps them trimmed,
+// without leading zeroes). Values are keyed by addrHash and hash of the storage key.
+// Use with FlatDBTrieLoader.SetStreamReceiver
+type StorageValuesReceiver struct {
+	next   StreamReceiver
+	values map[common.Hash]map[common.Hash]*uint256.Int
+	kBuf   []byte
+}
+
+func NewStorageValuesReceiver(next StreamReceiver) *StorageValuesReceiver {
+	return &StorageValuesReceiver{
+		next:   next,
+		values: map[common.Hash]map[common.Hash]*uint256.Int{},
+	}
+}
+
+func (r *StorageValuesReceiver) Receive(
+	itemType StreamItem,
+	accountKey []byte,
+	storageKey []byte,
+	accountValue *accounts.Account,
+	storageValue []byte,
+	hash []byte,
+	hasTree bool,
+	cutoff int,
+) error {
+	if itemType == StorageStreamItem {
+		addrHash := common.BytesToHash(accountKey[:common.HashLength])
+		hexutil.CompressNibbles(storageKey, &r.kBuf)
+		m, ok := r.values[addrHash]
+		if !ok {
+			m = map[common.Hash]*uint256.Int{}
+			r.values[addrHash] = m
+		}
+		m[common.BytesToHash(r.kBuf)] = new(uint256.Int).SetBytes(storageValue) // SetBytes copies, zero-length value gives 0
+	}
+	return r.next.Receive(itemType, accountKey, storageKey, accountValue, storageValue, hash, hasTree, cutoff)
+}
+
+func (r *StorageValuesReceiver) Result() SubTries  { return r.next.Result() }
+func (r *StorageValuesReceiver) Root() common.Hash { return r.next.Root() }
+
+// StorageValues - returns collected storage values of given account, keyed by hash of storage key.
+// Only storage leaves which were actually read from the state are present: sub-tries covered by
+// intermediate hashes are not expanded, use RetainDecider to force reading of required keys
+func (r *StorageValuesReceiver) StorageValues(addrHash common.Hash) map[common.Hash]*uint256.Int {
+	return r.values[addrHash]
+}
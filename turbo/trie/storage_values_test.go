@@ -0,0 +1,48 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageValuesReceiver(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	addrHash := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	acc := accounts.NewAccount()
+	acc.Incarnation = 1
+	acc.Balance.SetUint64(1)
+	encoded := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(encoded)
+	require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+
+	loc1 := common.HexToHash("0x1200000000000000000000000000000000000000000000000000000000000000")
+	loc2 := common.HexToHash("0x1400000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(addrHash, 1, loc1), common.FromHex("0x127a89")))
+	require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(addrHash, 1, loc2), common.FromHex("0x01")))
+
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	expectedRoot, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	r := NewStorageValuesReceiver(NewRootHashAggregator())
+	loader.SetStreamReceiver(r)
+	root, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+
+	values := r.StorageValues(addrHash)
+	require.Equal(t, 2, len(values))
+	require.Equal(t, uint256.NewInt(0x127a89), values[loc1])
+	require.Equal(t, uint256.NewInt(1), values[loc2])
+	require.Nil(t, r.StorageValues(common.Hash{}))
+}
@@ -728,3 +728,57 @@ func Test2(t *testing.T) {
 		t.Errorf("Could not execute step of structGen algorithm: %v", err)
 	}
 }
+
+func TestGenStructStepMalformedKeys(t *testing.T) {
+	retainNothing := func(_ []byte) bool { return false }
+	leaf := &GenStructStepLeafData{rlphacks.RlpSerializableBytes([]byte("VAL"))}
+	key := append(bytes.Repeat([]byte{1}, 2*common.HashLength), 16)
+
+	// duplicate keys
+	_, _, _, err := GenStructStep(retainNothing, key, key, NewHashBuilder(false), nil, leaf, nil, nil, nil, false)
+	require.Error(t, err)
+	// curr is a prefix of succ
+	_, _, _, err = GenStructStep(retainNothing, key[:10], key, NewHashBuilder(false), nil, leaf, nil, nil, nil, false)
+	require.Error(t, err)
+	// not a nibble
+	_, _, _, err = GenStructStep(retainNothing, []byte{1, 17, 16}, []byte{1, 2, 16}, NewHashBuilder(false), nil, leaf, nil, nil, nil, false)
+	require.Error(t, err)
+	// too deep
+	groups := make([]uint16, maxGenStructStepDepth+1)
+	_, _, _, err = GenStructStep(retainNothing, key, nil, NewHashBuilder(false), nil, leaf, groups, nil, nil, false)
+	require.ErrorIs(t, err, ErrTrieDepthExceeded)
+	long := append(bytes.Repeat([]byte{1}, maxGenStructStepDepth), 16)
+	_, _, _, err = GenStructStep(retainNothing, long, nil, NewHashBuilder(false), nil, leaf, nil, nil, nil, false)
+	require.ErrorIs(t, err, ErrTrieDepthExceeded)
+}
+
+func TestNodeSizeHistogram(t *testing.T) {
+	hb := NewHashBuilder(false)
+	var h NodeSizeHistogram
+	hb.SetNodeSizeHistogram(&h)
+	value := rlphacks.RlpSerializableBytes(bytes.Repeat([]byte{1}, 40))
+	keys := [][]byte{{1, 0, 16}, {2, 0, 16}, {2, 1, 16}}
+	var groups, hasTree, hasHash []uint16
+	var err error
+	for i := range keys {
+		var succ []byte
+		if i+1 < len(keys) {
+			succ = keys[i+1]
+		}
+		groups, hasTree, hasHash, err = GenStructStep(func(_ []byte) bool { return false }, keys[i], succ, hb, nil /* hashCollector */, &GenStructStepLeafData{value}, groups, hasTree, hasHash, false)
+		require.NoError(t, err)
+	}
+
+	// 3 leaves, branch of the keys starting with 2, root branch
+	stats := h.HistogramStats()
+	require.Equal(t, uint64(5), stats.Count)
+	var inBuckets uint64
+	for upper, n := range stats.Buckets {
+		require.Equal(t, 0, upper&(upper-1))
+		inBuckets += n
+	}
+	require.Equal(t, stats.Count, inBuckets)
+	leafSize := 1 + 1 + 2 + 40 // list prefix, compact key, double RLP prefix of the value, value
+	require.Equal(t, leafSize, stats.P50)
+	require.Greater(t, stats.P99, leafSize) // root branch with 2 hashes
+}
@@ -1,7 +1,12 @@
 package trie
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
+	"golang.org/x/sync/errgroup"
 )
 
 var emptyHash [32]byte
@@ -15,6 +20,30 @@ type SubTries struct {
 	roots  []node        // Sub-tries
 }
 
+// MergeWith appends sub-tries of `other` after the ones of `st`, so results of the loaders which worked on
+// consecutive groups of prefixes can be combined in the original order of prefixes.
+// Sub-tries are identified by position only: Hashes[i] must be the hash of roots[i], otherwise HookSubTries
+// would hook wrong sub-trie, so both parts are checked for that before merging
+func (st *SubTries) MergeWith(other SubTries) error {
+	if len(st.Hashes) != len(st.roots) {
+		return fmt.Errorf("sub-tries are inconsistent: %d hashes, %d roots", len(st.Hashes), len(st.roots))
+	}
+	if len(other.Hashes) != len(other.roots) {
+		return fmt.Errorf("merged sub-tries are inconsistent: %d hashes, %d roots", len(other.Hashes), len(other.roots))
+	}
+	st.Hashes = append(st.Hashes, other.Hashes...)
+	st.roots = append(st.roots, other.roots...)
+	return nil
+}
+
+// AtIndex - root and hash of i-th sub-trie, false if there is no such sub-trie
+func (st SubTries) AtIndex(i int) (node, common.Hash, bool) {
+	if i < 0 || i >= len(st.roots) || i >= len(st.Hashes) {
+		return nil, common.Hash{}, false
+	}
+	return st.roots[i], st.Hashes[i], true
+}
+
 type LoadFunc func(*SubTrieLoader, *RetainList, [][]byte, []int) (SubTries, error)
 
 // Resolver looks up (resolves) some keys and corresponding values from a database.
@@ -40,6 +69,59 @@ func (stl *SubTrieLoader) AddCodeRequest(req *LoadRequestForCode) {
 	stl.codeRequests = append(stl.codeRequests, req)
 }
 
+// AttachRequestedCode - fetches code of all added code requests by up to `workers` goroutines, each in own read
+// transaction, then attaches code (or code size) to the tries serially, in the order of requests:
+// UpdateAccountCode and UpdateAccountCodeSize modify the trie and can't run concurrently
+func (stl *SubTrieLoader) AttachRequestedCode(ctx context.Context, db kv.RoDB, workers int) error {
+	requests := stl.codeRequests
+	if len(requests) == 0 {
+		return nil
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+
+	codes := make([][]byte, len(requests))
+	g, gctx := errgroup.WithContext(ctx)
+	for w := 0; w < workers; w++ {
+		w := w
+		g.Go(func() error {
+			return db.View(gctx, func(tx kv.Tx) error {
+				for i := w; i < len(requests); i += workers {
+					if err := gctx.Err(); err != nil {
+						return err
+					}
+					code, err := tx.GetOne(kv.Code, requests[i].codeHash[:])
+					if err != nil {
+						return err
+					}
+					codes[i] = common.CopyBytes(code)
+				}
+				return nil
+			})
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	for i, req := range requests {
+		if req.bytecode {
+			if err := req.t.UpdateAccountCode(req.addrHash[:], codes[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := req.t.UpdateAccountCodeSize(req.addrHash[:], len(codes[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Various values of the account field set
 const (
 	AccountFieldNonceOnly     uint32 = 0x01
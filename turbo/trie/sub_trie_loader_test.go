@@ -0,0 +1,105 @@
+package trie
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubTriesMergeWith(t *testing.T) {
+	subTrie := func(b byte) SubTries {
+		tr := New(EmptyRoot)
+		tr.Update(common.Hash{b}.Bytes(), []byte{b})
+		return SubTries{Hashes: []common.Hash{tr.Hash()}, roots: []node{tr.root}}
+	}
+	a, b := subTrie(1), subTrie(2)
+
+	var merged SubTries
+	require.NoError(t, merged.MergeWith(SubTries{}))
+	_, _, ok := merged.AtIndex(0)
+	require.False(t, ok)
+
+	require.NoError(t, merged.MergeWith(a))
+	require.NoError(t, merged.MergeWith(SubTries{}))
+	require.NoError(t, merged.MergeWith(b))
+	require.Equal(t, 2, len(merged.Hashes))
+	for i, part := range []SubTries{a, b} {
+		root, hash, ok := merged.AtIndex(i)
+		require.True(t, ok)
+		require.Equal(t, part.Hashes[0], hash)
+		require.Equal(t, part.roots[0], root)
+	}
+	_, _, ok = merged.AtIndex(2)
+	require.False(t, ok)
+	_, _, ok = merged.AtIndex(-1)
+	require.False(t, ok)
+
+	// hash without sub-trie would shift all following sub-tries
+	require.Error(t, merged.MergeWith(SubTries{Hashes: []common.Hash{{3}}}))
+	require.Equal(t, 2, len(merged.Hashes))
+	broken := SubTries{Hashes: a.Hashes}
+	require.Error(t, broken.MergeWith(b))
+}
+
+func TestAttachRequestedCode(t *testing.T) {
+	const contracts = 50
+	db := memdb.NewTestDB(t)
+	addrHashes := make([]common.Hash, contracts)
+	codes := make([][]byte, contracts)
+	newTrie := func() *Trie {
+		tr := New(common.Hash{})
+		for i := range addrHashes {
+			acc := accounts.NewAccount()
+			acc.Initialised = true
+			acc.CodeHash = crypto.Keccak256Hash(codes[i])
+			tr.UpdateAccount(addrHashes[i][:], &acc)
+		}
+		return tr
+	}
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for i := range addrHashes {
+			addrHashes[i] = crypto.Keccak256Hash([]byte{byte(i)})
+			codes[i] = []byte(fmt.Sprintf("code of contract %d", i))
+			codeHash := crypto.Keccak256Hash(codes[i])
+			if err := tx.Put(kv.Code, codeHash[:], codes[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	var expectedHash common.Hash
+	for _, workers := range []int{1, 3, 8, contracts * 2} {
+		tr := newTrie()
+		loader := NewSubTrieLoader()
+		for i, addrHash := range addrHashes {
+			need, req := tr.NeedLoadCode(addrHash, crypto.Keccak256Hash(codes[i]), i%2 == 0)
+			require.True(t, need)
+			loader.AddCodeRequest(req)
+		}
+		require.NoError(t, loader.AttachRequestedCode(context.Background(), db, workers))
+
+		for i, addrHash := range addrHashes {
+			if i%2 == 0 {
+				code, ok := tr.GetAccountCode(addrHash[:])
+				require.True(t, ok)
+				require.Equal(t, codes[i], code)
+			} else {
+				size, ok := tr.GetAccountCodeSize(addrHash[:])
+				require.True(t, ok)
+				require.Equal(t, len(codes[i]), size)
+			}
+		}
+		if workers == 1 {
+			expectedHash = tr.Hash()
+		}
+		require.Equal(t, expectedHash, tr.Hash(), "workers=%d", workers)
+	}
+}
@@ -3,6 +3,7 @@ package trie
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/bits"
 	"time"
@@ -77,6 +78,7 @@ Then delete this account (SELFDESTRUCT).
 type FlatDBTrieLoader struct {
 	logPrefix          string
 	trace              bool
+	traceID            string
 	rd                 RetainDeciderWithMarker
 	accAddrHashWithInc [40]byte // Concatenation of addrHash of the currently build account with its incarnation encoding
 
@@ -90,11 +92,65 @@ type FlatDBTrieLoader struct {
 	defaultReceiver *RootHashAggregator
 	hc              HashCollector2
 	shc             StorageHashCollector2
+
+	ihDepths IHDepthStats
+	ignoreIH bool
+	ihSource IHSource
+
+	maxAccounts int
+	accounts    int // accounts and account sub-trie hashes sent to receiver by current CalcTrieRoot call
+	countBytes  bool
+	bytesRead   uint64
+
+	progressEvery int
+	progressFunc  ProgressFunc
+	prefixLen     int       // length of `prefix` of current CalcTrieRoot call, progress is estimated after it
+	scanStart     time.Time // start of current CalcTrieRoot call
+	progressHex   []byte
+
+	profile *LoaderProfile
+	stats   IterationStats
+}
+
+// IterationStats - amount of work done by the last CalcTrieRoot call, see FlatDBTrieLoader.DumpStats
+type IterationStats struct {
+	AccountsScanned     uint64        // accounts read from HashedAccounts
+	AccountHashesUsed   uint64        // account intermediate hashes used instead of reading state
+	StorageItemsScanned uint64        // storage items read from HashedStorage
+	StorageHashesUsed   uint64        // storage intermediate hashes used instead of reading state, including storage roots
+	IHSkippedCount      uint64        // intermediate hashes which can't be used because RetainDecider retains their prefix
+	TotalIterations     uint64        // iterations over account intermediate hashes
+	ScanDuration        time.Duration // reading of state and intermediate hashes, with hashing of received items
+	FinalizeDuration    time.Duration // hashing of items left after the scan
+}
+
+// IHDepthStats - distribution of depths (in nibbles) at which intermediate hashes were used instead of reading state.
+// Index of array is depth. Shallow depths mean IH saved a lot of work, deep depths mean IH didn't help much.
+// Storage depth counted from the storage root: depth 0 means whole storage of account was covered by storage root.
+type IHDepthStats struct {
+	Accounts [2*common.HashLength + 1]uint64
+	Storage  [2*common.HashLength + 1]uint64
+}
+
+func (s IHDepthStats) AvgAccountDepth() float64 { return avgDepth(s.Accounts[:]) }
+func (s IHDepthStats) AvgStorageDepth() float64 { return avgDepth(s.Storage[:]) }
+
+func avgDepth(hist []uint64) float64 {
+	var sum, cnt uint64
+	for depth, n := range hist {
+		sum += uint64(depth) * n
+		cnt += n
+	}
+	if cnt == 0 {
+		return 0
+	}
+	return float64(sum) / float64(cnt)
 }
 
 // RootHashAggregator - calculates Merkle trie root hash from incoming data stream
 type RootHashAggregator struct {
 	trace          bool
+	tracePrefix    string
 	wasIH          bool
 	wasIHStorage   bool
 	root           common.Hash
@@ -122,6 +178,9 @@ type RootHashAggregator struct {
 	a              accounts.Account
 	leafData       GenStructStepLeafData
 	accData        GenStructStepAccountData
+
+	storageRootHandler StorageRootHandler
+	profile            *LoaderProfile
 }
 
 type StreamReceiver interface {
@@ -163,16 +222,152 @@ func (l *FlatDBTrieLoader) Reset(rd RetainDeciderWithMarker, hc HashCollector2,
 	l.ihSeek, l.accSeek, l.storageSeek, l.kHex, l.kHexS = make([]byte, 0, 128), make([]byte, 0, 128), make([]byte, 0, 128), make([]byte, 0, 128), make([]byte, 0, 128)
 	l.rd = rd
 	if l.trace {
-		fmt.Printf("----------\n")
-		fmt.Printf("CalcTrieRoot\n")
+		fmt.Printf("%s----------\n", l.defaultReceiver.tracePrefix)
+		fmt.Printf("%sCalcTrieRoot\n", l.defaultReceiver.tracePrefix)
 	}
 	return nil
 }
 
+// SetTraceID - adds `id` to trace lines of the loader and default receiver and to progress log of the loader,
+// to tell apart concurrent root calculations. Must be called before Reset. Empty id disables it
+func (l *FlatDBTrieLoader) SetTraceID(id string) {
+	l.traceID = id
+	l.defaultReceiver.tracePrefix = ""
+	if id != "" {
+		l.defaultReceiver.tracePrefix = "[" + id + "] "
+	}
+}
+
 func (l *FlatDBTrieLoader) SetStreamReceiver(receiver StreamReceiver) {
 	l.receiver = receiver
 }
 
+// SetStorageRootHandler - see StorageRootHandler. Works with default receiver only
+func (l *FlatDBTrieLoader) SetStorageRootHandler(h StorageRootHandler) {
+	l.defaultReceiver.SetStorageRootHandler(h)
+}
+
+// SetIgnoreIH - makes loader behave as if TrieOfAccounts and TrieOfStorage buckets were empty.
+// RetainDecider is still used as usual. Useful to measure contribution of intermediate hashes to performance
+func (l *FlatDBTrieLoader) SetIgnoreIH(ignore bool) {
+	l.ignoreIH = ignore
+}
+
+// SetCountBytesRead - enables BytesRead. Disabled by default, because it wraps every cursor of CalcTrieRoot
+func (l *FlatDBTrieLoader) SetCountBytesRead(count bool) {
+	l.countBytes = count
+}
+
+// BytesRead - total size of keys and values returned by db cursors during last CalcTrieRoot call,
+// including the ones which were read but not used (for example first key after the end of prefix).
+// Zero unless enabled by SetCountBytesRead
+func (l *FlatDBTrieLoader) BytesRead() uint64 {
+	return l.bytesRead
+}
+
+// ErrTooManyAccounts - see SetMaxAccounts
+var ErrTooManyAccounts = errors.New("too many accounts")
+
+// SetMaxAccounts - CalcTrieRoot returns ErrTooManyAccounts once it sends more than `n` accounts to receiver.
+// Hash of account sub-trie (AHashStreamItem) counts as one account, storage items are not counted at all,
+// so large contract doesn't trip the limit. Zero means no limit
+func (l *FlatDBTrieLoader) SetMaxAccounts(n int) {
+	l.maxAccounts = n
+}
+
+func (l *FlatDBTrieLoader) countAccount() error {
+	l.accounts++
+	if l.maxAccounts > 0 && l.accounts > l.maxAccounts {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyAccounts, l.maxAccounts)
+	}
+	return nil
+}
+
+// LoaderProgress - state of CalcTrieRoot passed to ProgressFunc
+type LoaderProgress struct {
+	Key     []byte         // hex key of the last account or account sub-trie hash sent to receiver, valid only during the call
+	Done    float64        // estimated part of the keys (under `prefix`) already walked, by the first nibbles of Key
+	Elapsed time.Duration  // since start of CalcTrieRoot
+	Stats   IterationStats // counters so far, durations are not filled yet
+}
+
+// ProgressFunc - see SetProgressFunc
+type ProgressFunc func(p LoaderProgress)
+
+// SetProgressFunc - makes CalcTrieRoot call `f` every `every` accounts sent to receiver (counted as in SetMaxAccounts).
+// It's called from the walk, so must be fast. nil `f` or zero `every` disables it
+func (l *FlatDBTrieLoader) SetProgressFunc(every int, f ProgressFunc) {
+	l.progressEvery = every
+	l.progressFunc = f
+}
+
+func (l *FlatDBTrieLoader) reportProgress(keyHex []byte) {
+	if l.progressFunc == nil || l.progressEvery <= 0 || l.accounts%l.progressEvery != 0 {
+		return
+	}
+	var done float64
+	if len(keyHex) > l.prefixLen {
+		done = keyProgress(keyHex[l.prefixLen:])
+	}
+	l.progressFunc(LoaderProgress{Key: keyHex, Done: done, Elapsed: time.Since(l.scanStart), Stats: l.stats})
+}
+
+// keyProgress - estimated part of the key space before the hex key, by its first 4 nibbles
+func keyProgress(hex []byte) float64 {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		v <<= 4
+		if i < len(hex) {
+			v |= uint32(hex[i] & 0x0F)
+		}
+	}
+	return float64(v) / (1 << 16)
+}
+
+// SetProfile - makes CalcTrieRoot record time of its phases into `p`, see LoaderProfile. GenStructStep and hashing
+// are recorded only with default receiver. nil disables profiling
+func (l *FlatDBTrieLoader) SetProfile(p *LoaderProfile) {
+	l.profile = p
+	l.defaultReceiver.setProfile(p)
+}
+
+// DumpStats - see IterationStats
+func (l *FlatDBTrieLoader) DumpStats() IterationStats {
+	return l.stats
+}
+
+// IHSource - opens cursors over intermediate hashes in the format of TrieOfAccounts and TrieOfStorage. See SetIHSource
+type IHSource func(tx kv.Tx) (accTrie kv.Cursor, storageTrie kv.CursorDupSort, err error)
+
+// SetIHSource - makes CalcTrieRoot read intermediate hashes from given source (for example from a saved copy of
+// TrieOfAccounts/TrieOfStorage, to reproduce root or proof exactly as it was computed), state is still read from `tx`.
+// Loader closes returned cursors. IH must match the state: hashes not matching the state give wrong root.
+// nil restores default TrieOfAccounts/TrieOfStorage of `tx`
+func (l *FlatDBTrieLoader) SetIHSource(source IHSource) {
+	l.ihSource = source
+}
+
+func (l *FlatDBTrieLoader) openIH(tx kv.Tx) (kv.Cursor, kv.CursorDupSort, error) {
+	if l.ihSource != nil {
+		return l.ihSource(tx)
+	}
+	trieAccC, err := tx.Cursor(kv.TrieOfAccounts)
+	if err != nil {
+		return nil, nil, err
+	}
+	trieStorageC, err := tx.CursorDupSort(kv.TrieOfStorage)
+	if err != nil {
+		trieAccC.Close()
+		return nil, nil, err
+	}
+	return trieAccC, trieStorageC, nil
+}
+
+// IHDepthStats - returns depths at which intermediate hashes were used by last CalcTrieRoot call (for its prefix)
+func (l *FlatDBTrieLoader) IHDepthStats() IHDepthStats {
+	return l.ihDepths
+}
+
 // CalcTrieRoot algo:
 //	for iterateIHOfAccounts {
 //		if canSkipState
@@ -195,42 +390,70 @@ func (l *FlatDBTrieLoader) SetStreamReceiver(receiver StreamReceiver) {
 //		use(AccTrie)
 //	}
 func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan struct{}) (common.Hash, error) {
+	l.ihDepths = IHDepthStats{}
+	l.accounts = 0
+	l.bytesRead = 0
+	l.stats = IterationStats{}
+	l.prefixLen = len(prefix)
+	scanStart := time.Now()
+	l.scanStart = scanStart
+	defer l.profile.end(l.profile.begin("CalcTrieRoot"))
 
 	accC, err := tx.Cursor(kv.HashedAccounts)
 	if err != nil {
 		return EmptyRoot, err
 	}
 	defer accC.Close()
-	accs := NewStateCursor(accC, quit)
-	trieAccC, err := tx.Cursor(kv.TrieOfAccounts)
+	trieAccC, trieStorageC, err := l.openIH(tx)
 	if err != nil {
 		return EmptyRoot, err
 	}
 	defer trieAccC.Close()
-	trieStorageC, err := tx.CursorDupSort(kv.TrieOfStorage)
+	defer trieStorageC.Close()
+	ssC, err := tx.CursorDupSort(kv.HashedStorage)
 	if err != nil {
 		return EmptyRoot, err
 	}
-	defer trieStorageC.Close()
+	defer ssC.Close()
+	var accCursor, trieAccCursor kv.Cursor = accC, trieAccC
+	var trieStorageCursor, ss kv.CursorDupSort = trieStorageC, ssC
+	if l.countBytes {
+		accCursor = countingCursor{accCursor, &l.bytesRead}
+		trieAccCursor = countingCursor{trieAccCursor, &l.bytesRead}
+		trieStorageCursor = countingDupCursor{trieStorageCursor, &l.bytesRead}
+		ss = countingDupCursor{ss, &l.bytesRead}
+	}
+	var receiver StreamReceiver = l.receiver
+	profiling := l.profile != nil
+	if profiling {
+		accCursor = profiledCursor{accCursor, l.profile, "accounts;seek", "accounts;next"}
+		trieAccCursor = profiledCursor{trieAccCursor, l.profile, "accounts;ih;seek", "accounts;ih;next"}
+		trieStorageCursor = profiledDupCursor{trieStorageCursor, l.profile, "storage;ih;seek", "storage;ih;next"}
+		ss = profiledDupCursor{ss, l.profile, "storage;seek", "storage;next"}
+		receiver = profiledReceiver{receiver, l.profile}
+	}
+	accs := NewStateCursor(accCursor, quit)
+	if l.ignoreIH {
+		trieAccCursor, trieStorageCursor = emptyTrieCursor{trieAccCursor}, emptyTrieDupCursor{trieStorageCursor}
+	}
 
 	var canUse = func(prefix []byte) (bool, []byte) {
 		retain, nextCreated := l.rd.RetainWithMarker(prefix)
+		if retain {
+			l.stats.IHSkippedCount++
+		}
 		return !retain, nextCreated
 	}
-	accTrie := AccTrie(canUse, l.hc, trieAccC, quit)
-	storageTrie := StorageTrie(canUse, l.shc, trieStorageC, quit)
+	accTrie := AccTrie(canUse, l.hc, trieAccCursor, quit)
+	storageTrie := StorageTrie(canUse, l.shc, trieStorageCursor, quit)
 
-	ss, err := tx.CursorDupSort(kv.HashedStorage)
-	if err != nil {
-		return EmptyRoot, err
-	}
-	defer ss.Close()
 	logEvery := time.NewTicker(30 * time.Second)
 	defer logEvery.Stop()
 	for ihK, ihV, hasTree, err := accTrie.AtPrefix(prefix); ; ihK, ihV, hasTree, err = accTrie.Next() { // no loop termination is at he end of loop
 		if err != nil {
 			return EmptyRoot, err
 		}
+		l.stats.TotalIterations++
 		if accTrie.SkipState {
 			goto SkipAccounts
 		}
@@ -242,12 +465,25 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan str
 			if keyIsBefore(ihK, kHex) || !bytes.HasPrefix(kHex, prefix) { // read all accounts until next AccTrie
 				break
 			}
-			if err = l.accountValue.DecodeForStorage(v); err != nil {
+			if profiling {
+				t := l.profile.begin("accounts;decode")
+				err = l.accountValue.DecodeForStorage(v)
+				l.profile.end(t)
+			} else {
+				err = l.accountValue.DecodeForStorage(v)
+			}
+			if err != nil {
 				return EmptyRoot, fmt.Errorf("fail DecodeForStorage: %w", err)
 			}
-			if err = l.receiver.Receive(AccountStreamItem, kHex, nil, &l.accountValue, nil, nil, false, 0); err != nil {
+			if err = l.countAccount(); err != nil {
+				return EmptyRoot, err
+			}
+			l.stats.AccountsScanned++
+			err = receiver.Receive(AccountStreamItem, kHex, nil, &l.accountValue, nil, nil, false, 0)
+			if err != nil {
 				return EmptyRoot, err
 			}
+			l.reportProgress(kHex)
 			if l.accountValue.Incarnation == 0 {
 				continue
 			}
@@ -271,7 +507,9 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan str
 					if keyIsBefore(ihKS, l.kHexS) { // read until next AccTrie
 						break
 					}
-					if err = l.receiver.Receive(StorageStreamItem, accWithInc, l.kHexS, nil, vS[32:], nil, false, 0); err != nil {
+					l.stats.StorageItemsScanned++
+					err = receiver.Receive(StorageStreamItem, accWithInc, l.kHexS, nil, vS[32:], nil, false, 0)
+					if err != nil {
 						return EmptyRoot, err
 					}
 				}
@@ -281,9 +519,12 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan str
 					break
 				}
 
-				if err = l.receiver.Receive(SHashStreamItem, accWithInc, ihKS, nil, nil, ihVS, hasTreeS, 0); err != nil {
+				err = receiver.Receive(SHashStreamItem, accWithInc, ihKS, nil, nil, ihVS, hasTreeS, 0)
+				if err != nil {
 					return EmptyRoot, err
 				}
+				l.ihDepths.Storage[len(ihKS)]++
+				l.stats.StorageHashesUsed++
 				if len(ihKS) == 0 { // means we just sent acc.storageRoot
 					break
 				}
@@ -301,26 +542,170 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan str
 			break
 		}
 
-		if err = l.receiver.Receive(AHashStreamItem, ihK, nil, nil, nil, ihV, hasTree, 0); err != nil {
+		if err = l.countAccount(); err != nil {
 			return EmptyRoot, err
 		}
+		err = receiver.Receive(AHashStreamItem, ihK, nil, nil, nil, ihV, hasTree, 0)
+		if err != nil {
+			return EmptyRoot, err
+		}
+		l.ihDepths.Accounts[len(ihK)]++
+		l.stats.AccountHashesUsed++
+		l.reportProgress(ihK)
 	}
+	l.stats.ScanDuration = time.Since(scanStart)
 
-	if err := l.receiver.Receive(CutoffStreamItem, nil, nil, nil, nil, nil, false, len(prefix)); err != nil {
+	finalizeStart := time.Now()
+	err = receiver.Receive(CutoffStreamItem, nil, nil, nil, nil, nil, false, len(prefix))
+	if err != nil {
 		return EmptyRoot, err
 	}
+	root := l.receiver.Root()
+	l.stats.FinalizeDuration = time.Since(finalizeStart)
+	return root, nil
+}
+
+// emptyTrieCursor, emptyTrieDupCursor - pretend that intermediate hashes bucket is empty, see SetIgnoreIH
+type emptyTrieCursor struct{ kv.Cursor }
+
+func (emptyTrieCursor) First() ([]byte, []byte, error)        { return nil, nil, nil }
+func (emptyTrieCursor) Seek(_ []byte) ([]byte, []byte, error) { return nil, nil, nil }
+
+type emptyTrieDupCursor struct{ kv.CursorDupSort }
+
+func (emptyTrieDupCursor) First() ([]byte, []byte, error)        { return nil, nil, nil }
+func (emptyTrieDupCursor) Seek(_ []byte) ([]byte, []byte, error) { return nil, nil, nil }
+
+// countingCursor, countingDupCursor - add size of every returned key and value to `n`, see BytesRead
+type countingCursor struct {
+	kv.Cursor
+	n *uint64
+}
+
+func (c countingCursor) count(k, v []byte, err error) ([]byte, []byte, error) {
+	*c.n += uint64(len(k) + len(v))
+	return k, v, err
+}
+
+func (c countingCursor) First() ([]byte, []byte, error) {
+	return c.count(c.Cursor.First())
+}
+
+func (c countingCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	return c.count(c.Cursor.Seek(seek))
+}
+
+func (c countingCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	return c.count(c.Cursor.SeekExact(key))
+}
+
+func (c countingCursor) Next() ([]byte, []byte, error) {
+	return c.count(c.Cursor.Next())
+}
+
+func (c countingCursor) Prev() ([]byte, []byte, error) {
+	return c.count(c.Cursor.Prev())
+}
+
+func (c countingCursor) Last() ([]byte, []byte, error) {
+	return c.count(c.Cursor.Last())
+}
+
+func (c countingCursor) Current() ([]byte, []byte, error) {
+	return c.count(c.Cursor.Current())
+}
+
+type countingDupCursor struct {
+	kv.CursorDupSort
+	n *uint64
+}
+
+func (c countingDupCursor) count(k, v []byte, err error) ([]byte, []byte, error) {
+	*c.n += uint64(len(k) + len(v))
+	return k, v, err
+}
+
+func (c countingDupCursor) countV(v []byte, err error) ([]byte, error) {
+	*c.n += uint64(len(v))
+	return v, err
+}
+
+func (c countingDupCursor) First() ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.First())
+}
+
+func (c countingDupCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.Seek(seek))
+}
+
+func (c countingDupCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.SeekExact(key))
+}
+
+func (c countingDupCursor) Next() ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.Next())
+}
+
+func (c countingDupCursor) Prev() ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.Prev())
+}
 
-	return l.receiver.Root(), nil
+func (c countingDupCursor) Last() ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.Last())
+}
+
+func (c countingDupCursor) Current() ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.Current())
+}
+
+func (c countingDupCursor) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.SeekBothExact(key, value))
+}
+
+func (c countingDupCursor) SeekBothRange(key, value []byte) ([]byte, error) {
+	return c.countV(c.CursorDupSort.SeekBothRange(key, value))
+}
+
+func (c countingDupCursor) FirstDup() ([]byte, error) {
+	return c.countV(c.CursorDupSort.FirstDup())
+}
+
+func (c countingDupCursor) NextDup() ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.NextDup())
+}
+
+func (c countingDupCursor) NextNoDup() ([]byte, []byte, error) {
+	return c.count(c.CursorDupSort.NextNoDup())
+}
+
+func (c countingDupCursor) LastDup() ([]byte, error) {
+	return c.countV(c.CursorDupSort.LastDup())
 }
 
 func (l *FlatDBTrieLoader) logProgress(accountKey, ihK []byte) {
 	var k string
+	var done float64
 	if accountKey != nil {
 		k = makeCurrentKeyStr(accountKey)
+		if len(accountKey) > 2 {
+			accountKey = accountKey[:2]
+		}
+		hexutil.DecompressNibbles(accountKey, &l.progressHex)
+		done = keyProgress(l.progressHex)
 	} else if ihK != nil {
 		k = makeCurrentKeyStr(ihK)
+		done = keyProgress(ihK)
+	}
+	logCtx := []interface{}{"current key", k, "done", fmt.Sprintf("%.1f%%", done*100)}
+	if done > 0 {
+		elapsed := time.Since(l.scanStart)
+		eta := time.Duration(float64(elapsed) * (1 - done) / done)
+		logCtx = append(logCtx, "eta", eta.Round(time.Second))
+	}
+	if l.traceID != "" {
+		logCtx = append(logCtx, "trace", l.traceID)
 	}
-	log.Info(fmt.Sprintf("[%s] Calculating Merkle root", l.logPrefix), "current key", k)
+	log.Info(fmt.Sprintf("[%s] Calculating Merkle root", l.logPrefix), logCtx...)
 }
 
 func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
@@ -386,6 +771,9 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 			r.hb.hashStack = append(append(r.hb.hashStack, byte(80+common.HashLength)), hash...)
 			r.hb.nodeStack = append(r.hb.nodeStack, nil)
 			r.accData.FieldSet |= AccountFieldStorageOnly
+			if err := r.onStorageRoot(accountKey, hash); err != nil {
+				return err
+			}
 			break
 		}
 		if len(r.currAccK) == 0 {
@@ -400,23 +788,8 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		r.saveValueStorage(true, hasTree, storageValue, hash)
 	case AccountStreamItem:
 		r.advanceKeysAccount(accountKey, true /* terminator */)
-		if r.curr.Len() > 0 && !r.wasIH {
-			r.cutoffKeysStorage(0)
-			if r.currStorage.Len() > 0 {
-				if err := r.genStructStorage(); err != nil {
-					return err
-				}
-			}
-			if r.currStorage.Len() > 0 {
-				r.groupsStorage = r.groupsStorage[:0]
-				r.hasTreeStorage = r.hasTreeStorage[:0]
-				r.hasHashStorage = r.hasHashStorage[:0]
-				r.currStorage.Reset()
-				r.succStorage.Reset()
-				r.wasIHStorage = false
-				// There are some storage items
-				r.accData.FieldSet |= AccountFieldStorageOnly
-			}
+		if err := r.finaliseStorageRoot(); err != nil {
+			return err
 		}
 		r.currAccK = r.currAccK[:0]
 		if r.curr.Len() > 0 {
@@ -429,23 +802,8 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 	case AHashStreamItem:
 		r.advanceKeysAccount(accountKey, false /* terminator */)
-		if r.curr.Len() > 0 && !r.wasIH {
-			r.cutoffKeysStorage(0)
-			if r.currStorage.Len() > 0 {
-				if err := r.genStructStorage(); err != nil {
-					return err
-				}
-			}
-			if r.currStorage.Len() > 0 {
-				r.groupsStorage = r.groupsStorage[:0]
-				r.hasTreeStorage = r.hasTreeStorage[:0]
-				r.hasHashStorage = r.hasHashStorage[:0]
-				r.currStorage.Reset()
-				r.succStorage.Reset()
-				r.wasIHStorage = false
-				// There are some storage items
-				r.accData.FieldSet |= AccountFieldStorageOnly
-			}
+		if err := r.finaliseStorageRoot(); err != nil {
+			return err
 		}
 		r.currAccK = r.currAccK[:0]
 		if r.curr.Len() > 0 {
@@ -458,26 +816,11 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 	case CutoffStreamItem:
 		if r.trace {
-			fmt.Printf("storage cuttoff %d\n", cutoff)
+			fmt.Printf("%sstorage cuttoff %d\n", r.tracePrefix, cutoff)
 		}
 		r.cutoffKeysAccount(cutoff)
-		if r.curr.Len() > 0 && !r.wasIH {
-			r.cutoffKeysStorage(0)
-			if r.currStorage.Len() > 0 {
-				if err := r.genStructStorage(); err != nil {
-					return err
-				}
-			}
-			if r.currStorage.Len() > 0 {
-				r.groupsStorage = r.groupsStorage[:0]
-				r.hasTreeStorage = r.hasTreeStorage[:0]
-				r.hasHashStorage = r.hasHashStorage[:0]
-				r.currStorage.Reset()
-				r.succStorage.Reset()
-				r.wasIHStorage = false
-				// There are some storage items
-				r.accData.FieldSet |= AccountFieldStorageOnly
-			}
+		if err := r.finaliseStorageRoot(); err != nil {
+			return err
 		}
 		if r.curr.Len() > 0 {
 			if err := r.genStructAccount(); err != nil {
@@ -522,6 +865,56 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 // 	}
 // }
 
+// finaliseStorageRoot - completes storage trie of current account (if it has storage),
+// storage root stays on top of the hash builder stack and will be used by account leaf
+func (r *RootHashAggregator) finaliseStorageRoot() error {
+	if r.curr.Len() == 0 || r.wasIH {
+		return nil
+	}
+	r.cutoffKeysStorage(0)
+	if r.currStorage.Len() == 0 {
+		return nil
+	}
+	if err := r.genStructStorage(); err != nil {
+		return err
+	}
+	r.groupsStorage = r.groupsStorage[:0]
+	r.hasTreeStorage = r.hasTreeStorage[:0]
+	r.hasHashStorage = r.hasHashStorage[:0]
+	r.currStorage.Reset()
+	r.succStorage.Reset()
+	r.wasIHStorage = false
+	// There are some storage items
+	r.accData.FieldSet |= AccountFieldStorageOnly
+	return r.onStorageRoot(r.currAccK, r.hb.topHash())
+}
+
+// StorageRootHandler - called each time storage trie of an account is complete (computed from state or taken from TrieOfStorage).
+// Returning error aborts CalcTrieRoot
+type StorageRootHandler func(addrHash common.Hash, incarnation uint64, storageRoot common.Hash) error
+
+// setProfile - records GenStructStep and hashing into `p`, see FlatDBTrieLoader.SetProfile
+func (r *RootHashAggregator) setProfile(p *LoaderProfile) {
+	r.profile = p
+	if sha, ok := r.hb.sha.(profiledKeccak); ok {
+		r.hb.sha = sha.keccakState
+	}
+	if p != nil {
+		r.hb.sha = profiledKeccak{r.hb.sha, p}
+	}
+}
+
+func (r *RootHashAggregator) SetStorageRootHandler(h StorageRootHandler) {
+	r.storageRootHandler = h
+}
+
+func (r *RootHashAggregator) onStorageRoot(accWithInc, root []byte) error {
+	if r.storageRootHandler == nil {
+		return nil
+	}
+	return r.storageRootHandler(common.BytesToHash(accWithInc[:common.HashLength]), binary.BigEndian.Uint64(accWithInc[common.HashLength:]), common.BytesToHash(root))
+}
+
 func (r *RootHashAggregator) Result() SubTries {
 	panic("don't call me")
 }
@@ -553,6 +946,7 @@ func (r *RootHashAggregator) cutoffKeysStorage(cutoff int) {
 }
 
 func (r *RootHashAggregator) genStructStorage() error {
+	defer r.profile.end(r.profile.begin("genStructStorage"))
 	var err error
 	var data GenStructStepData
 	if r.wasIHStorage {
@@ -560,6 +954,9 @@ func (r *RootHashAggregator) genStructStorage() error {
 		r.hashData.HasTree = r.hadTreeStorage
 		data = &r.hashData
 	} else {
+		if err = rlphacks.ValidateStorageValue(r.valueStorage); err != nil {
+			return fmt.Errorf("account %x, storage %x: %w", r.currAccK, r.currStorage.Bytes(), err)
+		}
 		r.leafData.Value = rlphacks.RlpSerializableBytes(r.valueStorage)
 		data = &r.leafData
 	}
@@ -610,6 +1007,7 @@ func (r *RootHashAggregator) cutoffKeysAccount(cutoff int) {
 }
 
 func (r *RootHashAggregator) genStructAccount() error {
+	defer r.profile.end(r.profile.begin("genStructAccount"))
 	var data GenStructStepData
 	if r.wasIH {
 		r.hashData.Hash = r.hashAccount
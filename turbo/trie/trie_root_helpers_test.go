@@ -0,0 +1,74 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func addTestAccount(tx kv.Putter, hash common.Hash, balance uint64, incarnation uint64) error {
+	acc := accounts.NewAccount()
+	acc.Balance.SetUint64(balance)
+	acc.Incarnation = incarnation
+	if incarnation != 0 {
+		acc.CodeHash = common.HexToHash("0x5be74cad16203c4905c068b012a2e9fb6d19d036c410f16fd177f337541440dd")
+	}
+	encoded := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(encoded)
+	return tx.Put(kv.HashedAccounts, hash[:], encoded)
+}
+
+// testTrieIncarnation - incarnation of the account with storage in setupTestTrie
+const testTrieIncarnation = uint64(1)
+
+// setupTestTrie - writes accounts 0xB000.., 0xB040.., 0xB041.. (with 4 storage slots) and 0xB1A0.. to hashed state
+// and generates intermediate hashes for them. Returns root of the trie and hash of the account with storage
+func setupTestTrie(t *testing.T, tx kv.RwTx) (root common.Hash, withStorage common.Hash) {
+	t.Helper()
+	hash1 := common.HexToHash("0xB000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, addTestAccount(tx, hash1, 3*params.Ether, 0))
+	hash2 := common.HexToHash("0xB040000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, addTestAccount(tx, hash2, 1*params.Ether, 0))
+	withStorage = common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, addTestAccount(tx, withStorage, 2*params.Ether, testTrieIncarnation))
+	for i, loc := range []string{
+		"0x1200000000000000000000000000000000000000000000000000000000000000",
+		"0x1400000000000000000000000000000000000000000000000000000000000000",
+		"0x3000000000000000000000000000000000000000000000000000000000E00000",
+		"0x3000000000000000000000000000000000000000000000000000000000E00001",
+	} {
+		key := dbutils.GenerateCompositeStorageKey(withStorage, testTrieIncarnation, common.HexToHash(loc))
+		require.NoError(t, tx.Put(kv.HashedStorage, key, []byte{byte(i + 1)}))
+	}
+	hash4 := common.HexToHash("0xB1A0000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, addTestAccount(tx, hash4, 4*params.Ether, 0))
+
+	return regenerateTestIH(t, tx), withStorage
+}
+
+// regenerateTestIH - rebuilds TrieOfAccounts and TrieOfStorage from the hashed state, the same way as stage_trie does
+// it from scratch. Returns root of the trie
+func regenerateTestIH(t *testing.T, tx kv.RwTx) common.Hash {
+	t.Helper()
+	require.NoError(t, tx.ClearBucket(kv.TrieOfAccounts))
+	require.NoError(t, tx.ClearBucket(kv.TrieOfStorage))
+
+	accTrieCollector := etl.NewCollector("IH", t.TempDir(), etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer accTrieCollector.Close()
+	stTrieCollector := etl.NewCollector("IH", t.TempDir(), etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer stTrieCollector.Close()
+
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), AccountTrieCollector(accTrieCollector), StorageTrieCollector(stTrieCollector), false))
+	root, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.NoError(t, accTrieCollector.Load(tx, kv.TrieOfAccounts, etl.IdentityLoadFunc, etl.TransformArgs{}))
+	require.NoError(t, stTrieCollector.Load(tx, kv.TrieOfStorage, etl.IdentityLoadFunc, etl.TransformArgs{}))
+	return root
+}
@@ -0,0 +1,274 @@
+package trie
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+// LoaderProfile - wall time spent by FlatDBTrieLoader.CalcTrieRoot in each phase, see FlatDBTrieLoader.SetProfile.
+// Spans are identified by stacks of frames separated by ';':
+//
+//	CalcTrieRoot;accounts;seek         - HashedAccounts cursor (Seek/First), `next` - Next
+//	CalcTrieRoot;accounts;decode       - DecodeForStorage of account
+//	CalcTrieRoot;accounts;receive      - receiver.Receive of account, `accounts;ih;receive` - of account sub-trie hash
+//	CalcTrieRoot;accounts;ih;seek      - TrieOfAccounts cursor, `storage;ih;seek` - TrieOfStorage cursor
+//	CalcTrieRoot;storage;seek          - HashedStorage cursor, `storage;receive` - receiver.Receive of storage
+//	...;receive;genStructAccount;keccak - GenStructStep over accounts (genStructStorage - over storage) and hashing in it
+//
+// Time of the span includes time of its children. Profile accumulates over calls until Reset.
+// Measuring every cursor operation is expensive - don't use it in production, only for performance investigations.
+// Not thread-safe
+type LoaderProfile struct {
+	totals map[string]time.Duration // inclusive time by stack
+	open   []string                 // stacks of spans which are not ended yet, last one is innermost
+	stacks map[profileFrame]string  // stack of frame by its parent, to not concatenate strings on every span
+}
+
+type profileFrame struct {
+	parent, name string
+}
+
+func NewLoaderProfile() *LoaderProfile {
+	return &LoaderProfile{
+		totals: map[string]time.Duration{},
+		stacks: map[profileFrame]string{},
+	}
+}
+
+// Reset - forgets all recorded spans
+func (p *LoaderProfile) Reset() {
+	p.totals = map[string]time.Duration{}
+	p.open = p.open[:0]
+}
+
+// begin - opens span `name` (may consist of several frames) inside of the current span. nil profile does nothing
+func (p *LoaderProfile) begin(name string) time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	var parent string
+	if len(p.open) > 0 {
+		parent = p.open[len(p.open)-1]
+	}
+	stack, ok := p.stacks[profileFrame{parent, name}]
+	if !ok {
+		stack = name
+		if parent != "" {
+			stack = parent + ";" + name
+		}
+		p.stacks[profileFrame{parent, name}] = stack
+	}
+	p.open = append(p.open, stack)
+	return time.Now()
+}
+
+// end - closes the innermost span, `start` is the value returned by its begin
+func (p *LoaderProfile) end(start time.Time) {
+	if p == nil {
+		return
+	}
+	d := time.Since(start)
+	stack := p.open[len(p.open)-1]
+	p.open = p.open[:len(p.open)-1]
+	p.totals[stack] += d
+}
+
+// ProfileNode - frame of LoaderProfile.Tree
+type ProfileNode struct {
+	Name     string
+	Total    time.Duration  // including children
+	Children []*ProfileNode // sorted by Name
+}
+
+// Self - time spent in the frame itself, not in its children
+func (n *ProfileNode) Self() time.Duration {
+	self := n.Total
+	for _, c := range n.Children {
+		self -= c.Total
+	}
+	if self < 0 { // children measured separately, so their sum can slightly exceed measured total
+		return 0
+	}
+	return self
+}
+
+// Tree - recorded spans as a tree of frames. Root has empty Name and all top-level frames as children
+func (p *LoaderProfile) Tree() *ProfileNode {
+	root := &ProfileNode{}
+	nodes := map[string]*ProfileNode{"": root}
+	var node func(stack string) *ProfileNode
+	node = func(stack string) *ProfileNode {
+		if n, ok := nodes[stack]; ok {
+			return n
+		}
+		parent, name := "", stack
+		if i := strings.LastIndexByte(stack, ';'); i >= 0 {
+			parent, name = stack[:i], stack[i+1:]
+		}
+		n := &ProfileNode{Name: name}
+		pn := node(parent)
+		pn.Children = append(pn.Children, n)
+		nodes[stack] = n
+		return n
+	}
+	recorded := map[*ProfileNode]bool{}
+	for stack, d := range p.totals {
+		n := node(stack)
+		n.Total = d
+		recorded[n] = true
+	}
+
+	// frames like `accounts` have no span of their own - their time is time of their children
+	var fill func(n *ProfileNode)
+	fill = func(n *ProfileNode) {
+		sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+		var sum time.Duration
+		for _, c := range n.Children {
+			fill(c)
+			sum += c.Total
+		}
+		if !recorded[n] {
+			n.Total = sum
+		}
+	}
+	fill(root)
+	return root
+}
+
+// WriteFolded - writes profile in the folded stacks format (one `frame1;frame2;frame3 value` line per stack),
+// accepted by flamegraph.pl, inferno, speedscope. Value is self time of the stack in nanoseconds
+func (p *LoaderProfile) WriteFolded(w io.Writer) error {
+	var write func(stack string, n *ProfileNode) error
+	write = func(stack string, n *ProfileNode) error {
+		if self := n.Self(); self > 0 {
+			if _, err := fmt.Fprintf(w, "%s %d\n", stack, self.Nanoseconds()); err != nil {
+				return err
+			}
+		}
+		for _, c := range n.Children {
+			if err := write(stack+";"+c.Name, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, c := range p.Tree().Children {
+		if err := write(c.Name, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// profiledCursor, profiledDupCursor - record cursor operations used by CalcTrieRoot into LoaderProfile
+type profiledCursor struct {
+	kv.Cursor
+	p          *LoaderProfile
+	seek, next string
+}
+
+func (c profiledCursor) First() ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.seek))
+	return c.Cursor.First()
+}
+
+func (c profiledCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.seek))
+	return c.Cursor.Seek(seek)
+}
+
+func (c profiledCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.seek))
+	return c.Cursor.SeekExact(key)
+}
+
+func (c profiledCursor) Next() ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.next))
+	return c.Cursor.Next()
+}
+
+type profiledDupCursor struct {
+	kv.CursorDupSort
+	p          *LoaderProfile
+	seek, next string
+}
+
+func (c profiledDupCursor) First() ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.seek))
+	return c.CursorDupSort.First()
+}
+
+func (c profiledDupCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.seek))
+	return c.CursorDupSort.Seek(seek)
+}
+
+func (c profiledDupCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.seek))
+	return c.CursorDupSort.SeekExact(key)
+}
+
+func (c profiledDupCursor) SeekBothRange(key, value []byte) ([]byte, error) {
+	defer c.p.end(c.p.begin(c.seek))
+	return c.CursorDupSort.SeekBothRange(key, value)
+}
+
+func (c profiledDupCursor) Next() ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.next))
+	return c.CursorDupSort.Next()
+}
+
+func (c profiledDupCursor) NextDup() ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.next))
+	return c.CursorDupSort.NextDup()
+}
+
+func (c profiledDupCursor) NextNoDup() ([]byte, []byte, error) {
+	defer c.p.end(c.p.begin(c.next))
+	return c.CursorDupSort.NextNoDup()
+}
+
+// profiledKeccak - records hashing done by HashBuilder into LoaderProfile
+type profiledKeccak struct {
+	keccakState
+	p *LoaderProfile
+}
+
+func (h profiledKeccak) Write(b []byte) (int, error) {
+	defer h.p.end(h.p.begin("keccak"))
+	return h.keccakState.Write(b)
+}
+
+func (h profiledKeccak) Read(b []byte) (int, error) {
+	defer h.p.end(h.p.begin("keccak"))
+	return h.keccakState.Read(b)
+}
+
+// profiledReceiver - records items sent by CalcTrieRoot to StreamReceiver into LoaderProfile
+type profiledReceiver struct {
+	StreamReceiver
+	p *LoaderProfile
+}
+
+func (r profiledReceiver) Receive(itemType StreamItem, accountKey []byte, storageKey []byte, accountValue *accounts.Account,
+	storageValue []byte, hash []byte, hasTree bool, cutoff int) error {
+	span := "receive"
+	switch itemType {
+	case AccountStreamItem:
+		span = "accounts;receive"
+	case AHashStreamItem:
+		span = "accounts;ih;receive"
+	case StorageStreamItem:
+		span = "storage;receive"
+	case SHashStreamItem:
+		span = "storage;ih;receive"
+	}
+	defer r.p.end(r.p.begin(span))
+	return r.StreamReceiver.Receive(itemType, accountKey, storageKey, accountValue, storageValue, hash, hasTree, cutoff)
+}
@@ -0,0 +1,82 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+)
+
+// StateKV - item of the hashed state stream, see CalcTrieRootFromStream
+type StateKV struct {
+	K, V []byte
+}
+
+// CalcTrieRootFromStream - same as CalcTrieRoot, but state comes from the stream instead of HashedAccounts/HashedStorage
+// cursors (for example from the snapshot file). Stream must be sorted by key:
+//   - account: key is addrHash (32 bytes), value is account encoded for storage (as in HashedAccounts)
+//   - storage: key is addrHash+incarnation+locHash (72 bytes), value is slot value without leading zeroes
+//
+// Intermediate hashes are not used (there are no cursors to read them from), so RetainDecider and prefix
+// are not applicable - it always computes the root of the whole trie. HashCollector and StorageRootHandler work as usual.
+// Storage which doesn't belong to the current incarnation of the preceding account is skipped, as in CalcTrieRoot.
+// Stream is not drained in case of error.
+func (l *FlatDBTrieLoader) CalcTrieRootFromStream(kvs <-chan StateKV, quit <-chan struct{}) (common.Hash, error) {
+	l.ihDepths = IHDepthStats{}
+	l.accounts = 0
+	l.bytesRead = 0
+
+	var prev []byte
+	var accWithInc []byte // nil if the current account has no storage
+	for item := range kvs {
+		if err := libcommon.Stopped(quit); err != nil {
+			return EmptyRoot, err
+		}
+		if prev != nil && bytes.Compare(prev, item.K) >= 0 {
+			return EmptyRoot, fmt.Errorf("stream is not sorted: %x after %x", item.K, prev)
+		}
+		prev = append(prev[:0], item.K...)
+
+		switch len(item.K) {
+		case common.HashLength:
+			if err := l.accountValue.DecodeForStorage(item.V); err != nil {
+				return EmptyRoot, fmt.Errorf("fail DecodeForStorage: %w", err)
+			}
+			if err := l.countAccount(); err != nil {
+				return EmptyRoot, err
+			}
+			hexutil.DecompressNibbles(item.K, &l.kHex)
+			if err := l.receiver.Receive(AccountStreamItem, l.kHex, nil, &l.accountValue, nil, nil, false, 0); err != nil {
+				return EmptyRoot, err
+			}
+			accWithInc = nil
+			if l.accountValue.Incarnation == 0 {
+				continue
+			}
+			copy(l.accAddrHashWithInc[:], item.K)
+			binary.BigEndian.PutUint64(l.accAddrHashWithInc[32:], l.accountValue.Incarnation)
+			accWithInc = l.accAddrHashWithInc[:]
+		case common.StorageKeyLen:
+			if accWithInc == nil || !bytes.HasPrefix(item.K, accWithInc) {
+				continue
+			}
+			hexutil.DecompressNibbles(item.K[len(accWithInc):], &l.kHexS)
+			if err := l.receiver.Receive(StorageStreamItem, accWithInc, l.kHexS, nil, item.V, nil, false, 0); err != nil {
+				return EmptyRoot, err
+			}
+		default:
+			return EmptyRoot, fmt.Errorf("unexpected key length %d: %x", len(item.K), item.K)
+		}
+	}
+
+	if err := libcommon.Stopped(quit); err != nil {
+		return EmptyRoot, err
+	}
+	if err := l.receiver.Receive(CutoffStreamItem, nil, nil, nil, nil, nil, false, 0); err != nil {
+		return EmptyRoot, err
+	}
+	return l.receiver.Root(), nil
+}
@@ -0,0 +1,72 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcTrieRootFromStream(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	var stream []StateKV
+	putAcc := func(addrHash common.Hash, balance uint64, incarnation uint64) {
+		acc := accounts.NewAccount()
+		acc.Balance.SetUint64(balance)
+		acc.Incarnation = incarnation
+		encoded := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(encoded)
+		require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+		stream = append(stream, StateKV{K: addrHash[:], V: encoded})
+	}
+	putStorage := func(addrHash common.Hash, incarnation uint64, locHash common.Hash, value uint64) {
+		v := uint256.NewInt(value).Bytes()
+		k := dbutils.GenerateCompositeStorageKey(addrHash, incarnation, locHash)
+		require.NoError(t, tx.Put(kv.HashedStorage, k, v))
+		stream = append(stream, StateKV{K: k, V: v})
+	}
+
+	putAcc(common.HexToHash("0x0100000000000000000000000000000000000000000000000000000000000000"), 1, 0)
+	contract := common.HexToHash("0x0200000000000000000000000000000000000000000000000000000000000000")
+	putAcc(contract, 2, 2)
+	putStorage(contract, 1, common.HexToHash("0x01"), 10) // previous incarnation, not part of the root
+	putStorage(contract, 2, common.HexToHash("0x01"), 11)
+	putStorage(contract, 2, common.HexToHash("0x1002"), 12)
+	putStorage(contract, 2, common.HexToHash("0x1003"), 13)
+	putAcc(common.HexToHash("0x0201000000000000000000000000000000000000000000000000000000000000"), 3, 0)
+	putAcc(common.HexToHash("0xff00000000000000000000000000000000000000000000000000000000000000"), 4, 0)
+
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	expected, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(t, err)
+
+	send := func(items []StateKV) <-chan StateKV {
+		ch := make(chan StateKV, len(items))
+		for _, item := range items {
+			ch <- item
+		}
+		close(ch)
+		return ch
+	}
+
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	root, err := loader.CalcTrieRootFromStream(send(stream), nil)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	root, err = loader.CalcTrieRootFromStream(send(nil), nil)
+	require.NoError(t, err)
+	require.Equal(t, EmptyRoot, root)
+
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	_, err = loader.CalcTrieRootFromStream(send([]StateKV{stream[1], stream[0]}), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,464 @@
+package trie
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/rlphacks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderProfileFolded(t *testing.T) {
+	p := NewLoaderProfile()
+	p.totals["CalcTrieRoot"] = 100
+	p.totals["CalcTrieRoot;accounts;seek"] = 10
+	p.totals["CalcTrieRoot;accounts;receive"] = 50
+	p.totals["CalcTrieRoot;accounts;receive;genStructAccount"] = 30
+	p.totals["CalcTrieRoot;accounts;receive;genStructAccount;keccak"] = 20
+	p.totals["CalcTrieRoot;storage;receive"] = 40
+
+	root := p.Tree()
+	require.Equal(t, 1, len(root.Children))
+	calc := root.Children[0]
+	require.Equal(t, "CalcTrieRoot", calc.Name)
+	require.Equal(t, []string{"accounts", "storage"}, []string{calc.Children[0].Name, calc.Children[1].Name})
+	require.Equal(t, time.Duration(60), calc.Children[0].Total)
+	require.Equal(t, time.Duration(0), calc.Self())
+
+	var buf bytes.Buffer
+	require.NoError(t, p.WriteFolded(&buf))
+	require.Equal(t, `CalcTrieRoot;accounts;receive 20
+CalcTrieRoot;accounts;receive;genStructAccount 10
+CalcTrieRoot;accounts;receive;genStructAccount;keccak 20
+CalcTrieRoot;accounts;seek 10
+CalcTrieRoot;storage;receive 40
+`, buf.String())
+}
+
+func TestLoaderProfile(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	for i := 0; i < 16; i++ {
+		acc := accounts.NewAccount()
+		acc.Balance.SetUint64(uint64(i + 1))
+		acc.Incarnation = uint64(i % 2)
+		encoded := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(encoded)
+		addrHash := common.Hash{byte(i << 4)}
+		require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+		if acc.Incarnation == 0 {
+			continue
+		}
+		for j := 0; j < 4; j++ {
+			k := dbutils.GenerateCompositeStorageKey(addrHash, acc.Incarnation, common.Hash{byte(j << 4)})
+			require.NoError(t, tx.Put(kv.HashedStorage, k, uint256.NewInt(uint64(j+1)).Bytes()))
+		}
+	}
+
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	expected, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(t, err)
+
+	p := NewLoaderProfile()
+	loader.SetProfile(p)
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	root, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+	require.Empty(t, p.open)
+
+	for _, stack := range []string{
+		"CalcTrieRoot;accounts;seek",
+		"CalcTrieRoot;accounts;next",
+		"CalcTrieRoot;accounts;decode",
+		"CalcTrieRoot;accounts;receive;genStructAccount;keccak",
+		"CalcTrieRoot;storage;seek",
+		"CalcTrieRoot;storage;next",
+		"CalcTrieRoot;storage;receive;genStructStorage",
+		"CalcTrieRoot;storage;ih;seek",
+	} {
+		_, ok := p.totals[stack]
+		require.True(t, ok, stack)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, p.WriteFolded(&buf))
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		require.Regexp(t, `^CalcTrieRoot(;[a-zA-Z]+)* [0-9]+$`, line)
+	}
+
+	loader.SetProfile(nil)
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	root, err = loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+	_, ok := loader.defaultReceiver.hb.sha.(profiledKeccak)
+	require.False(t, ok)
+}
+
+func TestHashBuilderHashLength(t *testing.T) {
+	hb := NewHashBuilder(false)
+	require.Error(t, hb.hash(make([]byte, common.HashLength-1)))
+	require.Empty(t, hb.hashStack)
+	require.NoError(t, hb.hash(make([]byte, common.HashLength)))
+	require.Equal(t, 1+common.HashLength, len(hb.hashStack))
+}
+
+func TestCalcTrieRootTruncatedCodeHash(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	acc := accounts.NewAccount()
+	acc.Incarnation = 1
+	acc.CodeHash = common.HexToHash("0xc0de")
+	encoded := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(encoded)
+	addrHash := common.HexToHash("0x01")
+	require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded[:len(encoded)-1]))
+
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	_, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.Error(t, err)
+}
+
+func TestCalcTrieRootUntrimmedStorageValue(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	acc := accounts.NewAccount()
+	acc.Incarnation = 1
+	encoded := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(encoded)
+	addrHash := common.HexToHash("0x01")
+	require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+	k := dbutils.GenerateCompositeStorageKey(addrHash, acc.Incarnation, common.Hash{0x10})
+	require.NoError(t, tx.Put(kv.HashedStorage, k, []byte{0, 1}))
+
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	_, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.ErrorIs(t, err, rlphacks.ErrStorageValueNotTrimmed)
+}
+
+func TestIHDepthStats(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	acc := accounts.NewAccount()
+	acc.Balance.SetUint64(1 * params.Ether)
+	encoded := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(encoded)
+	for _, h := range []string{
+		"0x30af561000000000000000000000000000000000000000000000000000000000",
+		"0x30af569000000000000000000000000000000000000000000000000000000000",
+		"0x30af650000000000000000000000000000000000000000000000000000000000",
+		"0x30af6f0000000000000000000000000000000000000000000000000000000000",
+		"0x30af8f0000000000000000000000000000000000000000000000000000000000",
+		"0x3100000000000000000000000000000000000000000000000000000000000000",
+	} {
+		hash := common.HexToHash(h)
+		require.NoError(t, tx.Put(kv.HashedAccounts, hash[:], encoded))
+	}
+
+	expectedRoot := regenerateTestIH(t, tx)
+
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	root, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+
+	stats := loader.IHDepthStats()
+	var used uint64
+	for _, n := range stats.Accounts {
+		used += n
+	}
+	require.NotZero(t, used)
+	require.Greater(t, stats.AvgAccountDepth(), float64(0))
+	require.Zero(t, stats.AvgStorageDepth())
+}
+
+func TestIgnoreIH(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	expectedRoot, _ := setupTestTrie(t, tx)
+
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	loader.SetIgnoreIH(true)
+	root, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	require.Equal(t, IHDepthStats{}, loader.IHDepthStats())
+}
+
+func TestIterationStats(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	_, hash3 := setupTestTrie(t, tx)
+
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	loader.SetIgnoreIH(true)
+	_, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	stats := loader.DumpStats()
+	require.Equal(t, uint64(4), stats.AccountsScanned)
+	require.Equal(t, uint64(4), stats.StorageItemsScanned)
+	require.Zero(t, stats.AccountHashesUsed)
+	require.Zero(t, stats.IHSkippedCount)
+	require.NotZero(t, stats.TotalIterations)
+
+	loader.SetIgnoreIH(false)
+	_, err = loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	stats = loader.DumpStats()
+	require.Less(t, stats.AccountsScanned, uint64(4))
+	require.NotZero(t, stats.AccountHashesUsed)
+	require.Zero(t, stats.IHSkippedCount)
+
+	// account 3 and its storage are retained, so IH on their path can't be used
+	rl := NewRetainList(0)
+	rl.AddKey(hash3[:])
+	require.NoError(t, loader.Reset(rl, nil, nil, false))
+	_, err = loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	stats = loader.DumpStats()
+	require.NotZero(t, stats.AccountsScanned)
+	require.NotZero(t, stats.IHSkippedCount)
+}
+
+func TestStorageRootHandler(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	_, hash2 := setupTestTrie(t, tx)
+
+	storageRoots := func(ignoreIH bool) map[common.Hash]common.Hash {
+		roots := map[common.Hash]common.Hash{}
+		rl := NewRetainList(0)
+		rl.AddKey(hash2[:]) // make sure account itself is visited, its storage root may come from TrieOfStorage
+		loader := NewFlatDBTrieLoader("IH")
+		require.NoError(t, loader.Reset(rl, nil, nil, false))
+		loader.SetIgnoreIH(ignoreIH)
+		loader.SetStorageRootHandler(func(addrHash common.Hash, inc uint64, root common.Hash) error {
+			require.Equal(t, testTrieIncarnation, inc)
+			roots[addrHash] = root
+			return nil
+		})
+		_, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+		require.NoError(t, err)
+		return roots
+	}
+	fromState := storageRoots(true)
+	require.Equal(t, 1, len(fromState))
+	require.NotEqual(t, EmptyRoot, fromState[hash2])
+	require.Equal(t, fromState, storageRoots(false))
+}
+
+func TestMaxAccounts(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	setupTestTrie(t, tx)
+
+	calc := func(max int) error {
+		loader := NewFlatDBTrieLoader("IH")
+		require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+		loader.SetIgnoreIH(true) // every account is counted, storage is not
+		loader.SetMaxAccounts(max)
+		_, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+		return err
+	}
+	require.NoError(t, calc(0))
+	require.NoError(t, calc(4))
+	require.ErrorIs(t, calc(3), ErrTooManyAccounts)
+}
+
+func TestProgressFunc(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	hashes := []common.Hash{
+		common.HexToHash("0x4000000000000000000000000000000000000000000000000000000000000000"),
+		common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000"),
+		common.HexToHash("0xB000000000000000000000000000000000000000000000000000000000000000"),
+		common.HexToHash("0xC000000000000000000000000000000000000000000000000000000000000000"),
+	}
+	for i, hash := range hashes {
+		require.NoError(t, addTestAccount(tx, hash, uint64(i+1)*params.Ether, 0))
+	}
+
+	var reported []LoaderProgress
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	loader.SetProgressFunc(2, func(p LoaderProgress) {
+		p.Key = common.CopyBytes(p.Key)
+		reported = append(reported, p)
+	})
+	_, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(reported))
+	require.Equal(t, 0.5, reported[0].Done) // after 0x80...
+	require.Equal(t, uint64(2), reported[0].Stats.AccountsScanned)
+	require.Equal(t, 0.75, reported[1].Done) // after 0xC0...
+	require.Equal(t, uint64(4), reported[1].Stats.AccountsScanned)
+
+	reported = nil
+	loader.SetProgressFunc(0, nil)
+	_, err = loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Empty(t, reported)
+}
+
+func TestBytesRead(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	setupTestTrie(t, tx)
+
+	var stateSize uint64
+	for _, table := range []string{kv.HashedAccounts, kv.HashedStorage} {
+		require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+			stateSize += uint64(len(v))
+			if table == kv.HashedAccounts {
+				stateSize += uint64(len(k))
+			}
+			return nil
+		}))
+	}
+
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	_, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Zero(t, loader.BytesRead())
+
+	loader.SetCountBytesRead(true)
+	_, err = loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.NotZero(t, loader.BytesRead())
+
+	// without intermediate hashes every state record is read
+	loader.SetIgnoreIH(true)
+	_, err = loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, loader.BytesRead(), stateSize)
+}
+
+func TestIHSource(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	_, snapshotTx := memdb.NewTestTx(t)
+	expectedRoot, _ := setupTestTrie(t, tx)
+
+	// move intermediate hashes to the other db
+	for _, table := range []string{kv.TrieOfAccounts, kv.TrieOfStorage} {
+		require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+			return snapshotTx.Put(table, common.CopyBytes(k), common.CopyBytes(v))
+		}))
+		require.NoError(t, tx.ClearBucket(table))
+	}
+
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	loader.SetIHSource(func(kv.Tx) (kv.Cursor, kv.CursorDupSort, error) {
+		accTrie, err := snapshotTx.Cursor(kv.TrieOfAccounts)
+		if err != nil {
+			return nil, nil, err
+		}
+		storageTrie, err := snapshotTx.CursorDupSort(kv.TrieOfStorage)
+		if err != nil {
+			accTrie.Close()
+			return nil, nil, err
+		}
+		return accTrie, storageTrie, nil
+	})
+	root, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	require.NotEqual(t, IHDepthStats{}, loader.IHDepthStats())
+
+	// live buckets are empty now
+	loader.SetIHSource(nil)
+	root, err = loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	require.Equal(t, IHDepthStats{}, loader.IHDepthStats())
+}
+
+func TestVerifyStateRoot(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	expectedRoot, hash3 := setupTestTrie(t, tx)
+
+	require.NoError(t, VerifyStateRoot(tx, expectedRoot, false, nil))
+	require.NoError(t, VerifyStateRoot(tx, expectedRoot, true, nil))
+	require.ErrorIs(t, VerifyStateRoot(tx, common.Hash{1}, false, nil), ErrStateRootMismatch)
+
+	// change of the storage not reflected in intermediate hashes is noticed only in strict mode
+	loc := common.HexToHash("0x3000000000000000000000000000000000000000000000000000000000E00002")
+	require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, testTrieIncarnation, loc), []byte{5}))
+	require.NoError(t, VerifyStateRoot(tx, expectedRoot, false, nil))
+	require.ErrorIs(t, VerifyStateRoot(tx, expectedRoot, true, nil), ErrStateRootMismatch)
+}
+
+func TestStorageRetainDecider(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	cachedRoot, hash2 := setupTestTrie(t, tx)
+
+	// storage under nibble 1 changes, but TrieOfStorage still has the old hash of this sub-trie
+	loc := common.HexToHash("0x1500000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash2, testTrieIncarnation, loc), []byte{5}))
+
+	calcRoot := func(ignoreIH bool, retain ...[]byte) common.Hash {
+		rl := NewRetainList(0)
+		for _, key := range retain {
+			rl.AddKey(key)
+		}
+		loader := NewFlatDBTrieLoader("IH")
+		require.NoError(t, loader.Reset(rl, nil, nil, false))
+		loader.SetIgnoreIH(ignoreIH)
+		root, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+		require.NoError(t, err)
+		return root
+	}
+	freshRoot := calcRoot(true)
+	require.NotEqual(t, cachedRoot, freshRoot)
+
+	// account is retained, but none of its storage - storage root comes from TrieOfStorage
+	require.Equal(t, cachedRoot, calcRoot(false, hash2[:]))
+	// storage path under nibble 3 is retained - sub-trie under nibble 1 is not rescanned
+	retained := common.HexToHash("0x3000000000000000000000000000000000000000000000000000000000E00000")
+	require.Equal(t, cachedRoot, calcRoot(false, dbutils.GenerateCompositeStorageKey(hash2, testTrieIncarnation, retained)))
+	// storage path under nibble 1 is retained - sub-trie is rescanned and the change is noticed
+	retained = common.HexToHash("0x1200000000000000000000000000000000000000000000000000000000000000")
+	require.Equal(t, freshRoot, calcRoot(false, dbutils.GenerateCompositeStorageKey(hash2, testTrieIncarnation, retained)))
+}
+
+func TestUpdateIntermediateHashes(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	_, hash3 := setupTestTrie(t, tx)
+	hash1 := common.HexToHash("0xB000000000000000000000000000000000000000000000000000000000000000")
+
+	// changes of a block: balance, new storage slot, new account
+	u := NewIHUpdate()
+	require.NoError(t, addTestAccount(tx, hash1, 5*params.Ether, 0))
+	u.AddAccount(hash1, false)
+	loc := common.HexToHash("0x3000000000000000000000000000000000000000000000000000000000E00002")
+	require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, testTrieIncarnation, loc), []byte{5}))
+	u.AddStorage(hash3, testTrieIncarnation, loc, true)
+	hash5 := common.HexToHash("0xB042000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, addTestAccount(tx, hash5, 6*params.Ether, 0))
+	u.AddAccount(hash5, true)
+
+	root, err := UpdateIntermediateHashes("IH", tx, u, t.TempDir(), nil /* quit */)
+	require.NoError(t, err)
+
+	// updated intermediate hashes give the same root as the state
+	loader := NewFlatDBTrieLoader("IH")
+	require.NoError(t, loader.Reset(NewRetainList(0), nil, nil, false))
+	withIH, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	loader.SetIgnoreIH(true)
+	fromState, err := loader.CalcTrieRoot(tx, []byte{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, fromState, root)
+	require.Equal(t, fromState, withIH)
+
+	require.Equal(t, regenerateTestIH(t, tx), root)
+}
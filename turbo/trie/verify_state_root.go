@@ -0,0 +1,44 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// ErrStateRootMismatch - see VerifyStateRoot
+var ErrStateRootMismatch = errors.New("state root mismatch")
+
+// VerifyStateRoot - calculates root of the whole state and compares it with `expected` (usually Root of the header
+// of the block the state is at). Uses intermediate hashes, so it's fast, but it can't notice state which doesn't
+// match them. `strict` additionally calculates root from state only (see SetIgnoreIH) - it reads the whole state
+func VerifyStateRoot(tx kv.Tx, expected common.Hash, strict bool, quit <-chan struct{}) error {
+	loader := NewFlatDBTrieLoader("VerifyStateRoot")
+	if err := loader.Reset(NewRetainList(0), nil, nil, false); err != nil {
+		return err
+	}
+	root, err := loader.CalcTrieRoot(tx, nil, quit)
+	if err != nil {
+		return err
+	}
+	if root != expected {
+		return fmt.Errorf("%w: expected %x, got %x", ErrStateRootMismatch, expected, root)
+	}
+	if !strict {
+		return nil
+	}
+
+	if err = loader.Reset(NewRetainList(0), nil, nil, false); err != nil {
+		return err
+	}
+	loader.SetIgnoreIH(true)
+	if root, err = loader.CalcTrieRoot(tx, nil, quit); err != nil {
+		return err
+	}
+	if root != expected {
+		return fmt.Errorf("%w: expected %x, got %x without intermediate hashes", ErrStateRootMismatch, expected, root)
+	}
+	return nil
+}